@@ -117,11 +117,14 @@ func (app *App) validateGitVersion() (*git_commands.GitVersion, error) {
 	version, err := git_commands.GetGitVersion(app.OSCommand)
 	// if we get an error anywhere here we'll show the same status
 	minVersionError := errors.New(app.Tr.MinGitVersionError)
+	if errors.Is(err, git_commands.ErrGitNotFound) {
+		return nil, errors.New(app.Tr.GitNotFoundError)
+	}
 	if err != nil {
 		return nil, minVersionError
 	}
 
-	if version.IsOlderThan(2, 20, 0) {
+	if !version.MeetsMinimum(2, 20) {
 		return nil, minVersionError
 	}
 
@@ -3,9 +3,12 @@ package daemon
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
+	"net"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/fsmiamoto/git-todo-parser/todo"
 	"github.com/jesseduffield/lazygit/pkg/commands/models"
@@ -38,6 +41,7 @@ const (
 	DaemonKindInsertBreak
 	DaemonKindChangeTodoActions
 	DaemonKindMoveFixupCommitDown
+	DaemonKindAskpass
 )
 
 const (
@@ -58,6 +62,7 @@ func getInstruction() Instruction {
 		DaemonKindMoveTodoUp:          deserializeInstruction[*MoveTodoUpInstruction],
 		DaemonKindMoveTodoDown:        deserializeInstruction[*MoveTodoDownInstruction],
 		DaemonKindInsertBreak:         deserializeInstruction[*InsertBreakInstruction],
+		DaemonKindAskpass:             deserializeInstruction[*AskpassInstruction],
 	}
 
 	return mapping[getDaemonKind()](jsonData)
@@ -304,3 +309,47 @@ func (self *InsertBreakInstruction) run(common *common.Common) error {
 		return utils.PrependStrToTodoFile(path, []byte("break\n"))
 	})
 }
+
+// Used when lazygit is invoked by git (or ssh) as GIT_ASKPASS/SSH_ASKPASS.
+// The prompt is passed to the parent lazygit process over a unix socket, and
+// whatever the parent sends back (e.g. the password the user typed) is printed
+// to stdout for git to read.
+type AskpassInstruction struct {
+	SocketPath string
+}
+
+func NewAskpassInstruction(socketPath string) Instruction {
+	return &AskpassInstruction{
+		SocketPath: socketPath,
+	}
+}
+
+func (self *AskpassInstruction) Kind() DaemonKind {
+	return DaemonKindAskpass
+}
+
+func (self *AskpassInstruction) SerializedInstructions() string {
+	return serializeInstruction(self)
+}
+
+func (self *AskpassInstruction) run(common *common.Common) error {
+	conn, err := net.Dial("unix", self.SocketPath)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// the prompt (e.g. "Password for 'https://github.com':") is our only argument
+	prompt := strings.Join(os.Args[1:], " ")
+	if _, err := fmt.Fprintln(conn, strings.ReplaceAll(prompt, "\n", " ")); err != nil {
+		return err
+	}
+
+	answer, err := io.ReadAll(conn)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(strings.TrimSuffix(string(answer), "\n"))
+	return nil
+}
@@ -16,7 +16,7 @@ type errorMapping struct {
 func knownError(tr *i18n.TranslationSet, err error) (string, bool) {
 	errorMessage := err.Error()
 
-	knownErrorMessages := []string{tr.MinGitVersionError}
+	knownErrorMessages := []string{tr.MinGitVersionError, tr.GitNotFoundError}
 
 	if slices.Contains(knownErrorMessages, errorMessage) {
 		return errorMessage, true
@@ -20,6 +20,7 @@ import (
 
 // GitCommand is our main git interface
 type GitCommand struct {
+	Alias       *git_commands.AliasCommands
 	Branch      *git_commands.BranchCommands
 	Commit      *git_commands.CommitCommands
 	Config      *git_commands.ConfigCommands
@@ -67,17 +68,11 @@ func NewGitCommand(
 		return nil, err
 	}
 
-	dotGitDir, err := findDotGitDir(os.Stat, os.ReadFile)
-	if err != nil {
-		return nil, err
-	}
-
 	return NewGitCommandAux(
 		cmn,
 		version,
 		osCommand,
 		gitConfig,
-		dotGitDir,
 		repo,
 		syncMutex,
 	), nil
@@ -88,7 +83,6 @@ func NewGitCommandAux(
 	version *git_commands.GitVersion,
 	osCommand *oscommands.OSCommand,
 	gitConfig git_config.IGitConfig,
-	dotGitDir string,
 	repo *gogit.Repository,
 	syncMutex *deadlock.Mutex,
 ) *GitCommand {
@@ -98,12 +92,13 @@ func NewGitCommandAux(
 	// This is admittedly messy, but allows us to test each command struct in isolation,
 	// and allows for better namespacing when compared to having every method living
 	// on the one struct.
-	// common ones are: cmn, osCommand, dotGitDir, configCommands
+	// common ones are: cmn, osCommand, configCommands
 	configCommands := git_commands.NewConfigCommands(cmn, gitConfig, repo)
 
-	fileLoader := git_commands.NewFileLoader(cmn, cmd, configCommands)
+	gitCommon := git_commands.NewGitCommon(cmn, version, cmd, osCommand, repo, configCommands, syncMutex)
+	submoduleCommands := git_commands.NewSubmoduleCommands(gitCommon)
+	fileLoader := git_commands.NewFileLoader(cmn, cmd, configCommands, submoduleCommands.GetStatuses, gitCommon.GitDir)
 
-	gitCommon := git_commands.NewGitCommon(cmn, version, cmd, osCommand, dotGitDir, repo, configCommands, syncMutex)
 	statusCommands := git_commands.NewStatusCommands(gitCommon)
 	flowCommands := git_commands.NewFlowCommands(gitCommon)
 	remoteCommands := git_commands.NewRemoteCommands(gitCommon)
@@ -113,12 +108,16 @@ func NewGitCommandAux(
 	commitCommands := git_commands.NewCommitCommands(gitCommon)
 	customCommands := git_commands.NewCustomCommands(gitCommon)
 	fileCommands := git_commands.NewFileCommands(gitCommon)
-	submoduleCommands := git_commands.NewSubmoduleCommands(gitCommon)
 	workingTreeCommands := git_commands.NewWorkingTreeCommands(gitCommon, submoduleCommands, fileLoader)
 	rebaseCommands := git_commands.NewRebaseCommands(gitCommon, commitCommands, workingTreeCommands)
 	stashCommands := git_commands.NewStashCommands(gitCommon, fileLoader, workingTreeCommands)
 	// TODO: have patch builder take workingTreeCommands in its entirety
-	patchBuilder := patch.NewPatchBuilder(cmn.Log, workingTreeCommands.ApplyPatch,
+	patchBuilder := patch.NewPatchBuilder(cmn.Log,
+		func(patch string, flags ...string) error {
+			// the patch is made from diffs loaded by the function below, and
+			// the context size can't change while a patch is being built
+			return workingTreeCommands.ApplyPatch(patch, cmn.UserConfig.Git.DiffContextSize, flags...)
+		},
 		func(from string, to string, reverse bool, filename string, plain bool) (string, error) {
 			// TODO: make patch builder take Gui.IgnoreWhitespaceInDiffView into
 			// account. For now we just pass false.
@@ -126,16 +125,18 @@ func NewGitCommandAux(
 		})
 	patchCommands := git_commands.NewPatchCommands(gitCommon, rebaseCommands, commitCommands, statusCommands, stashCommands, patchBuilder)
 	bisectCommands := git_commands.NewBisectCommands(gitCommon)
+	aliasCommands := git_commands.NewAliasCommands(gitCommon)
 
 	branchLoader := git_commands.NewBranchLoader(cmn, branchCommands.GetRawBranches, branchCommands.CurrentBranchInfo, configCommands)
 	commitFileLoader := git_commands.NewCommitFileLoader(cmn, cmd)
-	commitLoader := git_commands.NewCommitLoader(cmn, cmd, dotGitDir, branchCommands.CurrentBranchInfo, statusCommands.RebaseMode)
+	commitLoader := git_commands.NewCommitLoader(cmn, cmd, gitCommon.GitDir, branchCommands.CurrentBranchInfo, statusCommands.RebaseMode)
 	reflogCommitLoader := git_commands.NewReflogCommitLoader(cmn, cmd)
 	remoteLoader := git_commands.NewRemoteLoader(cmn, cmd, repo.Remotes)
 	stashLoader := git_commands.NewStashLoader(cmn, cmd)
 	tagLoader := git_commands.NewTagLoader(cmn, cmd)
 
 	return &GitCommand{
+		Alias:       aliasCommands,
 		Branch:      branchCommands,
 		Commit:      commitCommands,
 		Config:      configCommands,
@@ -165,6 +166,13 @@ func NewGitCommandAux(
 	}
 }
 
+// SetBypassHooks sets whether commits and pushes skip git hooks
+func (self *GitCommand) SetBypassHooks(value bool) {
+	// all of the commands share the same GitCommon, so it doesn't matter which
+	// we set it through
+	self.Commit.SetBypassHooks(value)
+}
+
 func navigateToRepoRootDirectory(stat func(string) (os.FileInfo, error), chdir func(string) error) error {
 	gitDir := env.GetGitDirEnv()
 	if gitDir != "" {
@@ -249,31 +257,6 @@ func setupRepository(openGitRepository func(string, *gogit.PlainOpenOptions) (*g
 	return repository, err
 }
 
-func findDotGitDir(stat func(string) (os.FileInfo, error), readFile func(filename string) ([]byte, error)) (string, error) {
-	if env.GetGitDirEnv() != "" {
-		return env.GetGitDirEnv(), nil
-	}
-
-	f, err := stat(".git")
-	if err != nil {
-		return "", err
-	}
-
-	if f.IsDir() {
-		return ".git", nil
-	}
-
-	fileBytes, err := readFile(".git")
-	if err != nil {
-		return "", err
-	}
-	fileContent := string(fileBytes)
-	if !strings.HasPrefix(fileContent, "gitdir: ") {
-		return "", errors.New(".git is a file which suggests we are in a submodule or a worktree but the file's contents do not contain a gitdir pointing to the actual .git directory")
-	}
-	return strings.TrimSpace(strings.TrimPrefix(fileContent, "gitdir: ")), nil
-}
-
 func VerifyInGitRepo(osCommand *oscommands.OSCommand) error {
 	return osCommand.Cmd.New("git rev-parse --git-dir").DontLog().Run()
 }
@@ -0,0 +1,102 @@
+package git_commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-errors/errors"
+	"github.com/jesseduffield/generics/slices"
+	"github.com/jesseduffield/lazygit/pkg/commands/oscommands"
+)
+
+type AliasKind int
+
+const (
+	// an alias which expands to another git command e.g. `co = checkout`
+	ALIAS_KIND_GIT AliasKind = iota
+	// an alias starting with '!' which is run by the shell e.g. `lg = !git log | head`
+	ALIAS_KIND_SHELL
+)
+
+type AliasCommands struct {
+	*GitCommon
+}
+
+func NewAliasCommands(gitCommon *GitCommon) *AliasCommands {
+	return &AliasCommands{
+		GitCommon: gitCommon,
+	}
+}
+
+// ListAliases returns the user's git aliases, keyed by alias name
+func (self *AliasCommands) ListAliases() (map[string]string, error) {
+	aliases := map[string]string{}
+
+	output := strings.TrimSpace(self.config.GetAliases())
+	if output == "" {
+		return aliases, nil
+	}
+
+	for _, entry := range strings.Split(output, "\x00") {
+		if entry == "" {
+			continue
+		}
+
+		key, command, _ := strings.Cut(entry, "\n")
+		name := strings.TrimPrefix(key, "alias.")
+		if name == key || name == "" {
+			return nil, errors.New("unexpected git alias format: " + entry)
+		}
+
+		aliases[name] = strings.TrimSpace(command)
+	}
+
+	return aliases, nil
+}
+
+// GetAliasKind tells whether an alias's command is run by git or by the shell
+func GetAliasKind(command string) AliasKind {
+	if strings.HasPrefix(command, "!") {
+		return ALIAS_KIND_SHELL
+	}
+
+	return ALIAS_KIND_GIT
+}
+
+// RunAlias runs the named git alias with the given args appended, the same way
+// `git <name> <args>` would
+func (self *AliasCommands) RunAlias(name string, args ...string) error {
+	cmdObj, err := self.RunAliasCmdObj(name, args...)
+	if err != nil {
+		return err
+	}
+
+	return cmdObj.Run()
+}
+
+// RunAliasCmdObj returns the command for running the named git alias with the
+// given args appended. Shell aliases are run through the shell directly rather
+// than via git.
+func (self *AliasCommands) RunAliasCmdObj(name string, args ...string) (oscommands.ICmdObj, error) {
+	aliases, err := self.ListAliases()
+	if err != nil {
+		return nil, err
+	}
+
+	command, ok := aliases[name]
+	if !ok {
+		return nil, fmt.Errorf("no git alias named '%s'", name)
+	}
+
+	quotedArgs := strings.Join(slices.Map(args, func(arg string) string {
+		return self.cmd.Quote(arg)
+	}), " ")
+
+	if GetAliasKind(command) == ALIAS_KIND_SHELL {
+		// git runs shell aliases from the top-level of the repo which is where
+		// we already are, so we just need to pass the remaining args along
+		return self.cmd.NewShell(strings.TrimPrefix(command, "!") + pad(quotedArgs)), nil
+	}
+
+	return self.cmd.New("git " + name + pad(quotedArgs)), nil
+}
@@ -0,0 +1,131 @@
+package git_commands
+
+import (
+	"testing"
+
+	"github.com/jesseduffield/lazygit/pkg/commands/git_config"
+	"github.com/jesseduffield/lazygit/pkg/commands/oscommands"
+	"github.com/stretchr/testify/assert"
+)
+
+const aliasesOutput = "alias.co\ncheckout\x00" +
+	"alias.st\nstatus --short\x00" +
+	"alias.lg\n!git log --oneline | head -n 10\x00"
+
+func TestAliasListAliases(t *testing.T) {
+	type scenario struct {
+		testName        string
+		configOutput    string
+		expectedAliases map[string]string
+		expectedError   string
+	}
+
+	scenarios := []scenario{
+		{
+			testName:        "no aliases",
+			configOutput:    "",
+			expectedAliases: map[string]string{},
+		},
+		{
+			testName:     "git and shell aliases",
+			configOutput: aliasesOutput,
+			expectedAliases: map[string]string{
+				"co": "checkout",
+				"st": "status --short",
+				"lg": "!git log --oneline | head -n 10",
+			},
+		},
+		{
+			testName:     "multi-line alias",
+			configOutput: aliasesOutput + "alias.multi\n!f() {\n  git status\n}; f\x00",
+			expectedAliases: map[string]string{
+				"co":    "checkout",
+				"st":    "status --short",
+				"lg":    "!git log --oneline | head -n 10",
+				"multi": "!f() {\n  git status\n}; f",
+			},
+		},
+		{
+			testName:      "malformed output",
+			configOutput:  "core.editor\nvim\x00",
+			expectedError: "unexpected git alias format: core.editor\nvim",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			instance := buildAliasCommands(commonDeps{
+				gitConfig: git_config.NewFakeGitConfig(map[string]string{
+					"-z --get-regexp ^alias\\.": s.configOutput,
+				}),
+			})
+
+			aliases, err := instance.ListAliases()
+			if s.expectedError != "" {
+				assert.EqualError(t, err, s.expectedError)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, s.expectedAliases, aliases)
+		})
+	}
+}
+
+func TestAliasRunAliasCmdObj(t *testing.T) {
+	type scenario struct {
+		testName      string
+		name          string
+		args          []string
+		expectedCmd   string
+		expectedError string
+	}
+
+	scenarios := []scenario{
+		{
+			testName:    "git alias without args",
+			name:        "co",
+			args:        nil,
+			expectedCmd: "git co",
+		},
+		{
+			testName:    "git alias with args",
+			name:        "co",
+			args:        []string{"-b", "my branch"},
+			expectedCmd: `git co "-b" "my branch"`,
+		},
+		{
+			testName:    "shell alias",
+			name:        "lg",
+			args:        []string{"src"},
+			expectedCmd: `bash -c "git log --oneline | head -n 10 \"src\""`,
+		},
+		{
+			testName:      "unknown alias",
+			name:          "nope",
+			expectedError: "no git alias named 'nope'",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			instance := buildAliasCommands(commonDeps{
+				runner: oscommands.NewFakeRunner(t),
+				gitConfig: git_config.NewFakeGitConfig(map[string]string{
+					"-z --get-regexp ^alias\\.": aliasesOutput,
+				}),
+			})
+
+			cmdObj, err := instance.RunAliasCmdObj(s.name, s.args...)
+			if s.expectedError != "" {
+				assert.EqualError(t, err, s.expectedError)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, s.expectedCmd, cmdObj.ToString())
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package git_commands
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
@@ -23,6 +24,26 @@ func (self *BranchCommands) New(name string, base string) error {
 	return self.cmd.New(fmt.Sprintf("git checkout -b %s %s", self.cmd.Quote(name), self.cmd.Quote(base))).Run()
 }
 
+// MoveChangesToNewBranch creates a new branch at HEAD and checks it out. Because
+// the new branch starts at the current commit, git carries any uncommitted
+// changes over, so this is how you move changes made on the wrong branch.
+func (self *BranchCommands) MoveChangesToNewBranch(name string) error {
+	if err := self.cmd.New("git check-ref-format --branch " + self.cmd.Quote(name)).DontLog().Run(); err != nil {
+		return fmt.Errorf("'%s' is not a valid branch name", name)
+	}
+
+	if self.localBranchExists(name) {
+		return fmt.Errorf("a branch named '%s' already exists", name)
+	}
+
+	return self.cmd.New("git checkout -b " + self.cmd.Quote(name)).Run()
+}
+
+func (self *BranchCommands) localBranchExists(name string) bool {
+	err := self.cmd.New("git rev-parse --verify --quiet " + self.cmd.Quote("refs/heads/"+name)).DontLog().Run()
+	return err == nil
+}
+
 // CurrentBranchInfo get the current branch information.
 func (self *BranchCommands) CurrentBranchInfo() (BranchInfo, error) {
 	branchName, err := self.cmd.New("git symbolic-ref --short HEAD").DontLog().RunWithOutput()
@@ -75,16 +96,30 @@ type CheckoutOptions struct {
 }
 
 func (self *BranchCommands) Checkout(branch string, options CheckoutOptions) error {
+	return self.CheckoutContext(context.Background(), branch, options)
+}
+
+// CheckoutContext is like Checkout, but git is killed if ctx is cancelled
+// before it's done, in which case we return ctx.Err()
+func (self *BranchCommands) CheckoutContext(ctx context.Context, branch string, options CheckoutOptions) error {
 	forceArg := ""
 	if options.Force {
 		forceArg = " --force"
 	}
 
-	return self.cmd.New(fmt.Sprintf("git checkout%s %s", forceArg, self.cmd.Quote(branch))).
+	// `git switch` refuses to check out anything other than a local branch
+	// unless we pass --detach, so we only use it when we know we have one
+	command := "checkout"
+	if self.features.SupportsSwitch && self.localBranchExists(branch) {
+		command = "switch"
+	}
+
+	return self.cmd.New(fmt.Sprintf("git %s%s %s", command, forceArg, self.cmd.Quote(branch))).
 		// prevents git from prompting us for input which would freeze the program
 		// TODO: see if this is actually needed here
 		AddEnvVars("GIT_TERMINAL_PROMPT=0").
 		AddEnvVars(options.EnvVars...).
+		WithContext(ctx).
 		Run()
 }
 
@@ -138,6 +173,54 @@ func (self *BranchCommands) GetCommitDifferences(from, to string) (string, strin
 	return strings.TrimSpace(pushableCount), strings.TrimSpace(pullableCount)
 }
 
+// MergeBase returns the sha of the best common ancestor of the two refs
+func (self *BranchCommands) MergeBase(refA string, refB string) (string, error) {
+	output, err := self.cmd.New(
+		fmt.Sprintf("git merge-base %s %s", self.cmd.Quote(refA), self.cmd.Quote(refB)),
+	).DontLog().RunWithOutput()
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(output), nil
+}
+
+// NoUpstreamError is returned when the current branch has no upstream, e.g.
+// because none has been set or because HEAD is detached
+type NoUpstreamError struct {
+	// git's explanation of why there's no upstream
+	Reason string
+}
+
+func (self *NoUpstreamError) Error() string {
+	return "the current branch has no upstream: " + self.Reason
+}
+
+// the errors git gives from `git rev-parse @{u}` when there's simply no
+// upstream to resolve, as opposed to when something's actually gone wrong
+var noUpstreamErrorPatterns = []string{
+	"no upstream configured for branch",
+	"HEAD does not point to a branch",
+}
+
+// MergeBaseWithUpstream returns the sha of the best common ancestor of HEAD
+// and the current branch's upstream. If there's no upstream, a
+// *NoUpstreamError is returned.
+func (self *BranchCommands) MergeBaseWithUpstream() (string, error) {
+	upstream, err := self.cmd.New("git rev-parse --abbrev-ref --symbolic-full-name @{u}").DontLog().RunWithOutput()
+	if err != nil {
+		reason := strings.TrimSpace(err.Error())
+		for _, pattern := range noUpstreamErrorPatterns {
+			if strings.Contains(reason, pattern) {
+				return "", &NoUpstreamError{Reason: reason}
+			}
+		}
+		return "", err
+	}
+
+	return self.MergeBase("HEAD", strings.TrimSpace(upstream))
+}
+
 func (self *BranchCommands) IsHeadDetached() bool {
 	err := self.cmd.New("git symbolic-ref -q HEAD").DontLog().Run()
 	return err != nil
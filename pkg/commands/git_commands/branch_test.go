@@ -51,6 +51,68 @@ func TestBranchGetCommitDifferences(t *testing.T) {
 	}
 }
 
+func TestBranchMergeBase(t *testing.T) {
+	runner := oscommands.NewFakeRunner(t).
+		ExpectGitArgs([]string{"merge-base", "feature", "origin/main"}, "a1b2c3\n", nil)
+	instance := buildBranchCommands(commonDeps{runner: runner})
+
+	sha, err := instance.MergeBase("feature", "origin/main")
+	assert.NoError(t, err)
+	assert.Equal(t, "a1b2c3", sha)
+	runner.CheckForMissingCalls()
+}
+
+func TestBranchMergeBaseWithUpstream(t *testing.T) {
+	type scenario struct {
+		testName      string
+		runner        *oscommands.FakeCmdObjRunner
+		expectedSha   string
+		expectedError error
+	}
+
+	unresolvableUpstreamErr := errors.New("fatal: upstream branch 'refs/heads/feature' not stored as a remote-tracking branch")
+
+	scenarios := []scenario{
+		{
+			testName: "has an upstream",
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"rev-parse", "--abbrev-ref", "--symbolic-full-name", "@{u}"}, "origin/feature\n", nil).
+				ExpectGitArgs([]string{"merge-base", "HEAD", "origin/feature"}, "a1b2c3\n", nil),
+			expectedSha: "a1b2c3",
+		},
+		{
+			testName: "no upstream",
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"rev-parse", "--abbrev-ref", "--symbolic-full-name", "@{u}"}, "", errors.New("fatal: no upstream configured for branch 'feature'")),
+			expectedError: &NoUpstreamError{Reason: "fatal: no upstream configured for branch 'feature'"},
+		},
+		{
+			testName: "detached head",
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"rev-parse", "--abbrev-ref", "--symbolic-full-name", "@{u}"}, "", errors.New("fatal: HEAD does not point to a branch")),
+			expectedError: &NoUpstreamError{Reason: "fatal: HEAD does not point to a branch"},
+		},
+		{
+			testName: "upstream can't be resolved",
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"rev-parse", "--abbrev-ref", "--symbolic-full-name", "@{u}"}, "", unresolvableUpstreamErr),
+			expectedError: unresolvableUpstreamErr,
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			instance := buildBranchCommands(commonDeps{runner: s.runner})
+
+			sha, err := instance.MergeBaseWithUpstream()
+			assert.Equal(t, s.expectedError, err)
+			assert.Equal(t, s.expectedSha, sha)
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
+
 func TestBranchNewBranch(t *testing.T) {
 	runner := oscommands.NewFakeRunner(t).
 		Expect(`git checkout -b "test" "refs/heads/master"`, "", nil)
@@ -109,10 +171,11 @@ func TestBranchMerge(t *testing.T) {
 
 func TestBranchCheckout(t *testing.T) {
 	type scenario struct {
-		testName string
-		runner   *oscommands.FakeCmdObjRunner
-		test     func(error)
-		force    bool
+		testName   string
+		runner     *oscommands.FakeCmdObjRunner
+		test       func(error)
+		force      bool
+		gitVersion *GitVersion
 	}
 
 	scenarios := []scenario{
@@ -123,6 +186,7 @@ func TestBranchCheckout(t *testing.T) {
 				assert.NoError(t, err)
 			},
 			false,
+			nil,
 		},
 		{
 			"Checkout forced",
@@ -131,13 +195,36 @@ func TestBranchCheckout(t *testing.T) {
 				assert.NoError(t, err)
 			},
 			true,
+			nil,
+		},
+		{
+			"Checkout local branch with switch",
+			oscommands.NewFakeRunner(t).
+				Expect(`git rev-parse --verify --quiet "refs/heads/test"`, "", nil).
+				Expect(`git switch --force "test"`, "", nil),
+			func(err error) {
+				assert.NoError(t, err)
+			},
+			true,
+			&GitVersion{2, 23, 0, ""},
+		},
+		{
+			"Checkout something other than a local branch when switch is available",
+			oscommands.NewFakeRunner(t).
+				Expect(`git rev-parse --verify --quiet "refs/heads/test"`, "", errors.New("error")).
+				Expect(`git checkout "test"`, "", nil),
+			func(err error) {
+				assert.NoError(t, err)
+			},
+			false,
+			&GitVersion{2, 23, 0, ""},
 		},
 	}
 
 	for _, s := range scenarios {
 		s := s
 		t.Run(s.testName, func(t *testing.T) {
-			instance := buildBranchCommands(commonDeps{runner: s.runner})
+			instance := buildBranchCommands(commonDeps{runner: s.runner, gitVersion: s.gitVersion})
 			s.test(instance.Checkout("test", CheckoutOptions{Force: s.force}))
 			s.runner.CheckForMissingCalls()
 		})
@@ -231,3 +318,54 @@ func TestBranchCurrentBranchInfo(t *testing.T) {
 		})
 	}
 }
+
+func TestBranchMoveChangesToNewBranch(t *testing.T) {
+	type scenario struct {
+		testName string
+		name     string
+		runner   *oscommands.FakeCmdObjRunner
+		test     func(error)
+	}
+
+	scenarios := []scenario{
+		{
+			testName: "new branch",
+			name:     "feature",
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git check-ref-format --branch "feature"`, "feature\n", nil).
+				Expect(`git rev-parse --verify --quiet "refs/heads/feature"`, "", errors.New("exit status 1")).
+				Expect(`git checkout -b "feature"`, "", nil),
+			test: func(err error) {
+				assert.NoError(t, err)
+			},
+		},
+		{
+			testName: "invalid name",
+			name:     "bad..name",
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git check-ref-format --branch "bad..name"`, "", errors.New("exit status 128")),
+			test: func(err error) {
+				assert.EqualError(t, err, "'bad..name' is not a valid branch name")
+			},
+		},
+		{
+			testName: "branch already exists",
+			name:     "master",
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git check-ref-format --branch "master"`, "master\n", nil).
+				Expect(`git rev-parse --verify --quiet "refs/heads/master"`, "abc123\n", nil),
+			test: func(err error) {
+				assert.EqualError(t, err, "a branch named 'master' already exists")
+			},
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			instance := buildBranchCommands(commonDeps{runner: s.runner})
+			s.test(instance.MoveChangesToNewBranch(s.name))
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
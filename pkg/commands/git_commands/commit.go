@@ -2,10 +2,12 @@ package git_commands
 
 import (
 	"fmt"
+	"os"
 	"strings"
 
 	"github.com/go-errors/errors"
 	"github.com/jesseduffield/lazygit/pkg/commands/oscommands"
+	"github.com/samber/lo"
 )
 
 var ErrInvalidCommitIndex = errors.New("invalid commit index")
@@ -46,11 +48,11 @@ func (self *CommitCommands) CommitCmdObj(message string) oscommands.ICmdObj {
 
 	skipHookPrefix := self.UserConfig.Git.SkipHookPrefix
 	noVerifyFlag := ""
-	if skipHookPrefix != "" && strings.HasPrefix(message, skipHookPrefix) {
+	if self.BypassHooks() || (skipHookPrefix != "" && strings.HasPrefix(message, skipHookPrefix)) {
 		noVerifyFlag = " --no-verify"
 	}
 
-	return self.cmd.New(fmt.Sprintf("git commit%s%s%s", noVerifyFlag, self.signoffFlag(), messageArgs))
+	return self.bypassHooksIfSet(self.cmd.New(fmt.Sprintf("git commit%s%s%s", noVerifyFlag, self.signoffFlag(), messageArgs)))
 }
 
 // RewordLastCommit rewords the topmost commit with the given message
@@ -60,18 +62,55 @@ func (self *CommitCommands) RewordLastCommit(message string) error {
 }
 
 func (self *CommitCommands) commitMessageArgs(message string) string {
+	return commitMessageArgs(self.cmd, message)
+}
+
+// splits the message into a summary and a description, passing each via its own -m arg
+func commitMessageArgs(cmd oscommands.ICmdObjBuilder, message string) string {
 	msg, description, _ := strings.Cut(message, "\n")
 	descriptionArgs := ""
 	if description != "" {
-		descriptionArgs = fmt.Sprintf(" -m %s", self.cmd.Quote(description))
+		descriptionArgs = fmt.Sprintf(" -m %s", cmd.Quote(description))
 	}
 
-	return fmt.Sprintf(" -m %s%s", self.cmd.Quote(msg), descriptionArgs)
+	return fmt.Sprintf(" -m %s%s", cmd.Quote(msg), descriptionArgs)
+}
+
+// CommitTemplateMessage returns the commit message template configured with
+// commit.template, for starting off a message written in lazygit. git only
+// uses the template itself when it opens the editor. Comment lines are left
+// out because git doesn't strip them from a message passed with -m. If the
+// template can't be read we log a warning and start with an empty message.
+func (self *CommitCommands) CommitTemplateMessage() string {
+	path := self.config.GetCommitTemplatePath()
+	if path == "" {
+		return ""
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		self.Log.Warningf("could not read commit template: %v", err)
+		return ""
+	}
+
+	lines := lo.Reject(strings.Split(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n"), func(line string, _ int) bool {
+		return strings.HasPrefix(line, "#")
+	})
+
+	return strings.Trim(strings.Join(lines, "\n"), "\n")
 }
 
 // runs git commit without the -m argument meaning it will invoke the user's editor
 func (self *CommitCommands) CommitEditorCmdObj() oscommands.ICmdObj {
-	return self.cmd.New(fmt.Sprintf("git commit%s%s", self.signoffFlag(), self.verboseFlag()))
+	return self.bypassHooksIfSet(self.cmd.New(fmt.Sprintf("git commit%s%s%s", self.noVerifyFlag(), self.signoffFlag(), self.verboseFlag())))
+}
+
+// skips the hooks if the user has asked us to bypass them
+func (self *CommitCommands) noVerifyFlag() string {
+	if self.BypassHooks() {
+		return " --no-verify"
+	}
+	return ""
 }
 
 func (self *CommitCommands) signoffFlag() string {
@@ -155,7 +194,7 @@ func (self *CommitCommands) AmendHead() error {
 }
 
 func (self *CommitCommands) AmendHeadCmdObj() oscommands.ICmdObj {
-	return self.cmd.New("git commit --amend --no-edit --allow-empty")
+	return self.bypassHooksIfSet(self.cmd.New("git commit --amend --no-edit --allow-empty" + self.noVerifyFlag()))
 }
 
 func (self *CommitCommands) ShowCmdObj(sha string, filterPath string, ignoreWhitespace bool) oscommands.ICmdObj {
@@ -2,6 +2,7 @@ package git_commands
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/jesseduffield/generics/slices"
@@ -54,3 +55,53 @@ func getCommitFilesFromFilenames(filenames string) []*models.CommitFile {
 		}
 	})
 }
+
+// ChangedFilesBetween returns the files that differ between the two refs, e.g.
+// a branch and its upstream. As the changes are committed, each file's status
+// is like that of a staged change, e.g. 'M ' or 'R ', and a renamed file has
+// its PreviousName set.
+func (self *CommitFileLoader) ChangedFilesBetween(fromRef string, toRef string) ([]*models.File, error) {
+	output, err := self.cmd.New(fmt.Sprintf("git diff --no-ext-diff --name-status -z --find-renames %s..%s", fromRef, toRef)).DontLog().RunWithOutput()
+	if err != nil {
+		return nil, err
+	}
+
+	return parseNameStatusFiles(output), nil
+}
+
+// with -z, each file is a status entry (e.g. 'M' or 'R087', where the number is
+// the similarity score) followed by its path, or for a rename or copy, by the
+// old and new paths as separate entries
+func parseNameStatusFiles(output string) []*models.File {
+	entries := strings.Split(strings.TrimRight(output, "\x00"), "\x00")
+	files := []*models.File{}
+
+	for i := 0; i+1 < len(entries); i += 2 {
+		status := entries[i]
+		if status == "" {
+			continue
+		}
+		change := status[:1] + " "
+
+		file := &models.File{Name: entries[i+1], Type: "file"}
+		if status[0] == 'R' || status[0] == 'C' {
+			if i+2 >= len(entries) {
+				break
+			}
+			file.RenameScore, _ = strconv.Atoi(status[1:])
+			file.Name = entries[i+2]
+			if status[0] == 'R' {
+				file.PreviousName = entries[i+1]
+			}
+			file.DisplayString = fmt.Sprintf("%s %s -> %s", change, entries[i+1], file.Name)
+			i++
+		} else {
+			file.DisplayString = change + " " + file.Name
+		}
+
+		models.SetStatusFields(file, change)
+		files = append(files, file)
+	}
+
+	return files
+}
@@ -3,7 +3,10 @@ package git_commands
 import (
 	"testing"
 
+	"github.com/go-errors/errors"
 	"github.com/jesseduffield/lazygit/pkg/commands/models"
+	"github.com/jesseduffield/lazygit/pkg/commands/oscommands"
+	"github.com/jesseduffield/lazygit/pkg/utils"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -69,3 +72,99 @@ func TestGetCommitFilesFromFilenames(t *testing.T) {
 		})
 	}
 }
+
+func TestCommitFileLoaderChangedFilesBetween(t *testing.T) {
+	type scenario struct {
+		testName      string
+		runner        *oscommands.FakeCmdObjRunner
+		expectedFiles []*models.File
+		expectedError string
+	}
+
+	scenarios := []scenario{
+		{
+			testName: "no changes",
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git diff --no-ext-diff --name-status -z --find-renames main..feature`, "", nil),
+			expectedFiles: []*models.File{},
+		},
+		{
+			testName: "each kind of change",
+			runner: oscommands.NewFakeRunner(t).
+				Expect(
+					`git diff --no-ext-diff --name-status -z --find-renames main..feature`,
+					"M\x00modified.go\x00A\x00added.go\x00D\x00deleted.go\x00R087\x00old name.go\x00new name.go\x00C100\x00original.go\x00copy.go\x00",
+					nil,
+				),
+			expectedFiles: []*models.File{
+				{
+					Name:             "modified.go",
+					DisplayString:    "M  modified.go",
+					Type:             "file",
+					ShortStatus:      "M ",
+					HasStagedChanges: true,
+					Tracked:          true,
+				},
+				{
+					Name:             "added.go",
+					DisplayString:    "A  added.go",
+					Type:             "file",
+					ShortStatus:      "A ",
+					HasStagedChanges: true,
+					Added:            true,
+				},
+				{
+					Name:             "deleted.go",
+					DisplayString:    "D  deleted.go",
+					Type:             "file",
+					ShortStatus:      "D ",
+					HasStagedChanges: true,
+					Tracked:          true,
+					Deleted:          true,
+				},
+				{
+					Name:             "new name.go",
+					PreviousName:     "old name.go",
+					DisplayString:    "R  old name.go -> new name.go",
+					Type:             "file",
+					ShortStatus:      "R ",
+					HasStagedChanges: true,
+					Tracked:          true,
+					RenameScore:      87,
+				},
+				{
+					Name:             "copy.go",
+					DisplayString:    "C  original.go -> copy.go",
+					Type:             "file",
+					ShortStatus:      "C ",
+					HasStagedChanges: true,
+					Tracked:          true,
+					RenameScore:      100,
+				},
+			},
+		},
+		{
+			testName: "error",
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git diff --no-ext-diff --name-status -z --find-renames main..feature`, "", errors.New("bad revision")),
+			expectedError: "bad revision",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			loader := NewCommitFileLoader(utils.NewDummyCommon(), oscommands.NewDummyCmdObjBuilder(s.runner))
+
+			files, err := loader.ChangedFilesBetween("main", "feature")
+
+			if s.expectedError == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, s.expectedError)
+			}
+			assert.Equal(t, s.expectedFiles, files)
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
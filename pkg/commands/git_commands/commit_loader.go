@@ -32,14 +32,14 @@ type CommitLoader struct {
 	getRebaseMode        func() (enums.RebaseMode, error)
 	readFile             func(filename string) ([]byte, error)
 	walkFiles            func(root string, fn filepath.WalkFunc) error
-	dotGitDir            string
+	getGitDir            func() (string, error)
 }
 
 // making our dependencies explicit for the sake of easier testing
 func NewCommitLoader(
 	cmn *common.Common,
 	cmd oscommands.ICmdObjBuilder,
-	dotGitDir string,
+	getGitDir func() (string, error),
 	getCurrentBranchInfo func() (BranchInfo, error),
 	getRebaseMode func() (enums.RebaseMode, error),
 ) *CommitLoader {
@@ -50,7 +50,7 @@ func NewCommitLoader(
 		getRebaseMode:        getRebaseMode,
 		readFile:             os.ReadFile,
 		walkFiles:            filepath.Walk,
-		dotGitDir:            dotGitDir,
+		getGitDir:            getGitDir,
 	}
 }
 
@@ -109,6 +109,48 @@ func (self *CommitLoader) GetCommits(opts GetCommitsOptions) ([]*models.Commit,
 	return commits, nil
 }
 
+type LogOpts struct {
+	// pass --follow so that history from before the file was renamed is included
+	FollowRenames bool
+	// only load the most recent 300 commits
+	Limit bool
+}
+
+// FileHistory returns the commits of the current branch which touched the
+// given file, newest first
+func (self *CommitLoader) FileHistory(fileName string, opts LogOpts) ([]*models.Commit, error) {
+	limitFlag := ""
+	if opts.Limit {
+		limitFlag = " -300"
+	}
+
+	followFlag := ""
+	if opts.FollowRenames {
+		followFlag = " --follow"
+	}
+
+	cmdObj := self.cmd.New(
+		fmt.Sprintf(
+			"git log --oneline %s%s --abbrev=40%s --no-show-signature -- %s",
+			prettyFormat,
+			limitFlag,
+			followFlag,
+			self.cmd.Quote(fileName),
+		),
+	).DontLog().CheckExitStatus()
+
+	commits := []*models.Commit{}
+	err := cmdObj.RunAndProcessLines(func(line string) (bool, error) {
+		commits = append(commits, self.extractCommitFromLine(line))
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return commits, nil
+}
+
 func (self *CommitLoader) MergeRebasingCommits(commits []*models.Commit) ([]*models.Commit, error) {
 	// chances are we have as many commits as last time so we'll set the capacity to be the old length
 	result := make([]*models.Commit, 0, len(commits))
@@ -245,8 +287,13 @@ func (self *CommitLoader) getRebasingCommits(rebaseMode enums.RebaseMode) ([]*mo
 }
 
 func (self *CommitLoader) getNormalRebasingCommits() ([]*models.Commit, error) {
+	gitDir, err := self.getGitDir()
+	if err != nil {
+		return nil, err
+	}
+
 	rewrittenCount := 0
-	bytesContent, err := self.readFile(filepath.Join(self.dotGitDir, "rebase-apply/rewritten"))
+	bytesContent, err := self.readFile(filepath.Join(gitDir, "rebase-apply/rewritten"))
 	if err == nil {
 		content := string(bytesContent)
 		rewrittenCount = len(strings.Split(content, "\n"))
@@ -254,7 +301,7 @@ func (self *CommitLoader) getNormalRebasingCommits() ([]*models.Commit, error) {
 
 	// we know we're rebasing, so lets get all the files whose names have numbers
 	commits := []*models.Commit{}
-	err = self.walkFiles(filepath.Join(self.dotGitDir, "rebase-apply"), func(path string, f os.FileInfo, err error) error {
+	err = self.walkFiles(filepath.Join(gitDir, "rebase-apply"), func(path string, f os.FileInfo, err error) error {
 		if rewrittenCount > 0 {
 			rewrittenCount--
 			return nil
@@ -295,7 +342,12 @@ func (self *CommitLoader) getNormalRebasingCommits() ([]*models.Commit, error) {
 // and extracts out the sha and names of commits that we still have to go
 // in the rebase:
 func (self *CommitLoader) getInteractiveRebasingCommits() ([]*models.Commit, error) {
-	bytesContent, err := self.readFile(filepath.Join(self.dotGitDir, "rebase-merge/git-rebase-todo"))
+	gitDir, err := self.getGitDir()
+	if err != nil {
+		return nil, err
+	}
+
+	bytesContent, err := self.readFile(filepath.Join(gitDir, "rebase-merge/git-rebase-todo"))
 	if err != nil {
 		self.Log.Error(fmt.Sprintf("error occurred reading git-rebase-todo: %s", err.Error()))
 		// we assume an error means the file doesn't exist so we just return
@@ -5,6 +5,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/go-errors/errors"
 	"github.com/jesseduffield/lazygit/pkg/commands/models"
 	"github.com/jesseduffield/lazygit/pkg/commands/oscommands"
 	"github.com/jesseduffield/lazygit/pkg/commands/types/enums"
@@ -231,7 +232,7 @@ func TestGetCommits(t *testing.T) {
 					return BranchInfo{RefName: scenario.currentBranchName, DisplayName: scenario.currentBranchName, DetachedHead: false}, nil
 				},
 				getRebaseMode: func() (enums.RebaseMode, error) { return scenario.rebaseMode, nil },
-				dotGitDir:     ".git",
+				getGitDir:     func() (string, error) { return ".git", nil },
 				readFile: func(filename string) ([]byte, error) {
 					return []byte(""), nil
 				},
@@ -249,3 +250,74 @@ func TestGetCommits(t *testing.T) {
 		})
 	}
 }
+
+func TestCommitLoaderFileHistory(t *testing.T) {
+	type scenario struct {
+		testName        string
+		opts            LogOpts
+		err             error
+		expectedCmd     string
+		expectedCommits []*models.Commit
+		expectedError   string
+	}
+
+	commit := &models.Commit{
+		Sha:           "0eea75e8c631fba6b58135697835d58ba4c18dbc",
+		Name:          "rename file",
+		Tags:          []string{},
+		ExtraInfo:     "(HEAD -> master)",
+		UnixTimestamp: 1640826609,
+		AuthorName:    "Jesse Duffield",
+		AuthorEmail:   "jessedduffield@gmail.com",
+		Parents:       []string{"b21997d6b4cbdf84b149"},
+	}
+
+	scenarios := []scenario{
+		{
+			testName:        "without following renames",
+			opts:            LogOpts{},
+			expectedCmd:     `git log --oneline --pretty=format:"%H%x00%at%x00%aN%x00%ae%x00%d%x00%p%x00%s" --abbrev=40 --no-show-signature -- "my file.txt"`,
+			expectedCommits: []*models.Commit{commit},
+		},
+		{
+			testName:        "following renames with a limit",
+			opts:            LogOpts{FollowRenames: true, Limit: true},
+			expectedCmd:     `git log --oneline --pretty=format:"%H%x00%at%x00%aN%x00%ae%x00%d%x00%p%x00%s" -300 --abbrev=40 --follow --no-show-signature -- "my file.txt"`,
+			expectedCommits: []*models.Commit{commit},
+		},
+		{
+			testName:        "git log fails",
+			opts:            LogOpts{},
+			err:             errors.New("fatal: your current branch 'master' does not have any commits yet"),
+			expectedCmd:     `git log --oneline --pretty=format:"%H%x00%at%x00%aN%x00%ae%x00%d%x00%p%x00%s" --abbrev=40 --no-show-signature -- "my file.txt"`,
+			expectedCommits: nil,
+			expectedError:   "fatal: your current branch 'master' does not have any commits yet",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			runner := oscommands.NewFakeRunner(t).
+				ExpectFunc(func(cmdObj oscommands.ICmdObj) (string, error) {
+					assert.Equal(t, s.expectedCmd, cmdObj.ToString())
+					assert.True(t, cmdObj.ShouldCheckExitStatus())
+					return "0eea75e8c631fba6b58135697835d58ba4c18dbc\x001640826609\x00Jesse Duffield\x00jessedduffield@gmail.com\x00 (HEAD -> master)\x00b21997d6b4cbdf84b149\x00rename file", s.err
+				})
+
+			loader := &CommitLoader{
+				Common: utils.NewDummyCommon(),
+				cmd:    oscommands.NewDummyCmdObjBuilder(runner),
+			}
+
+			commits, err := loader.FileHistory("my file.txt", s.opts)
+			if s.expectedError != "" {
+				assert.EqualError(t, err, s.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, s.expectedCommits, commits)
+			runner.CheckForMissingCalls()
+		})
+	}
+}
@@ -1,8 +1,11 @@
 package git_commands
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/jesseduffield/lazygit/pkg/commands/git_config"
 	"github.com/jesseduffield/lazygit/pkg/commands/oscommands"
 	"github.com/jesseduffield/lazygit/pkg/config"
 	"github.com/stretchr/testify/assert"
@@ -109,6 +112,38 @@ func TestCommitCommitCmdObj(t *testing.T) {
 	}
 }
 
+func TestCommitBypassingHooks(t *testing.T) {
+	gitCommon := buildGitCommon(commonDeps{})
+	commitCommands := NewCommitCommands(gitCommon)
+	workingTreeCommands := NewWorkingTreeCommands(gitCommon, NewSubmoduleCommands(gitCommon), buildFileLoader(gitCommon))
+
+	// the setting is shared by all commands
+	workingTreeCommands.SetBypassHooks(true)
+
+	workingTreeCommitCmdObj, err := workingTreeCommands.Commit("test", CommitOpts{})
+	assert.NoError(t, err)
+
+	cmdObjs := []struct {
+		cmdObj   oscommands.ICmdObj
+		expected string
+	}{
+		{cmdObj: commitCommands.CommitCmdObj("test"), expected: `git commit --no-verify -m "test"`},
+		{cmdObj: commitCommands.CommitEditorCmdObj(), expected: `git commit --no-verify`},
+		{cmdObj: commitCommands.AmendHeadCmdObj(), expected: `git commit --amend --no-edit --allow-empty --no-verify`},
+		{cmdObj: workingTreeCommitCmdObj, expected: `git commit --no-verify -m "test"`},
+	}
+
+	for _, c := range cmdObjs {
+		assert.Equal(t, c.expected, c.cmdObj.ToString())
+		assert.Subset(t, c.cmdObj.GetEnvVars(), []string{"HUSKY=0", "LEFTHOOK=0"})
+	}
+
+	commitCommands.SetBypassHooks(false)
+	cmdObj := commitCommands.CommitCmdObj("test")
+	assert.Equal(t, `git commit -m "test"`, cmdObj.ToString())
+	assert.NotContains(t, cmdObj.GetEnvVars(), "HUSKY=0")
+}
+
 func TestCommitCommitEditorCmdObj(t *testing.T) {
 	type scenario struct {
 		testName      string
@@ -295,6 +330,53 @@ Merge pull request #1750 from mark2185/fix-issue-template
 	}
 }
 
+func TestCommitCommitTemplateMessage(t *testing.T) {
+	dir := t.TempDir()
+	templatePath := filepath.Join(dir, "template")
+	assert.NoError(t, os.WriteFile(
+		templatePath,
+		[]byte("feat: \r\n\r\n# Describe why, not what\r\nRefs: #\r\n\r\n# Lines starting with '#' are comments\r\n"),
+		0o644,
+	))
+
+	type scenario struct {
+		testName     string
+		templatePath string
+		expected     string
+	}
+
+	scenarios := []scenario{
+		{
+			testName:     "no template configured",
+			templatePath: "",
+			expected:     "",
+		},
+		{
+			testName:     "template with comments",
+			templatePath: templatePath,
+			expected:     "feat: \n\nRefs: #",
+		},
+		{
+			testName:     "template that doesn't exist",
+			templatePath: filepath.Join(dir, "missing"),
+			expected:     "",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			instance := buildCommitCommands(commonDeps{
+				gitConfig: git_config.NewFakeGitConfig(map[string]string{
+					"--path --get commit.template": s.templatePath + "\n",
+				}),
+			})
+
+			assert.Equal(t, s.expected, instance.CommitTemplateMessage())
+		})
+	}
+}
+
 func TestGetCommitMessageFromHistory(t *testing.T) {
 	type scenario struct {
 		testName string
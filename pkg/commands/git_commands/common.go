@@ -1,22 +1,37 @@
 package git_commands
 
 import (
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/go-errors/errors"
 	gogit "github.com/jesseduffield/go-git/v5"
 	"github.com/jesseduffield/lazygit/pkg/commands/oscommands"
 	"github.com/jesseduffield/lazygit/pkg/common"
+	"github.com/jesseduffield/lazygit/pkg/utils"
 	"github.com/sasha-s/go-deadlock"
 )
 
 type GitCommon struct {
 	*common.Common
-	version   *GitVersion
-	cmd       oscommands.ICmdObjBuilder
-	os        *oscommands.OSCommand
-	dotGitDir string
-	repo      *gogit.Repository
-	config    *ConfigCommands
+	version  *GitVersion
+	features *Features
+	cmd      oscommands.ICmdObjBuilder
+	os       *oscommands.OSCommand
+	repo     *gogit.Repository
+	config   *ConfigCommands
 	// mutex for doing things like push/pull/fetch
 	syncMutex *deadlock.Mutex
+
+	// see repoPaths()
+	cachedRepoPaths *repoPaths
+	repoPathsMutex  sync.Mutex
+
+	// 1 if hooks should be skipped, as set by SetBypassHooks. It's accessed
+	// atomically because commands run on background goroutines.
+	bypassHooks int32
 }
 
 func NewGitCommon(
@@ -24,7 +39,6 @@ func NewGitCommon(
 	version *GitVersion,
 	cmd oscommands.ICmdObjBuilder,
 	osCommand *oscommands.OSCommand,
-	dotGitDir string,
 	repo *gogit.Repository,
 	config *ConfigCommands,
 	syncMutex *deadlock.Mutex,
@@ -32,11 +46,163 @@ func NewGitCommon(
 	return &GitCommon{
 		Common:    cmn,
 		version:   version,
+		features:  NewFeatures(version),
 		cmd:       cmd,
 		os:        osCommand,
-		dotGitDir: dotGitDir,
 		repo:      repo,
 		config:    config,
 		syncMutex: syncMutex,
 	}
 }
+
+// GitVersion returns the version of git that was detected on startup
+func (self *GitCommon) GitVersion() *GitVersion {
+	return self.version
+}
+
+// Features returns which version-dependent capabilities the installed git has,
+// so that callers can branch on those rather than on version numbers
+func (self *GitCommon) Features() *Features {
+	return self.features
+}
+
+// GitDir returns the absolute path of the repo's git directory. This is not
+// necessarily '.git' under the repo root: for a linked worktree it's
+// '.git/worktrees/<name>' in the main repo, and for a submodule it's
+// '.git/modules/<name>' in the superproject.
+func (self *GitCommon) GitDir() (string, error) {
+	paths, err := self.repoPaths()
+	if err != nil {
+		return "", err
+	}
+
+	return paths.gitDir, nil
+}
+
+// returns the path of something in the git directory, e.g. 'rebase-merge'
+func (self *GitCommon) gitDirPath(elem ...string) (string, error) {
+	gitDir, err := self.GitDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(append([]string{gitDir}, elem...)...), nil
+}
+
+// returns whether something exists in the git directory, e.g. 'MERGE_HEAD'
+func (self *GitCommon) gitDirPathExists(elem ...string) (bool, error) {
+	path, err := self.gitDirPath(elem...)
+	if err != nil {
+		return false, err
+	}
+
+	return self.os.FileExists(path)
+}
+
+// CommonDir returns the absolute path of the git directory shared by all of
+// the repo's worktrees, i.e. the main repo's git directory, which is where
+// things like info/exclude and modules/ live. It's the same as GitDir except
+// in a linked worktree.
+func (self *GitCommon) CommonDir() (string, error) {
+	paths, err := self.repoPaths()
+	if err != nil {
+		return "", err
+	}
+
+	return paths.commonDir, nil
+}
+
+// RepoPaths returns the absolute paths of the repo's top level directory and
+// its git directory, which isn't simply '<top level>/.git' in a linked worktree
+// or a submodule. A bare repo has no top level directory, so for one we return
+// an empty topLevel.
+func (self *GitCommon) RepoPaths() (topLevel string, gitDir string, err error) {
+	paths, err := self.repoPaths()
+	if err != nil {
+		return "", "", err
+	}
+
+	return paths.topLevel, paths.gitDir, nil
+}
+
+type repoPaths struct {
+	topLevel  string
+	gitDir    string
+	commonDir string
+}
+
+// the repo's paths can't change while we're in it, so we only ask git once
+func (self *GitCommon) repoPaths() (*repoPaths, error) {
+	self.repoPathsMutex.Lock()
+	defer self.repoPathsMutex.Unlock()
+
+	if self.cachedRepoPaths != nil {
+		return self.cachedRepoPaths, nil
+	}
+
+	output, err := self.cmd.New("git rev-parse --is-bare-repository --absolute-git-dir --git-common-dir").DontLog().RunWithOutput()
+	if err != nil {
+		return nil, err
+	}
+
+	lines := utils.SplitLines(output)
+	if len(lines) != 3 {
+		return nil, errors.Errorf("unexpected output from git rev-parse: %s", output)
+	}
+
+	// before git 2.31 there's no way to ask for the common dir as an absolute
+	// path, and it may be given relative to the current directory
+	commonDir := lines[2]
+	if !filepath.IsAbs(commonDir) {
+		commonDir, err = filepath.Abs(commonDir)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// asking a bare repo for its top level is an error
+	topLevel := ""
+	if lines[0] != "true" {
+		output, err := self.cmd.New("git rev-parse --show-toplevel").DontLog().RunWithOutput()
+		if err != nil {
+			return nil, err
+		}
+		topLevel = strings.TrimSpace(output)
+	}
+
+	self.cachedRepoPaths = &repoPaths{
+		topLevel:  topLevel,
+		gitDir:    lines[1],
+		commonDir: commonDir,
+	}
+	return self.cachedRepoPaths, nil
+}
+
+// SetBypassHooks sets whether commits and pushes skip git hooks, e.g. to get
+// past a broken hook without changing any config. All of the commands share
+// the same GitCommon, so this applies to all of them.
+func (self *GitCommon) SetBypassHooks(value bool) {
+	var flag int32
+	if value {
+		flag = 1
+	}
+	atomic.StoreInt32(&self.bypassHooks, flag)
+}
+
+func (self *GitCommon) BypassHooks() bool {
+	return atomic.LoadInt32(&self.bypassHooks) == 1
+}
+
+// env vars which tell hook managers to do nothing, for the hooks that
+// --no-verify doesn't skip (e.g. prepare-commit-msg and post-commit)
+var hookBypassEnvVars = []string{"HUSKY=0", "LEFTHOOK=0"}
+
+// bypassHooksIfSet adds the env vars for bypassing hooks to a command that
+// supports --no-verify, if hooks are being bypassed. The caller adds the flag.
+func (self *GitCommon) bypassHooksIfSet(cmdObj oscommands.ICmdObj) oscommands.ICmdObj {
+	if self.BypassHooks() {
+		cmdObj.AddEnvVars(hookBypassEnvVars...)
+	}
+
+	return cmdObj
+}
@@ -0,0 +1,122 @@
+package git_commands
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-errors/errors"
+	"github.com/jesseduffield/lazygit/pkg/commands/oscommands"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGitCommonRepoPaths(t *testing.T) {
+	wd, err := os.Getwd()
+	assert.NoError(t, err)
+
+	const revParseCmd = `git rev-parse --is-bare-repository --absolute-git-dir --git-common-dir`
+
+	type scenario struct {
+		testName          string
+		runner            *oscommands.FakeCmdObjRunner
+		expectedTopLevel  string
+		expectedGitDir    string
+		expectedCommonDir string
+		expectedError     string
+	}
+
+	scenarios := []scenario{
+		{
+			testName: "regular repo",
+			runner: oscommands.NewFakeRunner(t).
+				Expect(revParseCmd, "false\n/home/user/repo/.git\n/home/user/repo/.git\n", nil).
+				Expect(`git rev-parse --show-toplevel`, "/home/user/repo\n", nil),
+			expectedTopLevel:  "/home/user/repo",
+			expectedGitDir:    "/home/user/repo/.git",
+			expectedCommonDir: "/home/user/repo/.git",
+		},
+		{
+			testName: "linked worktree",
+			runner: oscommands.NewFakeRunner(t).
+				Expect(revParseCmd, "false\n/home/user/repo/.git/worktrees/feature\n/home/user/repo/.git\n", nil).
+				Expect(`git rev-parse --show-toplevel`, "/home/user/feature\n", nil),
+			expectedTopLevel:  "/home/user/feature",
+			expectedGitDir:    "/home/user/repo/.git/worktrees/feature",
+			expectedCommonDir: "/home/user/repo/.git",
+		},
+		{
+			testName: "submodule",
+			runner: oscommands.NewFakeRunner(t).
+				Expect(revParseCmd, "false\n/home/user/repo/.git/modules/vendor/lib\n/home/user/repo/.git/modules/vendor/lib\n", nil).
+				Expect(`git rev-parse --show-toplevel`, "/home/user/repo/vendor/lib\n", nil),
+			expectedTopLevel:  "/home/user/repo/vendor/lib",
+			expectedGitDir:    "/home/user/repo/.git/modules/vendor/lib",
+			expectedCommonDir: "/home/user/repo/.git/modules/vendor/lib",
+		},
+		{
+			testName: "common dir relative to the current directory",
+			runner: oscommands.NewFakeRunner(t).
+				Expect(revParseCmd, "false\n"+wd+"/.git\n.git\n", nil).
+				Expect(`git rev-parse --show-toplevel`, wd+"\n", nil),
+			expectedTopLevel:  wd,
+			expectedGitDir:    wd + "/.git",
+			expectedCommonDir: filepath.Join(wd, ".git"),
+		},
+		{
+			testName: "bare repo",
+			runner: oscommands.NewFakeRunner(t).
+				Expect(revParseCmd, "true\n/home/user/repo.git\n/home/user/repo.git\n", nil),
+			expectedTopLevel:  "",
+			expectedGitDir:    "/home/user/repo.git",
+			expectedCommonDir: "/home/user/repo.git",
+		},
+		{
+			testName: "not a repo",
+			runner: oscommands.NewFakeRunner(t).
+				Expect(revParseCmd, "", errors.New("fatal: not a git repository")),
+			expectedError: "fatal: not a git repository",
+		},
+		{
+			testName: "unexpected output",
+			runner: oscommands.NewFakeRunner(t).
+				Expect(revParseCmd, "false\n", nil),
+			expectedError: "unexpected output from git rev-parse: false\n",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			instance := buildGitCommon(commonDeps{runner: s.runner, resolveRepoPaths: true})
+
+			// every call after the first should be served from the cache
+			for i := 0; i < 2; i++ {
+				topLevel, gitDir, err := instance.RepoPaths()
+				if s.expectedError != "" {
+					assert.EqualError(t, err, s.expectedError)
+					break
+				}
+				assert.NoError(t, err)
+				assert.Equal(t, s.expectedTopLevel, topLevel)
+				assert.Equal(t, s.expectedGitDir, gitDir)
+
+				gitDir, err = instance.GitDir()
+				assert.NoError(t, err)
+				assert.Equal(t, s.expectedGitDir, gitDir)
+
+				commonDir, err := instance.CommonDir()
+				assert.NoError(t, err)
+				assert.Equal(t, s.expectedCommonDir, commonDir)
+			}
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestGitCommonGitVersionAndFeatures(t *testing.T) {
+	gitCommon := buildGitCommon(commonDeps{gitVersion: &GitVersion{2, 23, 0, "(Apple Git-122)"}})
+
+	assert.Equal(t, &GitVersion{2, 23, 0, "(Apple Git-122)"}, gitCommon.GitVersion())
+	assert.True(t, gitCommon.Features().SupportsRestore)
+	assert.False(t, gitCommon.Features().SupportsRebaseEmptyKeep)
+}
@@ -65,6 +65,13 @@ func (self *ConfigCommands) UsingGpg() bool {
 		return false
 	}
 
+	return self.GpgSigningEnabled()
+}
+
+// GpgSigningEnabled tells us whether git is configured to sign commits. Unlike
+// UsingGpg, this ignores the overrideGpg setting, because git will sign the
+// commit either way.
+func (self *ConfigCommands) GpgSigningEnabled() bool {
 	return self.gitConfig.GetBool("commit.gpgsign")
 }
 
@@ -72,15 +79,35 @@ func (self *ConfigCommands) GetCoreEditor() string {
 	return self.gitConfig.Get("core.editor")
 }
 
+// GetCommitTemplatePath returns the path to the commit message template set by
+// commit.template, if any. We have git expand a leading '~' for us.
+func (self *ConfigCommands) GetCommitTemplatePath() string {
+	return strings.TrimSpace(self.gitConfig.GetGeneral("--path --get commit.template"))
+}
+
 // GetRemoteURL returns current repo remote url
 func (self *ConfigCommands) GetRemoteURL() string {
 	return self.gitConfig.Get("remote.origin.url")
 }
 
+// GetCoreAutoCRLF returns core.autocrlf, which is 'true', 'input' or 'false',
+// or empty if it isn't set
+func (self *ConfigCommands) GetCoreAutoCRLF() string {
+	return self.gitConfig.Get("core.autocrlf")
+}
+
 func (self *ConfigCommands) GetShowUntrackedFiles() string {
 	return self.gitConfig.Get("status.showUntrackedFiles")
 }
 
+func (self *ConfigCommands) GetSparseCheckout() bool {
+	return self.gitConfig.GetBool("core.sparseCheckout")
+}
+
+func (self *ConfigCommands) GetSparseCheckoutCone() bool {
+	return self.gitConfig.GetBool("core.sparseCheckoutCone")
+}
+
 // this determines whether the user has configured to push to the remote branch of the same name as the current or not
 func (self *ConfigCommands) GetPushToCurrent() bool {
 	return self.gitConfig.Get("push.default") == "current"
@@ -99,3 +126,10 @@ func (self *ConfigCommands) Branches() (map[string]*config.Branch, error) {
 func (self *ConfigCommands) GetGitFlowPrefixes() string {
 	return self.gitConfig.GetGeneral("--local --get-regexp gitflow.prefix")
 }
+
+// returns the raw output of `git config -z --get-regexp ^alias\.` i.e.
+// 'alias.<name>\n<command>' entries separated by NUL bytes. We use -z because
+// an alias's command can span multiple lines.
+func (self *ConfigCommands) GetAliases() string {
+	return self.gitConfig.GetGeneral("-z --get-regexp ^alias\\.")
+}
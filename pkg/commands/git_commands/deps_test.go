@@ -19,9 +19,13 @@ type commonDeps struct {
 	gitConfig  *git_config.FakeGitConfig
 	getenv     func(string) string
 	removeFile func(string) error
+	trashFile  func(string) error
 	dotGitDir  string
-	common     *common.Common
-	cmd        *oscommands.CmdObjBuilder
+	// ask git for the repo's paths as GitCommon normally would, rather than
+	// assuming that dotGitDir is both the git dir and the common dir
+	resolveRepoPaths bool
+	common           *common.Common
+	cmd              *oscommands.CmdObjBuilder
 }
 
 func buildGitCommon(deps commonDeps) *GitCommon {
@@ -53,6 +57,7 @@ func buildGitCommon(deps commonDeps) *GitCommon {
 	if gitCommon.version == nil {
 		gitCommon.version = &GitVersion{2, 0, 0, ""}
 	}
+	gitCommon.features = NewFeatures(gitCommon.version)
 
 	gitConfig := deps.gitConfig
 	if gitConfig == nil {
@@ -72,17 +77,26 @@ func buildGitCommon(deps commonDeps) *GitCommon {
 		removeFile = func(string) error { return errors.New("unexpected call to removeFile") }
 	}
 
+	trashFile := deps.trashFile
+	if trashFile == nil {
+		trashFile = func(string) error { return errors.New("unexpected call to trashFile") }
+	}
+
 	gitCommon.os = oscommands.NewDummyOSCommandWithDeps(oscommands.OSCommandDeps{
 		Common:       gitCommon.Common,
 		GetenvFn:     getenv,
 		Cmd:          cmd,
 		RemoveFileFn: removeFile,
+		TrashFileFn:  trashFile,
 		TempDir:      os.TempDir(),
 	})
 
-	gitCommon.dotGitDir = deps.dotGitDir
-	if gitCommon.dotGitDir == "" {
-		gitCommon.dotGitDir = ".git"
+	if !deps.resolveRepoPaths {
+		dotGitDir := deps.dotGitDir
+		if dotGitDir == "" {
+			dotGitDir = ".git"
+		}
+		gitCommon.cachedRepoPaths = &repoPaths{gitDir: dotGitDir, commonDir: dotGitDir}
 	}
 
 	return gitCommon
@@ -95,7 +109,7 @@ func buildRepo() *gogit.Repository {
 }
 
 func buildFileLoader(gitCommon *GitCommon) *FileLoader {
-	return NewFileLoader(gitCommon.Common, gitCommon.cmd, gitCommon.config)
+	return NewFileLoader(gitCommon.Common, gitCommon.cmd, gitCommon.config, NewSubmoduleCommands(gitCommon).GetStatuses, gitCommon.GitDir)
 }
 
 func buildSubmoduleCommands(deps commonDeps) *SubmoduleCommands {
@@ -150,3 +164,15 @@ func buildBranchCommands(deps commonDeps) *BranchCommands {
 
 	return NewBranchCommands(gitCommon)
 }
+
+func buildStatusCommands(deps commonDeps) *StatusCommands {
+	gitCommon := buildGitCommon(deps)
+
+	return NewStatusCommands(gitCommon)
+}
+
+func buildAliasCommands(deps commonDeps) *AliasCommands {
+	gitCommon := buildGitCommon(deps)
+
+	return NewAliasCommands(gitCommon)
+}
@@ -1,17 +1,45 @@
 package git_commands
 
 import (
+	"fmt"
 	"os"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/go-errors/errors"
+	"github.com/jesseduffield/lazygit/pkg/commands/models"
+	"github.com/jesseduffield/lazygit/pkg/commands/oscommands"
 	"github.com/jesseduffield/lazygit/pkg/config"
 	"github.com/jesseduffield/lazygit/pkg/utils"
 )
 
 type FileCommands struct {
 	*GitCommon
+
+	// the result of ResolveEditor, along with the config it was resolved from
+	// so that we know when to resolve it again
+	resolvedEditor         *EditorConfig
+	resolvedEditorOSConfig config.OSConfig
+	resolvedEditorMutex    sync.Mutex
+}
+
+type EditorKind int
+
+const (
+	EDITOR_KIND_OTHER EditorKind = iota
+	EDITOR_KIND_VIM
+	EDITOR_KIND_NVIM
+	EDITOR_KIND_EMACS
+	EDITOR_KIND_NANO
+	EDITOR_KIND_VSCODE
+	EDITOR_KIND_SUBLIME
+)
+
+type EditorConfig struct {
+	// the editor command as configured e.g. 'vim' or 'code -w'
+	Command string
+	Kind    EditorKind
 }
 
 func NewFileCommands(gitCommon *GitCommon) *FileCommands {
@@ -29,7 +57,17 @@ func (self *FileCommands) Cat(fileName string) (string, error) {
 	return string(buf), nil
 }
 
-func (self *FileCommands) GetEditCmdStrLegacy(filename string, lineNumber int) (string, error) {
+// ResolveEditor works out which editor to use from the lazygit config, the git
+// config or the environment, falling back to vi. The result is cached until
+// the user's OS config changes.
+func (self *FileCommands) ResolveEditor() (EditorConfig, error) {
+	self.resolvedEditorMutex.Lock()
+	defer self.resolvedEditorMutex.Unlock()
+
+	if self.resolvedEditor != nil && self.resolvedEditorOSConfig == self.UserConfig.OS {
+		return *self.resolvedEditor, nil
+	}
+
 	editor := self.UserConfig.OS.EditCommand
 
 	if editor == "" {
@@ -50,25 +88,56 @@ func (self *FileCommands) GetEditCmdStrLegacy(filename string, lineNumber int) (
 		}
 	}
 	if editor == "" {
-		return "", errors.New("No editor defined in config file, $GIT_EDITOR, $VISUAL, $EDITOR, or git config")
+		return EditorConfig{}, errors.New("No editor defined in config file, $GIT_EDITOR, $VISUAL, $EDITOR, or git config")
+	}
+
+	self.resolvedEditor = &EditorConfig{Command: editor, Kind: getEditorKind(editor)}
+	self.resolvedEditorOSConfig = self.UserConfig.OS
+
+	return *self.resolvedEditor, nil
+}
+
+func getEditorKind(editor string) EditorKind {
+	switch editor {
+	case "vi", "vim":
+		return EDITOR_KIND_VIM
+	case "nvim":
+		return EDITOR_KIND_NVIM
+	case "emacs":
+		return EDITOR_KIND_EMACS
+	case "nano":
+		return EDITOR_KIND_NANO
+	case "code":
+		return EDITOR_KIND_VSCODE
+	case "subl":
+		return EDITOR_KIND_SUBLIME
+	default:
+		return EDITOR_KIND_OTHER
+	}
+}
+
+func (self *FileCommands) GetEditCmdStrLegacy(filename string, lineNumber int) (string, error) {
+	editor, err := self.ResolveEditor()
+	if err != nil {
+		return "", err
 	}
 
 	templateValues := map[string]string{
-		"editor":   editor,
+		"editor":   editor.Command,
 		"filename": self.cmd.Quote(filename),
 		"line":     strconv.Itoa(lineNumber),
 	}
 
 	editCmdTemplate := self.UserConfig.OS.EditCommandTemplate
 	if len(editCmdTemplate) == 0 {
-		switch editor {
-		case "emacs", "nano", "vi", "vim", "nvim":
+		switch editor.Kind {
+		case EDITOR_KIND_VIM, EDITOR_KIND_NVIM, EDITOR_KIND_EMACS, EDITOR_KIND_NANO:
 			editCmdTemplate = "{{editor}} +{{line}} -- {{filename}}"
-		case "subl":
+		case EDITOR_KIND_SUBLIME:
 			editCmdTemplate = "{{editor}} -- {{filename}}:{{line}}"
-		case "code":
+		case EDITOR_KIND_VSCODE:
 			editCmdTemplate = "{{editor}} -r --goto -- {{filename}}:{{line}}"
-		default:
+		case EDITOR_KIND_OTHER:
 			editCmdTemplate = "{{editor}} -- {{filename}}"
 		}
 	}
@@ -112,6 +181,60 @@ func (self *FileCommands) GetEditAtLineCmdStr(filename string, lineNumber int) (
 	return cmdStr, editInTerminal
 }
 
+// EditFileAtLineCmdObj returns a shell command which opens the file in the
+// user's editor at the given line
+func (self *FileCommands) EditFileAtLineCmdObj(filename string, lineNumber int) oscommands.ICmdObj {
+	cmdStr, _ := self.GetEditAtLineCmdStr(filename, lineNumber)
+	return self.cmd.NewShell(cmdStr)
+}
+
+// EditFileAtConflictCmdObj returns a shell command which opens the file in the
+// user's editor at the first conflict marker, or at the first line if there
+// are no conflicts
+func (self *FileCommands) EditFileAtConflictCmdObj(filename string) (oscommands.ICmdObj, error) {
+	lineNumbers, err := conflictMarkerLineNumbers(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	lineNumber := 1
+	if len(lineNumbers) > 0 {
+		lineNumber = lineNumbers[0]
+	}
+
+	return self.EditFileAtLineCmdObj(filename, lineNumber), nil
+}
+
+// OpenShellCmdObj returns a command which starts an interactive shell at the
+// top level of the repo. The shell comes from the os.shell config, then $SHELL,
+// then the platform's default shell.
+func (self *FileCommands) OpenShellCmdObj() (oscommands.ICmdObj, error) {
+	repoDir, _, err := self.RepoPaths()
+	if err != nil {
+		return nil, err
+	}
+
+	var cmdObj oscommands.ICmdObj
+	if self.UserConfig.OS.Shell != "" {
+		// a command, which may have arguments
+		cmdObj = self.cmd.New(self.UserConfig.OS.Shell)
+	} else {
+		// a path, which may have spaces in it
+		shell := self.os.Getenv("SHELL")
+		if shell == "" {
+			if self.os.Platform.OS == "windows" {
+				shell = "cmd.exe"
+			} else {
+				shell = "/bin/sh"
+			}
+		}
+		cmdObj = self.cmd.NewFromArgs([]string{shell})
+	}
+
+	cmdObj.GetCmd().Dir = repoDir
+	return cmdObj, nil
+}
+
 func (self *FileCommands) GetEditAtLineAndWaitCmdStr(filename string, lineNumber int) string {
 	// Legacy support for old config; to be removed at some point
 	if self.UserConfig.OS.EditAtLineAndWait == "" && self.UserConfig.OS.EditCommandTemplate != "" {
@@ -153,3 +276,103 @@ func (self *FileCommands) guessDefaultEditor() string {
 
 	return editor
 }
+
+type BlameOpts struct {
+	// 1-based, inclusive line range to blame. A StartLine of 0 blames the whole
+	// file and an EndLine of 0 blames through to the end of the file.
+	StartLine        int
+	EndLine          int
+	IgnoreWhitespace bool
+}
+
+// Blame returns each line of the file along with the commit that last changed it
+func (self *FileCommands) Blame(fileName string, opts BlameOpts) ([]models.BlameLine, error) {
+	lineRange := ""
+	if opts.StartLine > 0 {
+		lineRange = fmt.Sprintf(" -L %d,", opts.StartLine)
+		if opts.EndLine > 0 {
+			lineRange += strconv.Itoa(opts.EndLine)
+		}
+	}
+
+	whitespaceFlag := ""
+	if opts.IgnoreWhitespace {
+		whitespaceFlag = " -w"
+	}
+
+	cmdStr := fmt.Sprintf(
+		"git blame --porcelain%s%s -- %s",
+		whitespaceFlag,
+		lineRange,
+		self.cmd.Quote(fileName),
+	)
+
+	output, err := self.cmd.New(cmdStr).DontLog().RunWithOutput()
+	if err != nil {
+		return nil, err
+	}
+
+	return parseBlamePorcelain(output)
+}
+
+type blameCommitInfo struct {
+	authorName    string
+	unixTimestamp int64
+}
+
+// git only prints a commit's details the first time the commit appears in the
+// output, so we remember them for the lines that come later
+func parseBlamePorcelain(output string) ([]models.BlameLine, error) {
+	commits := map[string]*blameCommitInfo{}
+	lines := []models.BlameLine{}
+
+	var current *models.BlameLine
+	for _, line := range strings.Split(output, "\n") {
+		if strings.HasPrefix(line, "\t") {
+			if current == nil {
+				return nil, errors.Errorf("unexpected line in git blame output: %s", line)
+			}
+			info := commits[current.Sha]
+			current.AuthorName = info.authorName
+			current.UnixTimestamp = info.unixTimestamp
+			current.Content = strings.TrimPrefix(line, "\t")
+			lines = append(lines, *current)
+			current = nil
+			continue
+		}
+
+		if current == nil {
+			// this is a header line: '<sha> <orig line> <final line>[ <lines in group>]'
+			fields := strings.Fields(line)
+			if len(fields) < 3 {
+				if line == "" {
+					continue
+				}
+				return nil, errors.Errorf("unexpected line in git blame output: %s", line)
+			}
+			lineNumber, err := strconv.Atoi(fields[2])
+			if err != nil {
+				return nil, err
+			}
+			current = &models.BlameLine{Sha: fields[0], LineNumber: lineNumber}
+			if _, ok := commits[current.Sha]; !ok {
+				commits[current.Sha] = &blameCommitInfo{}
+			}
+			continue
+		}
+
+		key, value, _ := strings.Cut(line, " ")
+		switch key {
+		case "author":
+			commits[current.Sha].authorName = value
+		case "author-time":
+			timestamp, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			commits[current.Sha].unixTimestamp = timestamp
+		}
+	}
+
+	return lines, nil
+}
@@ -1,51 +1,321 @@
 package git_commands
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/go-errors/errors"
 	"github.com/jesseduffield/lazygit/pkg/commands/models"
 	"github.com/jesseduffield/lazygit/pkg/commands/oscommands"
 	"github.com/jesseduffield/lazygit/pkg/common"
+	"github.com/jesseduffield/lazygit/pkg/utils"
+	"github.com/sahilm/fuzzy"
+	"github.com/samber/lo"
 )
 
 type FileLoaderConfig interface {
 	GetShowUntrackedFiles() string
+	GetSparseCheckout() bool
+	GetSparseCheckoutCone() bool
 }
 
 type FileLoader struct {
 	*common.Common
-	cmd         oscommands.ICmdObjBuilder
-	config      FileLoaderConfig
-	getFileType func(string) string
+	cmd                  oscommands.ICmdObjBuilder
+	config               FileLoaderConfig
+	getFileType          func(string) string
+	getSubmoduleStatuses func() (map[string]*models.SubmoduleStatus, error)
+	getGitDir            func() (string, error)
+
+	// see sparseCheckoutMatcher()
+	sparseCheckoutCache *sparseCheckoutCache
+	sparseCheckoutMutex sync.Mutex
+
+	// results of IsBinary, which we forget whenever the files are reloaded
+	binaryCache      map[string]bool
+	binaryCacheMutex sync.Mutex
 }
 
-func NewFileLoader(cmn *common.Common, cmd oscommands.ICmdObjBuilder, config FileLoaderConfig) *FileLoader {
+func NewFileLoader(
+	cmn *common.Common,
+	cmd oscommands.ICmdObjBuilder,
+	config FileLoaderConfig,
+	getSubmoduleStatuses func() (map[string]*models.SubmoduleStatus, error),
+	getGitDir func() (string, error),
+) *FileLoader {
 	return &FileLoader{
-		Common:      cmn,
-		cmd:         cmd,
-		getFileType: oscommands.FileType,
-		config:      config,
+		Common:               cmn,
+		cmd:                  cmd,
+		getFileType:          oscommands.FileType,
+		config:               config,
+		getSubmoduleStatuses: getSubmoduleStatuses,
+		getGitDir:            getGitDir,
 	}
 }
 
 type GetStatusFileOptions struct {
 	NoRenames bool
+	// parse `git status --porcelain=v2`, which tells us rename scores and the
+	// state of submodules, rather than the v1 format
+	PorcelainV2 bool
+	// leave out the files which are outside of a sparse checkout. By default
+	// we keep them, marked as SparseExcluded, because they can still have
+	// changes that the user needs to know about.
+	HideSparseExcluded bool
+	// count the lines added and deleted in each file. This costs us a couple of
+	// extra git calls, so it's opt-in.
+	IncludeDiffStats bool
+	// note which files have had their mode changed, e.g. by being made
+	// executable. Like diff stats, this is opt-in because it costs a couple of
+	// extra git calls.
+	IncludeModeChanges bool
+	// fill in SubmoduleStatus for the files which are submodules. This means
+	// reading .gitmodules and another git status call, so it's opt-in too.
+	IncludeSubmoduleStatuses bool
+	// also load the files that git ignores, marked as Ignored
+	IncludeIgnored bool
+	// how ignored files are listed if IncludeIgnored is set. Defaults to
+	// IGNORED_MODE_TRADITIONAL.
+	IgnoredMode IgnoredMode
+	// list a wholly untracked directory as a single entry, marked as
+	// IsCollapsedDir, rather than listing every file in it. This spares git
+	// from walking e.g. an unignored node_modules, whatever the
+	// status.showUntrackedFiles config says. Use LoadCollapsedDir to get the
+	// files in such a directory.
+	CollapseUntrackedDirs bool
 }
 
+type IgnoredMode string
+
+const (
+	// an ignored directory is listed as a whole, rather than file by file,
+	// unless the untracked files setting is 'all'. This is git's default.
+	IGNORED_MODE_TRADITIONAL IgnoredMode = "traditional"
+	// only the paths that match an ignore pattern are listed, so if a pattern
+	// matches a directory we don't list the (possibly very many) files in it
+	IGNORED_MODE_MATCHING IgnoredMode = "matching"
+)
+
 func (self *FileLoader) GetStatusFiles(opts GetStatusFileOptions) []*models.File {
+	return self.GetStatusFilesWithSummary(opts).Files
+}
+
+type GetStatusFilesResult struct {
+	Files   []*models.File
+	Summary models.StatusSummary
+}
+
+// GetStatusFilesWithSummary is like GetStatusFiles but also counts the files by
+// kind of change, so that callers don't each need to scan the files themselves
+func (self *FileLoader) GetStatusFilesWithSummary(opts GetStatusFileOptions) GetStatusFilesResult {
+	// the background context is never cancelled, so there's no error
+	result, _ := self.GetStatusFilesWithSummaryContext(context.Background(), opts)
+	return result
+}
+
+// GetStatusFilesWithSummaryContext is like GetStatusFilesWithSummary, but git
+// status is killed if ctx is cancelled before it's done, in which case we
+// return ctx.Err()
+func (self *FileLoader) GetStatusFilesWithSummaryContext(ctx context.Context, opts GetStatusFileOptions) (GetStatusFilesResult, error) {
+	result, err := self.loadStatusFiles(ctx, opts)
+	if ctx.Err() != nil {
+		return GetStatusFilesResult{}, ctx.Err()
+	}
+	if err != nil {
+		self.Log.Error(err)
+		return GetStatusFilesResult{Files: []*models.File{}}, nil
+	}
+
+	return result, nil
+}
+
+// LoadStatusFiles is like GetStatusFiles but returns git status's error
+// instead of logging it, for callers that act on the files they get back
+func (self *FileLoader) LoadStatusFiles(opts GetStatusFileOptions) ([]*models.File, error) {
+	result, err := self.loadStatusFiles(context.Background(), opts)
+	return result.Files, err
+}
+
+func (self *FileLoader) loadStatusFiles(ctx context.Context, opts GetStatusFileOptions) (GetStatusFilesResult, error) {
+	self.clearBinaryCache()
+
+	statuses, err := self.gitStatus(ctx, self.gitStatusOptions(opts))
+	if err != nil {
+		return GetStatusFilesResult{}, err
+	}
+	files := self.filesFromStatuses(statuses)
+
+	files = self.applySparseCheckout(files, opts.HideSparseExcluded)
+	if opts.IncludeSubmoduleStatuses {
+		self.setSubmoduleStatuses(files)
+	}
+	if opts.IncludeDiffStats {
+		self.setDiffStats(files)
+	}
+	if opts.IncludeModeChanges {
+		self.setModeChanges(files)
+	}
+
+	summary := models.StatusSummary{}
+	for _, file := range files {
+		summary.Add(file)
+	}
+
+	return GetStatusFilesResult{Files: files, Summary: summary}, nil
+}
+
+// FindStatusFiles loads the files like GetStatusFiles, keeping only those whose
+// path matches the query, best matches first. The characters of the query need
+// to appear in the path in order, but not necessarily next to each other, so
+// e.g. 'fb' matches 'foo/bar'. Case is ignored. An empty query matches every
+// file, leaving them in their usual order.
+func (self *FileLoader) FindStatusFiles(query string, opts GetStatusFileOptions) ([]*models.File, error) {
+	files, err := self.LoadStatusFiles(opts)
+	if err != nil {
+		return nil, err
+	}
+	if query == "" {
+		return files, nil
+	}
+
+	paths := make([]string, 0, len(files))
+	for _, file := range files {
+		paths = append(paths, file.Name)
+	}
+
+	// fuzzy.Find already sorts its matches by score, keeping ties in path order
+	matches := fuzzy.Find(query, paths)
+
+	result := make([]*models.File, 0, len(matches))
+	for _, match := range matches {
+		result = append(result, files[match.Index])
+	}
+
+	return result, nil
+}
+
+func (self *FileLoader) gitStatusOptions(opts GetStatusFileOptions) GitStatusOptions {
 	// check if config wants us ignoring untracked files
 	untrackedFilesSetting := self.config.GetShowUntrackedFiles()
 
 	if untrackedFilesSetting == "" {
 		untrackedFilesSetting = "all"
 	}
+	if opts.CollapseUntrackedDirs && untrackedFilesSetting != "no" {
+		untrackedFilesSetting = "normal"
+	}
 	untrackedFilesArg := fmt.Sprintf("--untracked-files=%s", untrackedFilesSetting)
 
-	statuses, err := self.GitStatus(GitStatusOptions{NoRenames: opts.NoRenames, UntrackedFilesArg: untrackedFilesArg})
+	ignoredArg := ""
+	if opts.IncludeIgnored {
+		ignoredMode := opts.IgnoredMode
+		if ignoredMode == "" {
+			ignoredMode = IGNORED_MODE_TRADITIONAL
+		}
+		ignoredArg = fmt.Sprintf("--ignored=%s", ignoredMode)
+	}
+
+	return GitStatusOptions{
+		NoRenames:         opts.NoRenames,
+		UntrackedFilesArg: untrackedFilesArg,
+		IgnoredArg:        ignoredArg,
+		PorcelainV2:       opts.PorcelainV2,
+	}
+}
+
+// the number of files GetStatusFilesStream parses before handing them over
+const statusStreamBatchSize = 500
+
+// GetStatusFilesStream is like GetStatusFiles, but rather than waiting for git
+// status to finish, it hands over the files in batches as git lists them, so
+// that in a huge repo we can show the first files while git is still working
+// on the rest. If ctx is cancelled, we stop git and return ctx.Err(), having
+// handed over no more batches.
+//
+// Diff stats and mode changes each need a diff of the whole working tree, which
+// would hold up the first batch, so we return an error if IncludeDiffStats or
+// IncludeModeChanges is set. The same goes for PorcelainV2, which we don't
+// parse line by line.
+func (self *FileLoader) GetStatusFilesStream(
+	ctx context.Context,
+	opts GetStatusFileOptions,
+	onBatch func([]*models.File),
+) error {
+	switch {
+	case opts.PorcelainV2:
+		return errors.New("PorcelainV2 is not supported when streaming the status")
+	case opts.IncludeDiffStats:
+		return errors.New("IncludeDiffStats is not supported when streaming the status")
+	case opts.IncludeModeChanges:
+		return errors.New("IncludeModeChanges is not supported when streaming the status")
+	}
+
+	self.clearBinaryCache()
+
+	isIncluded := self.sparseCheckoutMatcher()
+	var submoduleStatuses map[string]*models.SubmoduleStatus
+	if opts.IncludeSubmoduleStatuses {
+		submoduleStatuses = self.loadSubmoduleStatuses()
+	}
+
+	statusOpts := self.gitStatusOptions(opts)
+
+	batch := []FileStatus{}
+	flush := func() {
+		files := self.filesFromStatuses(batch)
+		batch = []FileStatus{}
+
+		if isIncluded != nil {
+			files = markSparseExcluded(files, isIncluded, opts.HideSparseExcluded)
+		}
+		if submoduleStatuses != nil {
+			for _, file := range files {
+				file.SubmoduleStatus = submoduleStatuses[file.Name]
+			}
+		}
+		if len(files) > 0 {
+			onBatch(files)
+		}
+	}
+
+	err := self.gitStatusCmdObj(statusOpts, false).WithContext(ctx).CheckExitStatus().RunAndProcessLines(func(line string) (bool, error) {
+		if ctx.Err() != nil {
+			return true, nil
+		}
+
+		status, ok := parseStatusLine(line)
+		if !ok {
+			return false, nil
+		}
+
+		batch = append(batch, status)
+		if len(batch) >= statusStreamBatchSize {
+			flush()
+		}
+
+		return false, nil
+	})
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
 	if err != nil {
-		self.Log.Error(err)
+		return err
 	}
+
+	flush()
+
+	return nil
+}
+
+func (self *FileLoader) filesFromStatuses(statuses []FileStatus) []*models.File {
 	files := []*models.File{}
 
 	for _, status := range statuses {
@@ -55,23 +325,506 @@ func (self *FileLoader) GetStatusFiles(opts GetStatusFileOptions) []*models.File
 		}
 
 		file := &models.File{
-			Name:          status.Name,
-			PreviousName:  status.PreviousName,
-			DisplayString: status.StatusString,
-			Type:          self.getFileType(status.Name),
+			Name:            status.Name,
+			PreviousName:    status.PreviousName,
+			DisplayString:   status.StatusString,
+			Type:            self.getFileType(status.Name),
+			RenameScore:     status.RenameScore,
+			SubmoduleStatus: status.SubmoduleStatus,
 		}
 
 		models.SetStatusFields(file, status.Change)
+		// with untracked files listed as 'normal', git gives a directory in
+		// which nothing is tracked as a single entry with a trailing slash
+		file.IsCollapsedDir = !file.Tracked && !file.Ignored && strings.HasSuffix(file.Name, "/")
 		files = append(files, file)
 	}
 
 	return files
 }
 
+// LoadCollapsedDir loads the untracked files inside a directory which
+// GetStatusFiles listed as a single entry (see IsCollapsedDir)
+func (self *FileLoader) LoadCollapsedDir(dir string) ([]*models.File, error) {
+	statuses, err := self.GitStatus(GitStatusOptions{
+		UntrackedFilesArg: "--untracked-files=all",
+		Paths:             []string{dir},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return self.filesFromStatuses(statuses), nil
+}
+
+// marks the files which are outside of the sparse checkout (if the repo is one),
+// and leaves them out if hideExcluded is true
+func (self *FileLoader) applySparseCheckout(files []*models.File, hideExcluded bool) []*models.File {
+	isIncluded := self.sparseCheckoutMatcher()
+	if isIncluded == nil {
+		return files
+	}
+
+	return markSparseExcluded(files, isIncluded, hideExcluded)
+}
+
+// the sparse checkout patterns as of when the sparse-checkout file last changed
+type sparseCheckoutCache struct {
+	cone       bool
+	modTime    time.Time
+	size       int64
+	isIncluded func(path string) bool
+}
+
+// returns nil if the repo isn't a sparse checkout. Rather than asking git for
+// the patterns on every refresh, we hang on to them until the sparse-checkout
+// file changes, which is what 'git sparse-checkout set' etc. write to.
+func (self *FileLoader) sparseCheckoutMatcher() func(path string) bool {
+	if !self.config.GetSparseCheckout() {
+		return nil
+	}
+	cone := self.config.GetSparseCheckoutCone()
+
+	gitDir, err := self.getGitDir()
+	if err != nil {
+		self.Log.Error(err)
+		return nil
+	}
+	fileInfo, err := os.Stat(filepath.Join(gitDir, "info", "sparse-checkout"))
+	if err != nil {
+		self.Log.Error(err)
+		return nil
+	}
+
+	self.sparseCheckoutMutex.Lock()
+	defer self.sparseCheckoutMutex.Unlock()
+
+	cache := self.sparseCheckoutCache
+	if cache != nil && cache.cone == cone && cache.modTime.Equal(fileInfo.ModTime()) && cache.size == fileInfo.Size() {
+		return cache.isIncluded
+	}
+
+	// in non-cone mode git also prints a warning to stderr, which we don't want
+	output, _, err := self.cmd.New("git sparse-checkout list").DontLog().RunWithOutputs()
+	if err != nil {
+		self.Log.Error(err)
+		return nil
+	}
+
+	self.sparseCheckoutCache = &sparseCheckoutCache{
+		cone:       cone,
+		modTime:    fileInfo.ModTime(),
+		size:       fileInfo.Size(),
+		isIncluded: sparseCheckoutMatcher(utils.SplitLines(output), cone),
+	}
+	return self.sparseCheckoutCache.isIncluded
+}
+
+func markSparseExcluded(files []*models.File, isIncluded func(path string) bool, hideExcluded bool) []*models.File {
+	result := make([]*models.File, 0, len(files))
+	for _, file := range files {
+		file.SparseExcluded = !isIncluded(file.Name)
+		if file.SparseExcluded && hideExcluded {
+			continue
+		}
+		result = append(result, file)
+	}
+
+	return result
+}
+
+func (self *FileLoader) setSubmoduleStatuses(files []*models.File) {
+	submoduleStatuses := self.loadSubmoduleStatuses()
+	if submoduleStatuses == nil {
+		return
+	}
+
+	for _, file := range files {
+		file.SubmoduleStatus = submoduleStatuses[file.Name]
+	}
+}
+
+func (self *FileLoader) loadSubmoduleStatuses() map[string]*models.SubmoduleStatus {
+	if self.getSubmoduleStatuses == nil {
+		return nil
+	}
+
+	submoduleStatuses, err := self.getSubmoduleStatuses()
+	if err != nil {
+		self.Log.Error(err)
+		return nil
+	}
+
+	return submoduleStatuses
+}
+
+func (self *FileLoader) setDiffStats(files []*models.File) {
+	stagedStats, err := self.DiffStats(true)
+	if err != nil {
+		self.Log.Error(err)
+		return
+	}
+
+	unstagedStats, err := self.DiffStats(false)
+	if err != nil {
+		self.Log.Error(err)
+		return
+	}
+
+	for _, file := range files {
+		staged, unstaged := stagedStats[file.Name], unstagedStats[file.Name]
+		file.LinesAdded = staged.LinesAdded + unstaged.LinesAdded
+		file.LinesDeleted = staged.LinesDeleted + unstaged.LinesDeleted
+		file.PurelyAdditive = file.Tracked && !file.Added && !file.Deleted &&
+			file.LinesAdded > 0 && file.LinesDeleted == 0
+	}
+}
+
+// IsBinary tells us whether git treats the file as binary when diffing it,
+// either because .gitattributes says so or because its content looks binary.
+// The result is cached until the files are next loaded.
+func (self *FileLoader) IsBinary(fileName string) (bool, error) {
+	self.binaryCacheMutex.Lock()
+	defer self.binaryCacheMutex.Unlock()
+
+	if isBinary, ok := self.binaryCache[fileName]; ok {
+		return isBinary, nil
+	}
+
+	isBinary, err := self.isBinaryAux(fileName)
+	if err != nil {
+		return false, err
+	}
+
+	if self.binaryCache == nil {
+		self.binaryCache = map[string]bool{}
+	}
+	self.binaryCache[fileName] = isBinary
+
+	return isBinary, nil
+}
+
+func (self *FileLoader) isBinaryAux(fileName string) (bool, error) {
+	quotedFileName := self.cmd.Quote(fileName)
+
+	output, err := self.cmd.New("git check-attr -z diff -- " + quotedFileName).DontLog().RunWithOutput()
+	if err != nil {
+		return false, err
+	}
+
+	// output looks like <path>\x00diff\x00<value>\x00. The 'binary' attribute
+	// is a macro which unsets 'diff', among others.
+	fields := strings.Split(output, "\x00")
+	if len(fields) < 3 {
+		return false, fmt.Errorf("unexpected output from git check-attr: %s", output)
+	}
+	switch fields[2] {
+	case "unset":
+		return true, nil
+	case "unspecified":
+		// git decides for itself, based on the content
+	default:
+		// either set explicitly or set to a diff driver
+		return false, nil
+	}
+
+	file, err := os.Open(fileName)
+	if err == nil {
+		defer file.Close()
+		return looksBinary(file)
+	}
+	if !os.IsNotExist(err) {
+		return false, err
+	}
+
+	// the file has been deleted from the working tree, so we look at the
+	// version in the index instead
+	content, err := self.cmd.New("git cat-file blob " + self.cmd.Quote(":"+fileName)).DontLog().RunWithOutput()
+	if err != nil {
+		return false, err
+	}
+	return looksBinary(strings.NewReader(content))
+}
+
+// uses the same heuristic as git: content is binary if there's a NUL byte
+// in the first 8000 bytes
+func looksBinary(reader io.Reader) (bool, error) {
+	buf := make([]byte, 8000)
+	n, err := io.ReadFull(reader, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return false, err
+	}
+
+	return bytes.IndexByte(buf[:n], 0) != -1, nil
+}
+
+func (self *FileLoader) clearBinaryCache() {
+	self.binaryCacheMutex.Lock()
+	defer self.binaryCacheMutex.Unlock()
+
+	self.binaryCache = nil
+}
+
+type DiffStat struct {
+	LinesAdded   int
+	LinesDeleted int
+	// git doesn't count lines for binary files
+	Binary bool
+}
+
+// DiffStats returns the number of lines added and deleted per file, either for
+// the staged changes or for the unstaged changes. Renamed files are keyed by
+// their new name.
+func (self *FileLoader) DiffStats(cached bool) (map[string]DiffStat, error) {
+	cachedFlag := ""
+	if cached {
+		cachedFlag = " --cached"
+	}
+
+	output, err := self.cmd.New("git diff" + cachedFlag + " --numstat -z").DontLog().RunWithOutput()
+	if err != nil {
+		return nil, err
+	}
+
+	return parseNumstat(output), nil
+}
+
+// with -z, each entry looks like '<added>\t<deleted>\t<path>', except that for a
+// rename the path is empty and the old and new paths follow as separate entries
+func parseNumstat(output string) map[string]DiffStat {
+	stats := map[string]DiffStat{}
+
+	entries := strings.Split(output, "\x00")
+	for i := 0; i < len(entries); i++ {
+		fields := strings.SplitN(entries[i], "\t", 3)
+		if len(fields) < 3 {
+			continue
+		}
+
+		path := fields[2]
+		if path == "" {
+			if i+2 >= len(entries) {
+				break
+			}
+			path = entries[i+2]
+			i += 2
+		}
+
+		if fields[0] == "-" {
+			stats[path] = DiffStat{Binary: true}
+			continue
+		}
+
+		added, _ := strconv.Atoi(fields[0])
+		deleted, _ := strconv.Atoi(fields[1])
+		stats[path] = DiffStat{LinesAdded: added, LinesDeleted: deleted}
+	}
+
+	return stats
+}
+
+func (self *FileLoader) setModeChanges(files []*models.File) {
+	stagedChanges, err := self.ModeChanges(true)
+	if err != nil {
+		self.Log.Error(err)
+		return
+	}
+
+	unstagedChanges, err := self.ModeChanges(false)
+	if err != nil {
+		self.Log.Error(err)
+		return
+	}
+
+	for _, file := range files {
+		staged, hasStaged := stagedChanges[file.Name]
+		unstaged, hasUnstaged := unstagedChanges[file.Name]
+		if !hasStaged && !hasUnstaged {
+			continue
+		}
+
+		// the staged diff goes from HEAD to the index and the unstaged diff from
+		// the index to the working tree, so between them they take us from HEAD
+		// to the working tree
+		oldMode, newMode := unstaged.OldMode, unstaged.NewMode
+		if hasStaged {
+			oldMode = staged.OldMode
+			if !hasUnstaged {
+				newMode = staged.NewMode
+			}
+		}
+
+		// a file that's been added or deleted has no mode on one side, and that
+		// doesn't count as changing it
+		if oldMode == noMode || newMode == noMode || oldMode == newMode {
+			continue
+		}
+
+		file.ModeChanged = true
+		file.NewMode = newMode
+	}
+}
+
+// what git diff gives as the mode of a file on the side of the diff where it
+// doesn't exist
+const noMode = "000000"
+
+type ModeChange struct {
+	OldMode string
+	NewMode string
+}
+
+// ModeChanges returns the old and new modes of each changed file, either for
+// the staged changes or for the unstaged changes. Files whose mode hasn't
+// changed are included too, with the same old and new mode. Renamed files are
+// keyed by their new name.
+func (self *FileLoader) ModeChanges(cached bool) (map[string]ModeChange, error) {
+	cachedFlag := ""
+	if cached {
+		cachedFlag = " --cached"
+	}
+
+	output, err := self.cmd.New("git diff" + cachedFlag + " --raw -z").DontLog().RunWithOutput()
+	if err != nil {
+		return nil, err
+	}
+
+	return parseRawModes(output), nil
+}
+
+// with -z, each entry looks like ':<old mode> <new mode> <old sha> <new sha>
+// <status>' followed by the path as a separate entry, or for a rename or copy,
+// the old and new paths as two separate entries
+func parseRawModes(output string) map[string]ModeChange {
+	changes := map[string]ModeChange{}
+
+	entries := strings.Split(output, "\x00")
+	for i := 0; i < len(entries); i++ {
+		if !strings.HasPrefix(entries[i], ":") {
+			continue
+		}
+
+		fields := strings.Fields(entries[i][1:])
+		if len(fields) < 5 {
+			continue
+		}
+
+		pathIndex := i + 1
+		if status := fields[4]; strings.HasPrefix(status, "R") || strings.HasPrefix(status, "C") {
+			pathIndex = i + 2
+		}
+		if pathIndex >= len(entries) {
+			break
+		}
+
+		changes[entries[pathIndex]] = ModeChange{OldMode: fields[0], NewMode: fields[1]}
+		i = pathIndex
+	}
+
+	return changes
+}
+
+// LoadConflictedFiles returns just the files with merge conflicts, which is much
+// cheaper than a full status scan when lots of other files have changed. The
+// files' statuses (e.g. 'UU' or 'DU') are the same as git status would give.
+func (self *FileLoader) LoadConflictedFiles() ([]*models.File, error) {
+	output, err := self.cmd.New("git ls-files --unmerged -z").DontLog().RunWithOutput()
+	if err != nil {
+		return nil, err
+	}
+
+	files := []*models.File{}
+	for _, entry := range parseUnmergedEntries(output) {
+		file := &models.File{
+			Name:          entry.path,
+			DisplayString: entry.change + " " + entry.path,
+			Type:          self.getFileType(entry.path),
+		}
+		models.SetStatusFields(file, entry.change)
+		files = append(files, file)
+	}
+
+	return files, nil
+}
+
+type unmergedEntry struct {
+	path   string
+	change string
+	// whether the common ancestor, ours and theirs are present, in that order
+	stages [3]bool
+}
+
+// `git ls-files --unmerged -z` gives an entry per stage of each unmerged path,
+// looking like '<mode> <sha> <stage>\t<path>'. Stage 1 is the common ancestor,
+// 2 is ours and 3 is theirs, and which of them are present tells us how the
+// path conflicts.
+func parseUnmergedEntries(output string) []unmergedEntry {
+	paths := []string{}
+	stagesByPath := map[string][3]bool{}
+
+	for _, entry := range strings.Split(output, "\x00") {
+		info, path, found := strings.Cut(entry, "\t")
+		if !found {
+			continue
+		}
+
+		fields := strings.Fields(info)
+		if len(fields) != 3 {
+			continue
+		}
+		stage, err := strconv.Atoi(fields[2])
+		if err != nil || stage < 1 || stage > 3 {
+			continue
+		}
+
+		stages, seen := stagesByPath[path]
+		if !seen {
+			paths = append(paths, path)
+		}
+		stages[stage-1] = true
+		stagesByPath[path] = stages
+	}
+
+	entries := make([]unmergedEntry, 0, len(paths))
+	for _, path := range paths {
+		stages := stagesByPath[path]
+		entries = append(entries, unmergedEntry{path: path, change: unmergedChange(stages), stages: stages})
+	}
+
+	return entries
+}
+
+// maps the stages present for an unmerged path to its two-letter status
+func unmergedChange(stages [3]bool) string {
+	base, ours, theirs := stages[0], stages[1], stages[2]
+
+	switch {
+	case base && ours && theirs:
+		return "UU"
+	case !base && ours && theirs:
+		return "AA"
+	case base && ours:
+		return "UD"
+	case base && theirs:
+		return "DU"
+	case base:
+		return "DD"
+	case ours:
+		return "AU"
+	default:
+		return "UA"
+	}
+}
+
 // GitStatus returns the file status of the repo
 type GitStatusOptions struct {
 	NoRenames         bool
 	UntrackedFilesArg string
+	// e.g. '--ignored=matching'. If empty, ignored files are left out.
+	IgnoredArg  string
+	PorcelainV2 bool
+	// if set, only the status of these paths is given
+	Paths []string
 }
 
 type FileStatus struct {
@@ -79,19 +832,26 @@ type FileStatus struct {
 	Change       string // ??, MM, AM, ...
 	Name         string
 	PreviousName string
+
+	// these are only set when using porcelain v2
+	RenameScore     int
+	SubmoduleStatus *models.SubmoduleStatus
 }
 
 func (c *FileLoader) GitStatus(opts GitStatusOptions) ([]FileStatus, error) {
-	noRenamesFlag := ""
-	if opts.NoRenames {
-		noRenamesFlag = " --no-renames"
-	}
+	return c.gitStatus(context.Background(), opts)
+}
 
-	statusLines, _, err := c.cmd.New(fmt.Sprintf("git status %s --porcelain -z%s", opts.UntrackedFilesArg, noRenamesFlag)).DontLog().RunWithOutputs()
+func (c *FileLoader) gitStatus(ctx context.Context, opts GitStatusOptions) ([]FileStatus, error) {
+	statusLines, _, err := c.gitStatusCmdObj(opts, true).WithContext(ctx).RunWithOutputs()
 	if err != nil {
 		return []FileStatus{}, err
 	}
 
+	if opts.PorcelainV2 {
+		return parsePorcelainV2Status(statusLines), nil
+	}
+
 	splitLines := strings.Split(statusLines, "\x00")
 	response := []FileStatus{}
 
@@ -109,8 +869,10 @@ func (c *FileLoader) GitStatus(opts GitStatusOptions) ([]FileStatus, error) {
 			PreviousName: "",
 		}
 
-		if strings.HasPrefix(status.Change, "R") {
-			// if a line starts with 'R' then the next line is the original file.
+		// a rename is followed by the original path. It's usually a staged
+		// rename, but a file added with intent-to-add (`git add -N`) can be
+		// renamed in the working tree, giving ' R'.
+		if strings.Contains(status.Change, "R") {
 			status.PreviousName = splitLines[i+1]
 			status.StatusString = fmt.Sprintf("%s %s -> %s", status.Change, status.PreviousName, status.Name)
 			i++
@@ -121,3 +883,193 @@ func (c *FileLoader) GitStatus(opts GitStatusOptions) ([]FileStatus, error) {
 
 	return response, nil
 }
+
+// without nulTerminated, entries are separated by newlines and paths with
+// unusual characters in them are quoted (see parseStatusLine)
+func (c *FileLoader) gitStatusCmdObj(opts GitStatusOptions, nulTerminated bool) oscommands.ICmdObj {
+	noRenamesFlag := ""
+	if opts.NoRenames {
+		noRenamesFlag = " --no-renames"
+	}
+
+	porcelainFlag := "--porcelain"
+	if opts.PorcelainV2 {
+		porcelainFlag = "--porcelain=v2"
+	}
+
+	ignoredFlag := ""
+	if opts.IgnoredArg != "" {
+		ignoredFlag = " " + opts.IgnoredArg
+	}
+
+	pathsArg := ""
+	if len(opts.Paths) > 0 {
+		pathsArg = " -- " + strings.Join(lo.Map(opts.Paths, func(path string, _ int) string {
+			return c.cmd.Quote(path)
+		}), " ")
+	}
+
+	nulFlag := ""
+	if nulTerminated {
+		nulFlag = " -z"
+	}
+
+	return c.cmd.New(fmt.Sprintf("git status %s %s%s%s%s%s", opts.UntrackedFilesArg, porcelainFlag, nulFlag, noRenamesFlag, ignoredFlag, pathsArg)).DontLog()
+}
+
+// parseStatusLine parses an entry of porcelain v1 output without -z, which
+// looks like '<XY> <path>', or '<XY> <orig path> -> <path>' for a rename. Git
+// quotes any path containing a space, so we know that an unquoted path doesn't
+// contain ' -> '.
+func parseStatusLine(line string) (FileStatus, bool) {
+	if len(line) < 4 {
+		return FileStatus{}, false
+	}
+
+	change := line[:2]
+	paths := line[3:]
+
+	if !strings.Contains(change, "R") {
+		name := unquoteGitPath(paths)
+		return FileStatus{StatusString: change + " " + name, Change: change, Name: name}, true
+	}
+
+	previousName, name, ok := splitRenamePaths(paths)
+	if !ok {
+		return FileStatus{}, false
+	}
+
+	return FileStatus{
+		StatusString: fmt.Sprintf("%s %s -> %s", change, previousName, name),
+		Change:       change,
+		Name:         name,
+		PreviousName: previousName,
+	}, true
+}
+
+func splitRenamePaths(paths string) (string, string, bool) {
+	end := 0
+	if strings.HasPrefix(paths, `"`) {
+		// find the closing quote, skipping over escaped characters
+		end = 1
+		for end < len(paths) && paths[end] != '"' {
+			if paths[end] == '\\' {
+				end++
+			}
+			end++
+		}
+		end++
+		if end > len(paths) || !strings.HasPrefix(paths[end:], " -> ") {
+			return "", "", false
+		}
+	} else {
+		end = strings.Index(paths, " -> ")
+		if end == -1 {
+			return "", "", false
+		}
+	}
+
+	return unquoteGitPath(paths[:end]), unquoteGitPath(paths[end+len(" -> "):]), true
+}
+
+// unquoteGitPath undoes the quoting git applies to a path containing unusual
+// characters in output that isn't NUL-separated. With core.quotePath (the
+// default) that includes any non-ASCII characters, so 'café.txt' comes out as
+// '"caf\303\251.txt"'. git's C-style escapes are all valid in Go string
+// literals. Paths which aren't quoted are returned as they are.
+func unquoteGitPath(path string) string {
+	if len(path) < 2 || !strings.HasPrefix(path, `"`) || !strings.HasSuffix(path, `"`) {
+		return path
+	}
+
+	unquoted, err := strconv.Unquote(path)
+	if err != nil {
+		return path
+	}
+
+	return unquoted
+}
+
+// Porcelain v2 entries look like this (with -z, the fields of an entry are
+// separated by spaces and entries by NUL):
+//
+//	1 <XY> <sub> <mH> <mI> <mW> <hH> <hI> <path>
+//	2 <XY> <sub> <mH> <mI> <mW> <hH> <hI> <X><score> <path> NUL <origPath>
+//	u <XY> <sub> <m1> <m2> <m3> <mW> <h1> <h2> <h3> <path>
+//	? <path>
+//	! <path>
+//
+// where an unchanged side of XY is '.' rather than ' ' as in v1. We convert
+// entries to the same Change and StatusString that v1 would give us.
+func parsePorcelainV2Status(output string) []FileStatus {
+	entries := strings.Split(output, "\x00")
+	response := []FileStatus{}
+
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		if len(entry) < 3 {
+			continue
+		}
+
+		var status FileStatus
+		switch entry[0] {
+		case '1':
+			fields := strings.SplitN(entry, " ", 9)
+			if len(fields) < 9 {
+				continue
+			}
+			status = FileStatus{
+				Change:          porcelainV2Change(fields[1]),
+				Name:            fields[8],
+				SubmoduleStatus: parseSubmoduleStatus(fields[2]),
+			}
+		case '2':
+			fields := strings.SplitN(entry, " ", 10)
+			if len(fields) < 10 || i+1 >= len(entries) {
+				continue
+			}
+			status = FileStatus{
+				Change:          porcelainV2Change(fields[1]),
+				Name:            fields[9],
+				SubmoduleStatus: parseSubmoduleStatus(fields[2]),
+			}
+			// e.g. 'R100' or 'C75'
+			status.RenameScore, _ = strconv.Atoi(fields[8][1:])
+			// the original path is in the next entry
+			originalName := entries[i+1]
+			i++
+			if strings.Contains(status.Change, "R") {
+				status.PreviousName = originalName
+			}
+			status.StatusString = fmt.Sprintf("%s %s -> %s", status.Change, originalName, status.Name)
+		case 'u':
+			fields := strings.SplitN(entry, " ", 11)
+			if len(fields) < 11 {
+				continue
+			}
+			status = FileStatus{
+				Change:          porcelainV2Change(fields[1]),
+				Name:            fields[10],
+				SubmoduleStatus: parseSubmoduleStatus(fields[2]),
+			}
+		case '?':
+			status = FileStatus{Change: "??", Name: entry[2:]}
+		case '!':
+			status = FileStatus{Change: "!!", Name: entry[2:]}
+		default:
+			continue
+		}
+
+		if status.StatusString == "" {
+			status.StatusString = status.Change + " " + status.Name
+		}
+
+		response = append(response, status)
+	}
+
+	return response
+}
+
+func porcelainV2Change(xy string) string {
+	return strings.ReplaceAll(xy, ".", " ")
+}
@@ -1,8 +1,15 @@
 package git_commands
 
 import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/go-errors/errors"
+	"github.com/jesseduffield/generics/slices"
 	"github.com/jesseduffield/lazygit/pkg/commands/models"
 	"github.com/jesseduffield/lazygit/pkg/commands/oscommands"
 	"github.com/jesseduffield/lazygit/pkg/utils"
@@ -203,8 +210,1023 @@ func TestFileGetStatusFiles(t *testing.T) {
 
 type FakeFileLoaderConfig struct {
 	showUntrackedFiles string
+	sparseCheckout     bool
+	sparseCheckoutCone bool
 }
 
 func (self *FakeFileLoaderConfig) GetShowUntrackedFiles() string {
 	return self.showUntrackedFiles
 }
+
+func (self *FakeFileLoaderConfig) GetSparseCheckout() bool {
+	return self.sparseCheckout
+}
+
+func (self *FakeFileLoaderConfig) GetSparseCheckoutCone() bool {
+	return self.sparseCheckoutCone
+}
+
+func TestFileGetStatusFilesWithSubmodules(t *testing.T) {
+	type scenario struct {
+		testName                 string
+		includeSubmoduleStatuses bool
+		expectedLoaded           bool
+	}
+
+	scenarios := []scenario{
+		{
+			testName:                 "not loaded by default",
+			includeSubmoduleStatuses: false,
+			expectedLoaded:           false,
+		},
+		{
+			testName:                 "loaded on request",
+			includeSubmoduleStatuses: true,
+			expectedLoaded:           true,
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			runner := oscommands.NewFakeRunner(t).
+				Expect(`git status --untracked-files=yes --porcelain -z`, " M file.txt\x00 M sub", nil)
+
+			submoduleStatus := &models.SubmoduleStatus{ModifiedContent: true}
+			loaded := false
+			loader := &FileLoader{
+				Common:      utils.NewDummyCommon(),
+				cmd:         oscommands.NewDummyCmdObjBuilder(runner),
+				config:      &FakeFileLoaderConfig{showUntrackedFiles: "yes"},
+				getFileType: func(string) string { return "file" },
+				getSubmoduleStatuses: func() (map[string]*models.SubmoduleStatus, error) {
+					loaded = true
+					return map[string]*models.SubmoduleStatus{"sub": submoduleStatus}, nil
+				},
+			}
+
+			files := loader.GetStatusFiles(GetStatusFileOptions{IncludeSubmoduleStatuses: s.includeSubmoduleStatuses})
+			assert.Equal(t, s.expectedLoaded, loaded)
+			assert.Len(t, files, 2)
+			assert.Nil(t, files[0].SubmoduleStatus)
+			if s.expectedLoaded {
+				assert.Equal(t, submoduleStatus, files[1].SubmoduleStatus)
+			} else {
+				assert.Nil(t, files[1].SubmoduleStatus)
+			}
+			runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestFileGetStatusFilesWithSummary(t *testing.T) {
+	runner := oscommands.NewFakeRunner(t).
+		Expect(
+			`git status --untracked-files=yes --porcelain -z`,
+			"MM file1.txt\x00A  file2.txt\x00 M file3.txt\x00?? file4.txt\x00?? file5.txt\x00UU file6.txt",
+			nil,
+		)
+
+	loader := &FileLoader{
+		Common:      utils.NewDummyCommon(),
+		cmd:         oscommands.NewDummyCmdObjBuilder(runner),
+		config:      &FakeFileLoaderConfig{showUntrackedFiles: "yes"},
+		getFileType: func(string) string { return "file" },
+	}
+
+	result := loader.GetStatusFilesWithSummary(GetStatusFileOptions{})
+	assert.Len(t, result.Files, 6)
+	assert.Equal(t, models.StatusSummary{
+		Staged:     2,
+		Modified:   2,
+		Untracked:  2,
+		Conflicted: 1,
+	}, result.Summary)
+}
+
+func TestFileGetStatusFilesWithSparseCheckout(t *testing.T) {
+	type scenario struct {
+		testName           string
+		hideSparseExcluded bool
+		expectedNames      []string
+	}
+
+	scenarios := []scenario{
+		{
+			testName:           "excluded files are kept and marked",
+			hideSparseExcluded: false,
+			expectedNames:      []string{"README.md", "docs/guide.md", "src/app/main.go"},
+		},
+		{
+			testName:           "excluded files are left out on request",
+			hideSparseExcluded: true,
+			expectedNames:      []string{"README.md", "src/app/main.go"},
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			runner := oscommands.NewFakeRunner(t).
+				Expect(`git status --untracked-files=yes --porcelain -z`, " M README.md\x00 M docs/guide.md\x00 M src/app/main.go", nil).
+				Expect(`git sparse-checkout list`, "src/app\n", nil)
+
+			gitDir := writeSparseCheckoutFile(t, t.TempDir(), "/*\n!/*/\n/src/\n!/src/*/\n/src/app/\n")
+			loader := &FileLoader{
+				Common:      utils.NewDummyCommon(),
+				cmd:         oscommands.NewDummyCmdObjBuilder(runner),
+				config:      &FakeFileLoaderConfig{showUntrackedFiles: "yes", sparseCheckout: true, sparseCheckoutCone: true},
+				getFileType: func(string) string { return "file" },
+				getGitDir:   func() (string, error) { return gitDir, nil },
+			}
+
+			files := loader.GetStatusFiles(GetStatusFileOptions{HideSparseExcluded: s.hideSparseExcluded})
+			names := make([]string, 0, len(files))
+			for _, file := range files {
+				names = append(names, file.Name)
+				assert.Equal(t, file.Name == "docs/guide.md", file.SparseExcluded)
+			}
+			assert.Equal(t, s.expectedNames, names)
+			runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestFileGetStatusFilesCachesSparseCheckoutPatterns(t *testing.T) {
+	const statusCmd = `git status --untracked-files=yes --porcelain -z`
+	const statusOutput = " M docs/guide.md\x00 M src/app/main.go"
+
+	gitDir := t.TempDir()
+	writeSparseCheckoutFile(t, gitDir, "/*\n!/*/\n/src/\n")
+
+	runner := oscommands.NewFakeRunner(t).
+		Expect(statusCmd, statusOutput, nil).
+		Expect(`git sparse-checkout list`, "src\n", nil).
+		Expect(statusCmd, statusOutput, nil).
+		Expect(statusCmd, statusOutput, nil).
+		Expect(`git sparse-checkout list`, "docs\n", nil)
+
+	loader := &FileLoader{
+		Common:      utils.NewDummyCommon(),
+		cmd:         oscommands.NewDummyCmdObjBuilder(runner),
+		config:      &FakeFileLoaderConfig{showUntrackedFiles: "yes", sparseCheckout: true, sparseCheckoutCone: true},
+		getFileType: func(string) string { return "file" },
+		getGitDir:   func() (string, error) { return gitDir, nil },
+	}
+
+	excludedNames := func() []string {
+		names := []string{}
+		for _, file := range loader.GetStatusFiles(GetStatusFileOptions{}) {
+			if file.SparseExcluded {
+				names = append(names, file.Name)
+			}
+		}
+		return names
+	}
+
+	assert.Equal(t, []string{"docs/guide.md"}, excludedNames())
+	// the patterns haven't changed, so we don't ask git for them again
+	assert.Equal(t, []string{"docs/guide.md"}, excludedNames())
+
+	writeSparseCheckoutFile(t, gitDir, "/*\n!/*/\n/docs/\n")
+	assert.Equal(t, []string{"src/app/main.go"}, excludedNames())
+
+	runner.CheckForMissingCalls()
+}
+
+// writes the sparse-checkout file in the given git dir, returning the git dir
+func writeSparseCheckoutFile(t *testing.T, gitDir string, content string) string {
+	t.Helper()
+
+	path := filepath.Join(gitDir, "info", "sparse-checkout")
+	assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return gitDir
+}
+
+func TestFileGetStatusFilesPorcelainV2(t *testing.T) {
+	const mode = "100644 100644 100644"
+	const hashes = "de980441c3ab03a8c07dda1ad27b8a11f39deb1e 53a4a4a4d1f3e1ef5b9dc2ef2d0dc0fae3e0d0bb"
+
+	output := "1 .M N... " + mode + " " + hashes + " modified.txt\x00" +
+		"1 A. N... 000000 100644 100644 0000000000000000000000000000000000000000 " + hashes[41:] + " added.txt\x00" +
+		"2 R. N... " + mode + " " + hashes + " R87 renamed.txt\x00original.txt\x00" +
+		"2 C. N... " + mode + " " + hashes + " C75 copy.txt\x00source.txt\x00" +
+		"1 .M SCMU 160000 160000 160000 " + hashes + " sub\x00" +
+		"u UU N... " + mode + " 100644 " + hashes + " " + hashes[:40] + " conflict.txt\x00" +
+		"? untracked file.txt\x00" +
+		"! ignored.log\x00"
+
+	runner := oscommands.NewFakeRunner(t).
+		Expect(`git status --untracked-files=yes --porcelain=v2 -z`, output, nil)
+
+	loader := &FileLoader{
+		Common:      utils.NewDummyCommon(),
+		cmd:         oscommands.NewDummyCmdObjBuilder(runner),
+		config:      &FakeFileLoaderConfig{showUntrackedFiles: "yes"},
+		getFileType: func(string) string { return "file" },
+	}
+
+	type expectedFile struct {
+		Name            string
+		PreviousName    string
+		ShortStatus     string
+		DisplayString   string
+		RenameScore     int
+		SubmoduleStatus *models.SubmoduleStatus
+	}
+
+	expected := []expectedFile{
+		{Name: "modified.txt", ShortStatus: " M", DisplayString: " M modified.txt"},
+		{Name: "added.txt", ShortStatus: "A ", DisplayString: "A  added.txt"},
+		{Name: "renamed.txt", PreviousName: "original.txt", ShortStatus: "R ", DisplayString: "R  original.txt -> renamed.txt", RenameScore: 87},
+		// a copy leaves its source alone, so unlike a rename there's no previous name
+		{Name: "copy.txt", ShortStatus: "C ", DisplayString: "C  source.txt -> copy.txt", RenameScore: 75},
+		{
+			Name: "sub", ShortStatus: " M", DisplayString: " M sub",
+			SubmoduleStatus: &models.SubmoduleStatus{NewCommits: true, ModifiedContent: true, UntrackedContent: true},
+		},
+		{Name: "conflict.txt", ShortStatus: "UU", DisplayString: "UU conflict.txt"},
+		{Name: "untracked file.txt", ShortStatus: "??", DisplayString: "?? untracked file.txt"},
+		{Name: "ignored.log", ShortStatus: "!!", DisplayString: "!! ignored.log"},
+	}
+
+	files := loader.GetStatusFiles(GetStatusFileOptions{PorcelainV2: true})
+	actual := make([]expectedFile, 0, len(files))
+	for _, file := range files {
+		actual = append(actual, expectedFile{
+			Name:            file.Name,
+			PreviousName:    file.PreviousName,
+			ShortStatus:     file.ShortStatus,
+			DisplayString:   file.DisplayString,
+			RenameScore:     file.RenameScore,
+			SubmoduleStatus: file.SubmoduleStatus,
+		})
+	}
+
+	assert.Equal(t, expected, actual)
+	runner.CheckForMissingCalls()
+}
+
+func TestFileGetStatusFilesPorcelainV2MatchesV1(t *testing.T) {
+	// the same repo state in both formats should give us the same files, apart
+	// from the extra detail that only v2 has
+	v1Output := "MM file1.txt\x00A  file3.txt\x00AM file2.txt\x00?? file4.txt\x00UU file5.txt\x00R  after.txt\x00before.txt\x00" +
+		// a rename in the working tree, of a file added with intent-to-add
+		" R moved.txt\x00added.txt\x00"
+	v2Output := "1 MM N... 100644 100644 100644 a a file1.txt\x00" +
+		"1 A. N... 000000 100644 100644 0 a file3.txt\x00" +
+		"1 AM N... 000000 100644 100644 0 a file2.txt\x00" +
+		"? file4.txt\x00" +
+		"u UU N... 100644 100644 100644 100644 a b c file5.txt\x00" +
+		"2 R. N... 100644 100644 100644 a a R100 after.txt\x00before.txt\x00" +
+		"2 .R N... 000000 100644 100644 0 0 R100 moved.txt\x00added.txt\x00"
+
+	runner := oscommands.NewFakeRunner(t).
+		Expect(`git status --untracked-files=yes --porcelain -z`, v1Output, nil).
+		Expect(`git status --untracked-files=yes --porcelain=v2 -z`, v2Output, nil)
+
+	loader := &FileLoader{
+		Common:      utils.NewDummyCommon(),
+		cmd:         oscommands.NewDummyCmdObjBuilder(runner),
+		config:      &FakeFileLoaderConfig{showUntrackedFiles: "yes"},
+		getFileType: func(string) string { return "file" },
+	}
+
+	v1Files := loader.GetStatusFiles(GetStatusFileOptions{})
+	v2Files := loader.GetStatusFiles(GetStatusFileOptions{PorcelainV2: true})
+
+	for _, file := range v2Files {
+		file.RenameScore = 0
+	}
+	assert.Equal(t, v1Files, v2Files)
+	assert.Len(t, v1Files, 7)
+	assert.Equal(t, "added.txt", v1Files[6].PreviousName)
+	runner.CheckForMissingCalls()
+}
+
+func TestFileGetStatusFilesWithUnicodeNames(t *testing.T) {
+	// with -z, git never quotes paths, whatever core.quotePath is set to
+	scenarios := []struct {
+		testName    string
+		porcelainV2 bool
+		command     string
+		output      string
+	}{
+		{
+			testName: "porcelain v1",
+			command:  `git status --untracked-files=yes --porcelain -z`,
+			output:   " M café.txt\x00R  🎉 new.txt\x00ünïcödé.txt\x00",
+		},
+		{
+			testName:    "porcelain v2",
+			porcelainV2: true,
+			command:     `git status --untracked-files=yes --porcelain=v2 -z`,
+			output: "1 .M N... 100644 100644 100644 aaa aaa café.txt\x00" +
+				"2 R. N... 100644 100644 100644 bbb bbb R100 🎉 new.txt\x00ünïcödé.txt\x00",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			runner := oscommands.NewFakeRunner(t).Expect(s.command, s.output, nil)
+			loader := &FileLoader{
+				Common:      utils.NewDummyCommon(),
+				cmd:         oscommands.NewDummyCmdObjBuilder(runner),
+				config:      &FakeFileLoaderConfig{showUntrackedFiles: "yes"},
+				getFileType: func(string) string { return "file" },
+			}
+
+			files := loader.GetStatusFiles(GetStatusFileOptions{PorcelainV2: s.porcelainV2})
+
+			assert.Equal(t, []string{"café.txt", "🎉 new.txt"}, slices.Map(files, func(file *models.File) string {
+				return file.Name
+			}))
+			assert.Equal(t, "ünïcödé.txt", files[1].PreviousName)
+			runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestUnquoteGitPath(t *testing.T) {
+	scenarios := []struct {
+		path     string
+		expected string
+	}{
+		{path: "plain.txt", expected: "plain.txt"},
+		// what git gives with core.quotePath set to false
+		{path: "café.txt", expected: "café.txt"},
+		{path: `"caf\303\251.txt"`, expected: "café.txt"},
+		{path: `"\360\237\216\211 party.txt"`, expected: "🎉 party.txt"},
+		{path: `"tab\there \"quoted\" back\\slash"`, expected: "tab\there \"quoted\" back\\slash"},
+		// not quoted as a whole, so left alone
+		{path: `"starts with a quote`, expected: `"starts with a quote`},
+		{path: `"`, expected: `"`},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.path, func(t *testing.T) {
+			assert.Equal(t, s.expected, unquoteGitPath(s.path))
+		})
+	}
+}
+
+func TestParseStatusLine(t *testing.T) {
+	scenarios := []struct {
+		line     string
+		expected FileStatus
+		ok       bool
+	}{
+		{
+			line:     " M file.txt",
+			expected: FileStatus{StatusString: " M file.txt", Change: " M", Name: "file.txt"},
+			ok:       true,
+		},
+		{
+			line:     `?? "sp ace.txt"`,
+			expected: FileStatus{StatusString: "?? sp ace.txt", Change: "??", Name: "sp ace.txt"},
+			ok:       true,
+		},
+		{
+			line:     "R  old.txt -> new.txt",
+			expected: FileStatus{StatusString: "R  old.txt -> new.txt", Change: "R ", Name: "new.txt", PreviousName: "old.txt"},
+			ok:       true,
+		},
+		{
+			line:     `RM "a -> b.txt" -> "c \"d\".txt"`,
+			expected: FileStatus{StatusString: `RM a -> b.txt -> c "d".txt`, Change: "RM", Name: `c "d".txt`, PreviousName: "a -> b.txt"},
+			ok:       true,
+		},
+		{
+			line:     ` R "caf\303\251.txt" -> plain.txt`,
+			expected: FileStatus{StatusString: " R café.txt -> plain.txt", Change: " R", Name: "plain.txt", PreviousName: "café.txt"},
+			ok:       true,
+		},
+		{line: "R  no-arrow.txt", ok: false},
+		{line: `R  "unterminated -> quote`, ok: false},
+		{line: "", ok: false},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.line, func(t *testing.T) {
+			status, ok := parseStatusLine(s.line)
+			assert.Equal(t, s.ok, ok)
+			assert.Equal(t, s.expected, status)
+		})
+	}
+}
+
+func TestFileGetStatusFilesStream(t *testing.T) {
+	lines := []string{}
+	for i := 0; i < statusStreamBatchSize+2; i++ {
+		lines = append(lines, fmt.Sprintf(" M file%d.txt", i))
+	}
+	lines = append(lines, `R  "old name.txt" -> new.txt`)
+	output := strings.Join(lines, "\n")
+
+	type scenario struct {
+		testName             string
+		opts                 GetStatusFileOptions
+		runner               *oscommands.FakeCmdObjRunner
+		cancelAfterFirst     bool
+		expectedBatchLengths []int
+		expectedError        string
+	}
+
+	expectStatus := func(err error) *oscommands.FakeCmdObjRunner {
+		return oscommands.NewFakeRunner(t).
+			ExpectFunc(func(cmdObj oscommands.ICmdObj) (string, error) {
+				assert.Equal(t, `git status --untracked-files=yes --porcelain`, cmdObj.ToString())
+				assert.True(t, cmdObj.ShouldCheckExitStatus())
+				return output, err
+			})
+	}
+
+	scenarios := []scenario{
+		{
+			testName:             "hands over the files in batches",
+			runner:               expectStatus(nil),
+			expectedBatchLengths: []int{statusStreamBatchSize, 3},
+		},
+		{
+			testName:             "stops once cancelled",
+			runner:               expectStatus(nil),
+			cancelAfterFirst:     true,
+			expectedBatchLengths: []int{statusStreamBatchSize},
+			expectedError:        context.Canceled.Error(),
+		},
+		{
+			testName:             "git status fails",
+			runner:               expectStatus(errors.New("fatal: not a git repository")),
+			expectedBatchLengths: []int{},
+			expectedError:        "fatal: not a git repository",
+		},
+		{
+			testName:             "porcelain v2 is not supported",
+			opts:                 GetStatusFileOptions{PorcelainV2: true},
+			runner:               oscommands.NewFakeRunner(t),
+			expectedBatchLengths: []int{},
+			expectedError:        "PorcelainV2 is not supported when streaming the status",
+		},
+		{
+			testName:             "diff stats are not supported",
+			opts:                 GetStatusFileOptions{IncludeDiffStats: true},
+			runner:               oscommands.NewFakeRunner(t),
+			expectedBatchLengths: []int{},
+			expectedError:        "IncludeDiffStats is not supported when streaming the status",
+		},
+		{
+			testName:             "mode changes are not supported",
+			opts:                 GetStatusFileOptions{IncludeModeChanges: true},
+			runner:               oscommands.NewFakeRunner(t),
+			expectedBatchLengths: []int{},
+			expectedError:        "IncludeModeChanges is not supported when streaming the status",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			loader := &FileLoader{
+				Common:      utils.NewDummyCommon(),
+				cmd:         oscommands.NewDummyCmdObjBuilder(s.runner),
+				config:      &FakeFileLoaderConfig{showUntrackedFiles: "yes"},
+				getFileType: func(string) string { return "file" },
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			files := []*models.File{}
+			batchLengths := []int{}
+			err := loader.GetStatusFilesStream(ctx, s.opts, func(batch []*models.File) {
+				files = append(files, batch...)
+				batchLengths = append(batchLengths, len(batch))
+				if s.cancelAfterFirst {
+					cancel()
+				}
+			})
+
+			if s.expectedError != "" {
+				assert.EqualError(t, err, s.expectedError)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, "file0.txt", files[0].Name)
+				lastFile := files[len(files)-1]
+				assert.Equal(t, "new.txt", lastFile.Name)
+				assert.Equal(t, "old name.txt", lastFile.PreviousName)
+				assert.True(t, lastFile.HasStagedChanges)
+			}
+			assert.Equal(t, s.expectedBatchLengths, batchLengths)
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestFileFindStatusFiles(t *testing.T) {
+	type scenario struct {
+		testName      string
+		query         string
+		statusError   error
+		expectedNames []string
+		expectedError string
+	}
+
+	scenarios := []scenario{
+		{
+			testName:      "empty query",
+			query:         "",
+			expectedNames: []string{"foo/bar.go", "README.md", "pkg/foo_test.go", "docs/guide.md"},
+		},
+		{
+			testName:      "substring, ignoring case",
+			query:         "readme",
+			expectedNames: []string{"README.md"},
+		},
+		{
+			testName:      "subsequence",
+			query:         "fb",
+			expectedNames: []string{"foo/bar.go"},
+		},
+		{
+			testName:      "best matches first",
+			query:         "foo",
+			expectedNames: []string{"foo/bar.go", "pkg/foo_test.go"},
+		},
+		{
+			testName:      "no matches",
+			query:         "xyz",
+			expectedNames: []string{},
+		},
+		{
+			testName:      "git status fails",
+			query:         "foo",
+			statusError:   errors.New("fatal: not a git repository"),
+			expectedError: "fatal: not a git repository",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			runner := oscommands.NewFakeRunner(t).
+				Expect(
+					`git status --untracked-files=yes --porcelain -z`,
+					" M foo/bar.go\x00 M README.md\x00?? pkg/foo_test.go\x00A  docs/guide.md",
+					s.statusError,
+				)
+
+			loader := &FileLoader{
+				Common:      utils.NewDummyCommon(),
+				cmd:         oscommands.NewDummyCmdObjBuilder(runner),
+				config:      &FakeFileLoaderConfig{showUntrackedFiles: "yes"},
+				getFileType: func(string) string { return "file" },
+			}
+
+			files, err := loader.FindStatusFiles(s.query, GetStatusFileOptions{})
+			if s.expectedError != "" {
+				assert.EqualError(t, err, s.expectedError)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, s.expectedNames, slices.Map(files, func(file *models.File) string {
+					return file.Name
+				}))
+			}
+			runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestFileGetStatusFilesWithSummaryContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	runner := oscommands.NewFakeRunner(t).
+		ExpectFunc(func(cmdObj oscommands.ICmdObj) (string, error) {
+			assert.Equal(t, ctx, cmdObj.Context())
+			// as if the user moved on while git was still working
+			cancel()
+			return "", context.Canceled
+		})
+
+	loader := &FileLoader{
+		Common:      utils.NewDummyCommon(),
+		cmd:         oscommands.NewDummyCmdObjBuilder(runner),
+		config:      &FakeFileLoaderConfig{showUntrackedFiles: "yes"},
+		getFileType: func(string) string { return "file" },
+	}
+
+	result, err := loader.GetStatusFilesWithSummaryContext(ctx, GetStatusFileOptions{})
+	assert.Equal(t, context.Canceled, err)
+	assert.Empty(t, result.Files)
+	runner.CheckForMissingCalls()
+}
+
+func TestFileGetStatusFilesWithDiffStats(t *testing.T) {
+	runner := oscommands.NewFakeRunner(t).
+		Expect(
+			`git status --untracked-files=yes --porcelain -z`,
+			" M appended.go\x00MM edited.go\x00R  new.go\x00old.go\x00A  added.go\x00 D deleted.go\x00M  image.png\x00",
+			nil,
+		).
+		Expect(
+			`git diff --cached --numstat -z`,
+			"3\t1\tedited.go\x002\t0\t\x00old.go\x00new.go\x005\t0\tadded.go\x00-\t-\timage.png\x00",
+			nil,
+		).
+		Expect(
+			`git diff --numstat -z`,
+			"4\t0\tappended.go\x002\t0\tedited.go\x000\t7\tdeleted.go\x00",
+			nil,
+		)
+
+	loader := &FileLoader{
+		Common:      utils.NewDummyCommon(),
+		cmd:         oscommands.NewDummyCmdObjBuilder(runner),
+		config:      &FakeFileLoaderConfig{showUntrackedFiles: "yes"},
+		getFileType: func(string) string { return "file" },
+	}
+
+	type diffStats struct {
+		Name           string
+		LinesAdded     int
+		LinesDeleted   int
+		PurelyAdditive bool
+	}
+
+	files := loader.GetStatusFiles(GetStatusFileOptions{IncludeDiffStats: true})
+	actual := make([]diffStats, 0, len(files))
+	for _, file := range files {
+		actual = append(actual, diffStats{file.Name, file.LinesAdded, file.LinesDeleted, file.PurelyAdditive})
+	}
+
+	assert.Equal(t, []diffStats{
+		{"appended.go", 4, 0, true},
+		{"edited.go", 5, 1, false},
+		{"new.go", 2, 0, true},
+		// new files are all additions, but there's nothing existing to compare against
+		{"added.go", 5, 0, false},
+		{"deleted.go", 0, 7, false},
+		{"image.png", 0, 0, false},
+	}, actual)
+	runner.CheckForMissingCalls()
+}
+
+func TestFileGetStatusFilesWithModeChanges(t *testing.T) {
+	runner := oscommands.NewFakeRunner(t).
+		Expect(
+			`git status --untracked-files=yes --porcelain -z`,
+			" M executable.sh\x00MM edited.sh\x00R  new.sh\x00old.sh\x00A  added.sh\x00 M content.go\x00MM reverted.sh\x00",
+			nil,
+		).
+		Expect(
+			`git diff --cached --raw -z`,
+			":100644 100755 aaa bbb M\x00edited.sh\x00"+
+				":100644 100755 ccc ccc R100\x00old.sh\x00new.sh\x00"+
+				":000000 100644 000 ddd A\x00added.sh\x00"+
+				":100644 100755 eee eee M\x00reverted.sh\x00",
+			nil,
+		).
+		Expect(
+			`git diff --raw -z`,
+			":100644 100755 fff fff M\x00executable.sh\x00"+
+				":100755 100755 bbb 000 M\x00edited.sh\x00"+
+				":100644 100644 ggg 000 M\x00content.go\x00"+
+				":100755 100644 eee eee M\x00reverted.sh\x00",
+			nil,
+		)
+
+	loader := &FileLoader{
+		Common:      utils.NewDummyCommon(),
+		cmd:         oscommands.NewDummyCmdObjBuilder(runner),
+		config:      &FakeFileLoaderConfig{showUntrackedFiles: "yes"},
+		getFileType: func(string) string { return "file" },
+	}
+
+	type modeChange struct {
+		Name        string
+		ModeChanged bool
+		NewMode     string
+	}
+
+	files := loader.GetStatusFiles(GetStatusFileOptions{IncludeModeChanges: true})
+	actual := make([]modeChange, 0, len(files))
+	for _, file := range files {
+		actual = append(actual, modeChange{file.Name, file.ModeChanged, file.NewMode})
+	}
+
+	assert.Equal(t, []modeChange{
+		{"executable.sh", true, "100755"},
+		// the mode change is staged and there are further content changes
+		{"edited.sh", true, "100755"},
+		{"new.sh", true, "100755"},
+		{"added.sh", false, ""},
+		{"content.go", false, ""},
+		// made executable in the index, then not in the working tree
+		{"reverted.sh", false, ""},
+	}, actual)
+	runner.CheckForMissingCalls()
+}
+
+func TestFileLoadConflictedFiles(t *testing.T) {
+	runner := oscommands.NewFakeRunner(t).
+		Expect(
+			`git ls-files --unmerged -z`,
+			"100644 aaa 1\tboth-modified.txt\x00100644 bbb 2\tboth-modified.txt\x00100644 ccc 3\tboth-modified.txt\x00"+
+				"100644 bbb 2\tboth-added.txt\x00100644 ccc 3\tboth-added.txt\x00"+
+				"100644 aaa 1\tdeleted-by-them.txt\x00100644 bbb 2\tdeleted-by-them.txt\x00"+
+				"100644 aaa 1\tdeleted-by-us.txt\x00100644 ccc 3\tdeleted-by-us.txt\x00"+
+				"100644 aaa 1\tboth-deleted.txt\x00"+
+				"100644 bbb 2\tadded-by-us.txt\x00"+
+				"100644 ccc 3\tadded-by-them.txt\x00",
+			nil,
+		)
+
+	loader := &FileLoader{
+		Common:      utils.NewDummyCommon(),
+		cmd:         oscommands.NewDummyCmdObjBuilder(runner),
+		config:      &FakeFileLoaderConfig{showUntrackedFiles: "yes"},
+		getFileType: func(string) string { return "file" },
+	}
+
+	files, err := loader.LoadConflictedFiles()
+	assert.NoError(t, err)
+
+	type conflict struct {
+		Name        string
+		ShortStatus string
+	}
+	conflicts := slices.Map(files, func(file *models.File) conflict {
+		assert.True(t, file.HasMergeConflicts)
+		return conflict{Name: file.Name, ShortStatus: file.ShortStatus}
+	})
+
+	assert.Equal(t, []conflict{
+		{Name: "both-modified.txt", ShortStatus: "UU"},
+		{Name: "both-added.txt", ShortStatus: "AA"},
+		{Name: "deleted-by-them.txt", ShortStatus: "UD"},
+		{Name: "deleted-by-us.txt", ShortStatus: "DU"},
+		{Name: "both-deleted.txt", ShortStatus: "DD"},
+		{Name: "added-by-us.txt", ShortStatus: "AU"},
+		{Name: "added-by-them.txt", ShortStatus: "UA"},
+	}, conflicts)
+	runner.CheckForMissingCalls()
+}
+
+func TestFileIsBinary(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(name string, content string) string {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+		return path
+	}
+
+	type scenario struct {
+		testName string
+		fileName string
+		runner   *oscommands.FakeCmdObjRunner
+		expected bool
+	}
+
+	checkAttr := func(fileName string, value string) (string, string) {
+		return fmt.Sprintf(`git check-attr -z diff -- "%s"`, fileName), fmt.Sprintf("%s\x00diff\x00%s\x00", fileName, value)
+	}
+
+	scenarios := []scenario{
+		func() scenario {
+			// the content doesn't matter if .gitattributes says the file is binary
+			fileName := writeFile("declared.dat", "plain text")
+			cmd, output := checkAttr(fileName, "unset")
+			return scenario{
+				testName: "binary according to .gitattributes",
+				fileName: fileName,
+				runner:   oscommands.NewFakeRunner(t).Expect(cmd, output, nil),
+				expected: true,
+			}
+		}(),
+		func() scenario {
+			fileName := writeFile("image.png", "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+			cmd, output := checkAttr(fileName, "unspecified")
+			return scenario{
+				testName: "binary according to its content",
+				fileName: fileName,
+				runner:   oscommands.NewFakeRunner(t).Expect(cmd, output, nil),
+				expected: true,
+			}
+		}(),
+		func() scenario {
+			fileName := writeFile("text.txt", "line one\nline two\n")
+			cmd, output := checkAttr(fileName, "unspecified")
+			return scenario{
+				testName: "text according to its content",
+				fileName: fileName,
+				runner:   oscommands.NewFakeRunner(t).Expect(cmd, output, nil),
+				expected: false,
+			}
+		}(),
+		func() scenario {
+			fileName := writeFile("forced-text.dat", "text\x00with a NUL")
+			cmd, output := checkAttr(fileName, "set")
+			return scenario{
+				testName: "text according to .gitattributes",
+				fileName: fileName,
+				runner:   oscommands.NewFakeRunner(t).Expect(cmd, output, nil),
+				expected: false,
+			}
+		}(),
+		func() scenario {
+			fileName := filepath.Join(dir, "deleted.bin")
+			cmd, output := checkAttr(fileName, "unspecified")
+			return scenario{
+				testName: "deleted file is checked in the index",
+				fileName: fileName,
+				runner: oscommands.NewFakeRunner(t).
+					Expect(cmd, output, nil).
+					Expect(fmt.Sprintf(`git cat-file blob ":%s"`, fileName), "\x00\x01\x02", nil),
+				expected: true,
+			}
+		}(),
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			loader := &FileLoader{
+				Common:      utils.NewDummyCommon(),
+				cmd:         oscommands.NewDummyCmdObjBuilder(s.runner),
+				config:      &FakeFileLoaderConfig{showUntrackedFiles: "yes"},
+				getFileType: func(string) string { return "file" },
+			}
+
+			isBinary, err := loader.IsBinary(s.fileName)
+			assert.NoError(t, err)
+			assert.Equal(t, s.expected, isBinary)
+
+			// the second call is answered from the cache
+			isBinary, err = loader.IsBinary(s.fileName)
+			assert.NoError(t, err)
+			assert.Equal(t, s.expected, isBinary)
+
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestFileGetStatusFilesIncludingIgnored(t *testing.T) {
+	type scenario struct {
+		testName    string
+		opts        GetStatusFileOptions
+		expectedCmd string
+		output      string
+	}
+
+	scenarios := []scenario{
+		{
+			testName:    "traditional by default",
+			opts:        GetStatusFileOptions{IncludeIgnored: true},
+			expectedCmd: `git status --untracked-files=yes --porcelain -z --ignored=traditional`,
+			output:      "?? new.txt\x00!! build/\x00",
+		},
+		{
+			testName:    "matching",
+			opts:        GetStatusFileOptions{IncludeIgnored: true, IgnoredMode: IGNORED_MODE_MATCHING},
+			expectedCmd: `git status --untracked-files=yes --porcelain -z --ignored=matching`,
+			output:      "?? new.txt\x00!! build/\x00",
+		},
+		{
+			testName:    "porcelain v2",
+			opts:        GetStatusFileOptions{IncludeIgnored: true, PorcelainV2: true},
+			expectedCmd: `git status --untracked-files=yes --porcelain=v2 -z --ignored=traditional`,
+			output:      "? new.txt\x00! build/\x00",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			runner := oscommands.NewFakeRunner(t).Expect(s.expectedCmd, s.output, nil)
+			loader := &FileLoader{
+				Common:      utils.NewDummyCommon(),
+				cmd:         oscommands.NewDummyCmdObjBuilder(runner),
+				config:      &FakeFileLoaderConfig{showUntrackedFiles: "yes"},
+				getFileType: func(string) string { return "file" },
+			}
+
+			result := loader.GetStatusFilesWithSummary(s.opts)
+
+			assert.Len(t, result.Files, 2)
+			untracked, ignored := result.Files[0], result.Files[1]
+
+			assert.Equal(t, "new.txt", untracked.Name)
+			assert.False(t, untracked.Ignored)
+
+			assert.Equal(t, "build/", ignored.Name)
+			assert.True(t, ignored.Ignored)
+			assert.Equal(t, "!!", ignored.ShortStatus)
+			assert.False(t, ignored.Tracked)
+			assert.False(t, ignored.Added)
+			assert.False(t, ignored.HasStagedChanges)
+			assert.False(t, ignored.HasUnstagedChanges)
+
+			// ignored files aren't changes
+			assert.Equal(t, models.StatusSummary{Untracked: 1}, result.Summary)
+			runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestFileGetStatusFilesCollapsingUntrackedDirs(t *testing.T) {
+	type scenario struct {
+		testName           string
+		showUntrackedFiles string
+		expectedCmd        string
+	}
+
+	scenarios := []scenario{
+		{
+			testName:           "overrides 'all'",
+			showUntrackedFiles: "all",
+			expectedCmd:        `git status --untracked-files=normal --porcelain -z`,
+		},
+		{
+			testName:           "defaults to 'all', which is overridden",
+			showUntrackedFiles: "",
+			expectedCmd:        `git status --untracked-files=normal --porcelain -z`,
+		},
+		{
+			testName:           "leaves 'no' alone",
+			showUntrackedFiles: "no",
+			expectedCmd:        `git status --untracked-files=no --porcelain -z`,
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			runner := oscommands.NewFakeRunner(t).Expect(s.expectedCmd, "", nil)
+			loader := &FileLoader{
+				Common:      utils.NewDummyCommon(),
+				cmd:         oscommands.NewDummyCmdObjBuilder(runner),
+				config:      &FakeFileLoaderConfig{showUntrackedFiles: s.showUntrackedFiles},
+				getFileType: func(string) string { return "file" },
+			}
+
+			loader.GetStatusFiles(GetStatusFileOptions{CollapseUntrackedDirs: true})
+			runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestFileGetStatusFilesMarksCollapsedDirs(t *testing.T) {
+	runner := oscommands.NewFakeRunner(t).
+		Expect(
+			`git status --untracked-files=normal --porcelain -z`,
+			"?? node_modules/\x00?? new.txt\x00 M src/main.go\x00",
+			nil,
+		)
+	loader := &FileLoader{
+		Common:      utils.NewDummyCommon(),
+		cmd:         oscommands.NewDummyCmdObjBuilder(runner),
+		config:      &FakeFileLoaderConfig{showUntrackedFiles: "all"},
+		getFileType: func(string) string { return "file" },
+	}
+
+	files := loader.GetStatusFiles(GetStatusFileOptions{CollapseUntrackedDirs: true})
+
+	assert.Len(t, files, 3)
+	assert.Equal(t, "node_modules/", files[0].Name)
+	assert.True(t, files[0].IsCollapsedDir)
+	assert.False(t, files[1].IsCollapsedDir)
+	assert.False(t, files[2].IsCollapsedDir)
+	runner.CheckForMissingCalls()
+}
+
+func TestFileLoadCollapsedDir(t *testing.T) {
+	runner := oscommands.NewFakeRunner(t).
+		Expect(
+			`git status --untracked-files=all --porcelain -z -- "node_modules/"`,
+			"?? node_modules/a/index.js\x00?? node_modules/b/index.js\x00",
+			nil,
+		)
+	loader := &FileLoader{
+		Common:      utils.NewDummyCommon(),
+		cmd:         oscommands.NewDummyCmdObjBuilder(runner),
+		config:      &FakeFileLoaderConfig{showUntrackedFiles: "all"},
+		getFileType: func(string) string { return "file" },
+	}
+
+	files, err := loader.LoadCollapsedDir("node_modules/")
+
+	assert.NoError(t, err)
+	assert.Equal(t,
+		[]string{"node_modules/a/index.js", "node_modules/b/index.js"},
+		slices.Map(files, func(file *models.File) string { return file.Name }),
+	)
+	for _, file := range files {
+		assert.False(t, file.IsCollapsedDir)
+		assert.Equal(t, "??", file.ShortStatus)
+	}
+	runner.CheckForMissingCalls()
+}
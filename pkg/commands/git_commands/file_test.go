@@ -1,10 +1,16 @@
 package git_commands
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/go-errors/errors"
 	"github.com/jesseduffield/lazygit/pkg/commands/git_config"
+	"github.com/jesseduffield/lazygit/pkg/commands/models"
 	"github.com/jesseduffield/lazygit/pkg/commands/oscommands"
 	"github.com/jesseduffield/lazygit/pkg/config"
 	"github.com/stretchr/testify/assert"
@@ -375,3 +381,275 @@ func TestGuessDefaultEditor(t *testing.T) {
 		assert.Equal(t, s.expectedResult, instance.guessDefaultEditor())
 	}
 }
+
+func TestEditFileAtConflictCmdObj(t *testing.T) {
+	type scenario struct {
+		testName       string
+		content        string
+		expectedCmdStr string
+	}
+
+	scenarios := []scenario{
+		{
+			testName:       "conflict in the middle of the file",
+			content:        "one\ntwo\n<<<<<<< HEAD\nours\n=======\ntheirs\n>>>>>>> branch\n",
+			expectedCmdStr: `bash -c "vim +3 -- \"%s\""`,
+		},
+		{
+			testName:       "no conflicts",
+			content:        "one\ntwo\n",
+			expectedCmdStr: `bash -c "vim +1 -- \"%s\""`,
+		},
+		{
+			testName:       "conflict after a line longer than 64KB",
+			content:        strings.Repeat("a", 100*1024) + "\n<<<<<<< HEAD\nours\n=======\ntheirs\n>>>>>>> branch\n",
+			expectedCmdStr: `bash -c "vim +2 -- \"%s\""`,
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "file.txt")
+			assert.NoError(t, os.WriteFile(filename, []byte(s.content), 0o644))
+
+			userConfig := config.GetDefaultConfig()
+			instance := buildFileCommands(commonDeps{userConfig: userConfig})
+
+			cmdObj, err := instance.EditFileAtConflictCmdObj(filename)
+			assert.NoError(t, err)
+			assert.Equal(t, fmt.Sprintf(s.expectedCmdStr, filename), cmdObj.ToString())
+		})
+	}
+}
+
+func TestOpenShellCmdObj(t *testing.T) {
+	type scenario struct {
+		testName     string
+		osConfig     config.OSConfig
+		getenv       func(string) string
+		expectedArgs []string
+	}
+
+	scenarios := []scenario{
+		{
+			testName: "configured shell",
+			osConfig: config.OSConfig{Shell: "fish"},
+			getenv: func(env string) string {
+				return "/bin/zsh"
+			},
+			expectedArgs: []string{"fish"},
+		},
+		{
+			testName: "configured shell with arguments",
+			osConfig: config.OSConfig{Shell: "bash --login"},
+			getenv: func(env string) string {
+				return "/bin/zsh"
+			},
+			expectedArgs: []string{"bash", "--login"},
+		},
+		{
+			testName: "$SHELL",
+			osConfig: config.OSConfig{},
+			getenv: func(env string) string {
+				if env == "SHELL" {
+					return "/bin/zsh"
+				}
+
+				return ""
+			},
+			expectedArgs: []string{"/bin/zsh"},
+		},
+		{
+			testName: "$SHELL with a space in its path",
+			osConfig: config.OSConfig{},
+			getenv: func(env string) string {
+				if env == "SHELL" {
+					return "/opt/my shells/zsh"
+				}
+
+				return ""
+			},
+			expectedArgs: []string{"/opt/my shells/zsh"},
+		},
+		{
+			testName: "fallback",
+			osConfig: config.OSConfig{},
+			getenv: func(env string) string {
+				return ""
+			},
+			expectedArgs: []string{"/bin/sh"},
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			userConfig := config.GetDefaultConfig()
+			userConfig.OS = s.osConfig
+
+			runner := oscommands.NewFakeRunner(t).
+				Expect(`git rev-parse --is-bare-repository --absolute-git-dir --git-common-dir`, "false\n/path/to/repo/.git\n/path/to/repo/.git\n", nil).
+				Expect(`git rev-parse --show-toplevel`, "/path/to/repo\n", nil)
+			instance := buildFileCommands(commonDeps{
+				runner:           runner,
+				userConfig:       userConfig,
+				getenv:           s.getenv,
+				resolveRepoPaths: true,
+			})
+
+			cmdObj, err := instance.OpenShellCmdObj()
+			assert.NoError(t, err)
+			assert.Equal(t, s.expectedArgs, cmdObj.GetCmd().Args)
+			assert.Equal(t, "/path/to/repo", cmdObj.GetCmd().Dir)
+			runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestResolveEditor(t *testing.T) {
+	runner := oscommands.NewFakeRunner(t).
+		Expect(`which vi`, "/usr/bin/vi", nil)
+	userConfig := config.GetDefaultConfig()
+	instance := buildFileCommands(commonDeps{
+		runner:     runner,
+		userConfig: userConfig,
+	})
+
+	editor, err := instance.ResolveEditor()
+	assert.NoError(t, err)
+	assert.Equal(t, EditorConfig{Command: "vi", Kind: EDITOR_KIND_VIM}, editor)
+
+	// the result is cached, so we don't run `which vi` again
+	editor, err = instance.ResolveEditor()
+	assert.NoError(t, err)
+	assert.Equal(t, EditorConfig{Command: "vi", Kind: EDITOR_KIND_VIM}, editor)
+
+	// changing the config invalidates the cache
+	userConfig.OS.EditCommand = "code"
+	editor, err = instance.ResolveEditor()
+	assert.NoError(t, err)
+	assert.Equal(t, EditorConfig{Command: "code", Kind: EDITOR_KIND_VSCODE}, editor)
+
+	runner.CheckForMissingCalls()
+}
+
+func TestResolveEditorConcurrently(t *testing.T) {
+	runner := oscommands.NewFakeRunner(t).
+		Expect(`which vi`, "/usr/bin/vi", nil)
+	instance := buildFileCommands(commonDeps{
+		runner:     runner,
+		userConfig: config.GetDefaultConfig(),
+	})
+
+	// only the first caller resolves the editor; the others wait for it and
+	// get the cached result
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			editor, err := instance.ResolveEditor()
+			assert.NoError(t, err)
+			assert.Equal(t, EditorConfig{Command: "vi", Kind: EDITOR_KIND_VIM}, editor)
+		}()
+	}
+	wg.Wait()
+
+	runner.CheckForMissingCalls()
+}
+
+const blamePorcelainOutput = `63cee6c7bd92d374e753bcc94448d69128667a19 1 1 2
+author Jesse Duffield
+author-mail <jesse@example.com>
+author-time 1652443200
+author-tz +1000
+committer Jesse Duffield
+committer-mail <jesse@example.com>
+committer-time 1652443200
+committer-tz +1000
+summary first commit
+boundary
+filename file.txt
+	line one
+63cee6c7bd92d374e753bcc94448d69128667a19 2 2
+	line two
+2ef235db02feeec348e614087192079b1c870596 3 3 1
+author Stefan Haller
+author-mail <stefan@example.com>
+author-time 1673740800
+author-tz +0100
+committer Stefan Haller
+committer-mail <stefan@example.com>
+committer-time 1673740800
+committer-tz +0100
+summary second commit
+previous 63cee6c7bd92d374e753bcc94448d69128667a19 file.txt
+filename file.txt
+	line three
+63cee6c7bd92d374e753bcc94448d69128667a19 3 4 1
+filename file.txt
+	line four
+`
+
+func TestBlame(t *testing.T) {
+	type scenario struct {
+		testName      string
+		opts          BlameOpts
+		runner        *oscommands.FakeCmdObjRunner
+		expectedLines []models.BlameLine
+		expectedError string
+	}
+
+	scenarios := []scenario{
+		{
+			testName: "whole file",
+			opts:     BlameOpts{},
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git blame --porcelain -- "file.txt"`, blamePorcelainOutput, nil),
+			expectedLines: []models.BlameLine{
+				{Sha: "63cee6c7bd92d374e753bcc94448d69128667a19", AuthorName: "Jesse Duffield", UnixTimestamp: 1652443200, LineNumber: 1, Content: "line one"},
+				{Sha: "63cee6c7bd92d374e753bcc94448d69128667a19", AuthorName: "Jesse Duffield", UnixTimestamp: 1652443200, LineNumber: 2, Content: "line two"},
+				{Sha: "2ef235db02feeec348e614087192079b1c870596", AuthorName: "Stefan Haller", UnixTimestamp: 1673740800, LineNumber: 3, Content: "line three"},
+				{Sha: "63cee6c7bd92d374e753bcc94448d69128667a19", AuthorName: "Jesse Duffield", UnixTimestamp: 1652443200, LineNumber: 4, Content: "line four"},
+			},
+		},
+		{
+			testName: "line range ignoring whitespace",
+			opts:     BlameOpts{StartLine: 3, EndLine: 4, IgnoreWhitespace: true},
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git blame --porcelain -w -L 3,4 -- "file.txt"`, "", nil),
+			expectedLines: []models.BlameLine{},
+		},
+		{
+			testName: "open-ended line range",
+			opts:     BlameOpts{StartLine: 3},
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git blame --porcelain -L 3, -- "file.txt"`, "", nil),
+			expectedLines: []models.BlameLine{},
+		},
+		{
+			testName: "error",
+			opts:     BlameOpts{},
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git blame --porcelain -- "file.txt"`, "", errors.New("no such path 'file.txt' in HEAD")),
+			expectedError: "no such path 'file.txt' in HEAD",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			instance := buildFileCommands(commonDeps{runner: s.runner})
+
+			lines, err := instance.Blame("file.txt", s.opts)
+			if s.expectedError != "" {
+				assert.EqualError(t, err, s.expectedError)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, s.expectedLines, lines)
+			}
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
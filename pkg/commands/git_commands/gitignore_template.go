@@ -0,0 +1,49 @@
+package git_commands
+
+import (
+	"embed"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+//go:embed gitignore_templates/*.gitignore
+var gitignoreTemplates embed.FS
+
+const gitignoreTemplateExt = ".gitignore"
+
+// GitignoreTemplateNames returns the names of the bundled gitignore templates,
+// in alphabetical order
+func GitignoreTemplateNames() []string {
+	// the pattern is fixed at compile time so this can't fail
+	entries, _ := gitignoreTemplates.ReadDir("gitignore_templates")
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, strings.TrimSuffix(entry.Name(), gitignoreTemplateExt))
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// gitignoreTemplateLines returns the lines of the named template, matching the
+// name case-insensitively
+func gitignoreTemplateLines(name string) ([]string, error) {
+	names := GitignoreTemplateNames()
+	for _, templateName := range names {
+		if !strings.EqualFold(templateName, name) {
+			continue
+		}
+
+		content, err := gitignoreTemplates.ReadFile(path.Join("gitignore_templates", templateName+gitignoreTemplateExt))
+		if err != nil {
+			return nil, err
+		}
+
+		return strings.Split(strings.TrimRight(string(content), "\n"), "\n"), nil
+	}
+
+	return nil, fmt.Errorf("unknown gitignore template '%s'. Available templates: %s", name, strings.Join(names, ", "))
+}
@@ -0,0 +1,53 @@
+package git_commands
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGitignoreTemplateNames(t *testing.T) {
+	assert.Equal(t, []string{"Go", "Java", "Node", "Python", "Rust"}, GitignoreTemplateNames())
+}
+
+func TestGitignoreTemplateLines(t *testing.T) {
+	type scenario struct {
+		testName      string
+		name          string
+		expectedFirst []string
+		expectedError string
+	}
+
+	scenarios := []scenario{
+		{
+			testName:      "exact name",
+			name:          "Rust",
+			expectedFirst: []string{"# Build output", "target/"},
+		},
+		{
+			testName:      "name in a different case",
+			name:          "rust",
+			expectedFirst: []string{"# Build output", "target/"},
+		},
+		{
+			testName:      "unknown name",
+			name:          "Cobol",
+			expectedError: "unknown gitignore template 'Cobol'. Available templates: Go, Java, Node, Python, Rust",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			lines, err := gitignoreTemplateLines(s.name)
+			if s.expectedError != "" {
+				assert.EqualError(t, err, s.expectedError)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, s.expectedFirst, lines[:len(s.expectedFirst)])
+			assert.NotEqual(t, "", lines[len(lines)-1])
+		})
+	}
+}
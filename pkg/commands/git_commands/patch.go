@@ -144,7 +144,7 @@ func (self *PatchCommands) MovePatchToSelectedCommit(commits []*models.Commit, s
 	self.rebase.onSuccessfulContinue = func() error {
 		// now we should be up to the destination, so let's apply forward these patches to that.
 		// ideally we would ensure we're on the right commit but I'm not sure if that check is necessary
-		if err := self.rebase.workingTree.ApplyPatch(patch, "index", "3way"); err != nil {
+		if err := self.rebase.workingTree.ApplyPatch(patch, headDiffContextSize, "index", "3way"); err != nil {
 			// Don't abort the rebase here; this might cause conflicts, so give
 			// the user a chance to resolve them
 			return err
@@ -201,7 +201,7 @@ func (self *PatchCommands) MovePatchIntoIndex(commits []*models.Commit, commitId
 
 	self.rebase.onSuccessfulContinue = func() error {
 		// add patches to index
-		if err := self.rebase.workingTree.ApplyPatch(patch, "index", "3way"); err != nil {
+		if err := self.rebase.workingTree.ApplyPatch(patch, headDiffContextSize, "index", "3way"); err != nil {
 			if self.status.WorkingTreeState() == enums.REBASE_MODE_REBASING {
 				_ = self.rebase.AbortRebase()
 			}
@@ -242,7 +242,7 @@ func (self *PatchCommands) PullPatchIntoNewCommit(commits []*models.Commit, comm
 		return err
 	}
 
-	if err := self.rebase.workingTree.ApplyPatch(patch, "index", "3way"); err != nil {
+	if err := self.rebase.workingTree.ApplyPatch(patch, headDiffContextSize, "index", "3way"); err != nil {
 		_ = self.rebase.AbortRebase()
 		return err
 	}
@@ -266,6 +266,10 @@ func (self *PatchCommands) PullPatchIntoNewCommit(commits []*models.Commit, comm
 // index, then this would conflict "with itself" in case the patch contained
 // only some lines of a range of adjacent added lines. To solve this, we
 // get the diff of HEAD and the original commit and then apply that.
+// the context size of the diffs from diffHeadAgainstCommit. It's spelled out
+// so that the user's diff.context git config can't change it.
+const headDiffContextSize = 3
+
 func (self *PatchCommands) diffHeadAgainstCommit(commit *models.Commit) (string, error) {
-	return self.cmd.New(fmt.Sprintf("git diff HEAD..%s", commit.Sha)).RunWithOutput()
+	return self.cmd.New(fmt.Sprintf("git diff --unified=%d HEAD..%s", headDiffContextSize, commit.Sha)).RunWithOutput()
 }
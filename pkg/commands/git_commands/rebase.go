@@ -2,6 +2,7 @@ package git_commands
 
 import (
 	"fmt"
+	"os"
 	"path/filepath"
 	"strings"
 
@@ -181,14 +182,14 @@ func (self *RebaseCommands) PrepareInteractiveRebaseCommand(opts PrepareInteract
 		debug = "TRUE"
 	}
 
-	emptyArg := " --empty=keep"
-	if self.version.IsOlderThan(2, 26, 0) {
-		emptyArg = ""
+	emptyArg := ""
+	if self.features.SupportsRebaseEmptyKeep {
+		emptyArg = " --empty=keep"
 	}
 
-	rebaseMergesArg := " --rebase-merges"
-	if self.version.IsOlderThan(2, 22, 0) {
-		rebaseMergesArg = ""
+	rebaseMergesArg := ""
+	if self.features.SupportsRebaseMerges {
+		rebaseMergesArg = " --rebase-merges"
 	}
 
 	cmdStr := fmt.Sprintf("git rebase --interactive --autostash --keep-empty%s --no-autosquash%s %s",
@@ -242,22 +243,204 @@ func (self *RebaseCommands) AmendTo(commits []*models.Commit, commitIndex int) e
 
 // EditRebaseTodo sets the action for a given rebase commit in the git-rebase-todo file
 func (self *RebaseCommands) EditRebaseTodo(commit *models.Commit, action todo.TodoCommand) error {
-	return utils.EditRebaseTodo(
-		filepath.Join(self.dotGitDir, "rebase-merge/git-rebase-todo"), commit.Sha, commit.Action, action)
+	fileName, err := self.rebaseTodoPath()
+	if err != nil {
+		return err
+	}
+	return utils.EditRebaseTodo(fileName, commit.Sha, commit.Action, action)
 }
 
 // MoveTodoDown moves a rebase todo item down by one position
 func (self *RebaseCommands) MoveTodoDown(commit *models.Commit) error {
-	fileName := filepath.Join(self.dotGitDir, "rebase-merge/git-rebase-todo")
+	fileName, err := self.rebaseTodoPath()
+	if err != nil {
+		return err
+	}
 	return utils.MoveTodoDown(fileName, commit.Sha, commit.Action)
 }
 
 // MoveTodoDown moves a rebase todo item down by one position
 func (self *RebaseCommands) MoveTodoUp(commit *models.Commit) error {
-	fileName := filepath.Join(self.dotGitDir, "rebase-merge/git-rebase-todo")
+	fileName, err := self.rebaseTodoPath()
+	if err != nil {
+		return err
+	}
 	return utils.MoveTodoUp(fileName, commit.Sha, commit.Action)
 }
 
+var ErrNotInInteractiveRebase = errors.New("not in an interactive rebase")
+
+func (self *RebaseCommands) rebaseTodoPath() (string, error) {
+	return self.gitDirPath("rebase-merge", "git-rebase-todo")
+}
+
+// IsInteractiveRebase tells us whether we're paused in the middle of an
+// interactive rebase (as opposed to a regular rebase or no rebase at all).
+// Since git 2.26 a regular rebase also uses the rebase-merge directory, so
+// it's the 'interactive' marker file in there that tells them apart.
+func (self *RebaseCommands) IsInteractiveRebase() (bool, error) {
+	return self.gitDirPathExists("rebase-merge", "interactive")
+}
+
+// RebaseTodo returns the remaining entries of the todo of the in-progress
+// interactive rebase, in the order that git will process them
+func (self *RebaseCommands) RebaseTodo() ([]*models.RebaseTodoEntry, error) {
+	isInteractive, err := self.IsInteractiveRebase()
+	if err != nil {
+		return nil, err
+	}
+	if !isInteractive {
+		return nil, ErrNotInInteractiveRebase
+	}
+
+	fileName, err := self.rebaseTodoPath()
+	if err != nil {
+		return nil, err
+	}
+
+	todos, err := utils.ReadRebaseTodoFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	return slices.Map(todos, func(t todo.Todo) *models.RebaseTodoEntry {
+		return &models.RebaseTodoEntry{
+			Action:      t.Command,
+			Sha:         t.Commit,
+			Subject:     t.Msg,
+			ExecCommand: t.ExecCommand,
+			Label:       t.Label,
+			Ref:         t.Ref,
+			Flag:        t.Flag,
+			Comment:     t.Comment,
+		}
+	}), nil
+}
+
+// WriteRebaseTodo replaces the todo of the in-progress interactive rebase
+// with the given entries
+func (self *RebaseCommands) WriteRebaseTodo(entries []*models.RebaseTodoEntry) error {
+	isInteractive, err := self.IsInteractiveRebase()
+	if err != nil {
+		return err
+	}
+	if !isInteractive {
+		return ErrNotInInteractiveRebase
+	}
+
+	todos := slices.Map(entries, func(entry *models.RebaseTodoEntry) todo.Todo {
+		return todo.Todo{
+			Command:     entry.Action,
+			Commit:      entry.Sha,
+			Msg:         entry.Subject,
+			ExecCommand: entry.ExecCommand,
+			Label:       entry.Label,
+			Ref:         entry.Ref,
+			Flag:        entry.Flag,
+			Comment:     entry.Comment,
+		}
+	})
+
+	fileName, err := self.rebaseTodoPath()
+	if err != nil {
+		return err
+	}
+
+	self.os.LogCommand("Updating rebase todo", false)
+
+	return utils.WriteRebaseTodoFile(fileName, todos)
+}
+
+// ReorderRebaseTodo moves the todo entry at fromIndex to toIndex, shifting the
+// entries in between. Indices refer to the slice returned by RebaseTodo. All
+// other entries (including exec lines) keep their relative order, and comment
+// lines stay where they are: they can neither be moved nor be used as a
+// destination, so an entry can't end up inside git's trailing help text. A move
+// that would leave a squash or fixup without a commit above it is rejected.
+func (self *RebaseCommands) ReorderRebaseTodo(fromIndex int, toIndex int) error {
+	entries, err := self.RebaseTodo()
+	if err != nil {
+		return err
+	}
+
+	for _, index := range []int{fromIndex, toIndex} {
+		if index < 0 || index >= len(entries) {
+			return fmt.Errorf("rebase todo index %d out of range", index)
+		}
+		if entries[index].Action == todo.Comment {
+			return fmt.Errorf("rebase todo entry %d is a comment", index)
+		}
+	}
+
+	// move the entry among the non-comment entries only, then put those back
+	// into the non-comment slots so that comment lines don't shift
+	slots := []int{}
+	for i, entry := range entries {
+		if entry.Action != todo.Comment {
+			slots = append(slots, i)
+		}
+	}
+	moved := utils.MoveElement(
+		lo.Map(slots, func(i int, _ int) *models.RebaseTodoEntry { return entries[i] }),
+		lo.IndexOf(slots, fromIndex),
+		lo.IndexOf(slots, toIndex),
+	)
+	reordered := make([]*models.RebaseTodoEntry, len(entries))
+	copy(reordered, entries)
+	for i, slot := range slots {
+		reordered[slot] = moved[i]
+	}
+
+	for i, entry := range reordered {
+		if (entry.Action == todo.Squash || entry.Action == todo.Fixup) && !hasCommitBefore(reordered, i) {
+			return fmt.Errorf("cannot %s the first commit of the rebase", entry.Action)
+		}
+	}
+
+	return self.WriteRebaseTodo(reordered)
+}
+
+// git needs a commit above a squash or fixup entry to squash it into
+func hasCommitBefore(entries []*models.RebaseTodoEntry, index int) bool {
+	return lo.SomeBy(entries[:index], func(e *models.RebaseTodoEntry) bool { return e.IsCommit() })
+}
+
+// SetRebaseTodoAction changes the action of the commit entry at the given
+// index (as returned by RebaseTodo) e.g. from pick to squash
+func (self *RebaseCommands) SetRebaseTodoAction(index int, action todo.TodoCommand) error {
+	if !lo.Contains([]todo.TodoCommand{todo.Pick, todo.Reword, todo.Edit, todo.Squash, todo.Fixup, todo.Drop}, action) {
+		return fmt.Errorf("cannot set rebase todo action to '%s'", action)
+	}
+
+	entries, err := self.RebaseTodo()
+	if err != nil {
+		return err
+	}
+
+	if index < 0 || index >= len(entries) {
+		return fmt.Errorf("rebase todo index %d out of range", index)
+	}
+
+	entry := entries[index]
+	if !entry.IsCommit() {
+		return fmt.Errorf("rebase todo entry %d is not a commit", index)
+	}
+
+	if action == todo.Squash || action == todo.Fixup {
+		if !hasCommitBefore(entries, index) {
+			return fmt.Errorf("cannot %s the first commit of the rebase", action)
+		}
+	}
+
+	entry.Action = action
+	if action != todo.Fixup {
+		// only fixup entries may carry a -C/-c flag
+		entry.Flag = ""
+	}
+
+	return self.WriteRebaseTodo(entries)
+}
+
 // SquashAllAboveFixupCommits squashes all fixup! commits above the given one
 func (self *RebaseCommands) SquashAllAboveFixupCommits(commit *models.Commit) error {
 	shaOrRoot := commit.Sha + "^"
@@ -344,6 +527,158 @@ func (self *RebaseCommands) GenericMergeOrRebaseAction(commandType string, comma
 	return nil
 }
 
+// RebaseConflictsError is returned when a rebase stops because of merge conflicts
+type RebaseConflictsError struct {
+	Files []*models.File
+}
+
+func (self *RebaseConflictsError) Error() string {
+	names := slices.Map(self.Files, func(file *models.File) string { return file.Name })
+	return "rebase stopped due to conflicts in: " + strings.Join(names, ", ")
+}
+
+// AmendAndContinueRebase is for when the rebase has paused on an 'edit' step:
+// it amends the current commit with whatever is staged (if anything) and then
+// continues the rebase, keeping the existing commit messages. If the rebase
+// stops again because of conflicts, a *RebaseConflictsError is returned.
+func (self *RebaseCommands) AmendAndContinueRebase() error {
+	// exits with 1 if there are staged changes, and with something else if it
+	// couldn't tell
+	if err := self.cmd.New("git diff --cached --quiet").DontLog().Run(); err != nil {
+		if err.Error() != "exit status 1" {
+			return err
+		}
+
+		if err := self.cmd.New("git commit --amend --no-edit").Run(); err != nil {
+			return err
+		}
+	}
+
+	err := self.cmd.New("git rebase --continue").AddEnvVars("GIT_EDITOR=true").Run()
+	if err != nil {
+		return self.wrapRebaseConflicts(err)
+	}
+
+	return nil
+}
+
+var ErrNotRebasing = errors.New("no rebase in progress")
+
+// SkipRebaseCommit drops the commit that the rebase is currently stopped on and
+// carries on with the rest of the rebase. If the rebase stops again because of
+// conflicts, a *RebaseConflictsError is returned; otherwise nil means the
+// rebase has finished or stopped on a later step e.g. an 'edit'.
+func (self *RebaseCommands) SkipRebaseCommit() error {
+	isRebasing, err := self.isRebasing()
+	if err != nil {
+		return err
+	}
+	if !isRebasing {
+		return ErrNotRebasing
+	}
+
+	if err := self.runSkipEditorCommand(self.GenericMergeOrRebaseActionCmdObj("rebase", "skip")); err != nil {
+		return self.wrapRebaseConflicts(err)
+	}
+
+	return nil
+}
+
+// CurrentRebaseCommit returns the commit that the rebase is currently stopped
+// on (e.g. for an 'edit' step, or because it had conflicts), or nil if the
+// rebase isn't stopped on a commit (e.g. for a 'break' step).
+func (self *RebaseCommands) CurrentRebaseCommit() (*models.Commit, error) {
+	isRebasing, err := self.isRebasing()
+	if err != nil {
+		return nil, err
+	}
+	if !isRebasing {
+		return nil, ErrNotRebasing
+	}
+
+	sha := self.currentRebaseSha()
+	if sha == "" {
+		return nil, nil
+	}
+
+	output, err := self.cmd.New("git show --no-patch --pretty=format:%H%x00%s " + sha).DontLog().RunWithOutput()
+	if err != nil {
+		return nil, err
+	}
+
+	fullSha, name, found := strings.Cut(strings.TrimSpace(output), "\x00")
+	if !found {
+		return nil, errors.New("unexpected git output")
+	}
+
+	return &models.Commit{
+		Sha:    fullSha,
+		Name:   name,
+		Status: models.StatusRebasing,
+	}, nil
+}
+
+// The two rebase backends store the current commit differently:
+// the merge backend (used for interactive rebases, and for regular rebases
+// since git 2.26) writes rebase-merge/stopped-sha when stopping on a commit,
+// and records processed steps in rebase-merge/done. The apply backend writes
+// rebase-apply/original-commit when a patch fails to apply.
+func (self *RebaseCommands) currentRebaseSha() string {
+	gitDir, err := self.GitDir()
+	if err != nil {
+		return ""
+	}
+
+	readFirstLine := func(path string) string {
+		content, err := os.ReadFile(filepath.Join(gitDir, path))
+		if err != nil {
+			return ""
+		}
+		firstLine, _, _ := strings.Cut(string(content), "\n")
+		return strings.TrimSpace(firstLine)
+	}
+
+	if sha := readFirstLine("rebase-merge/stopped-sha"); sha != "" {
+		return sha
+	}
+
+	if doneTodos, err := utils.ReadRebaseTodoFile(filepath.Join(gitDir, "rebase-merge/done")); err == nil {
+		if len(doneTodos) > 0 {
+			// the last completed step is the one we're stopped at, provided it
+			// was for a commit
+			return doneTodos[len(doneTodos)-1].Commit
+		}
+	}
+
+	return readFirstLine("rebase-apply/original-commit")
+}
+
+// covers both interactive and regular (i.e. 'apply' backend) rebases
+func (self *RebaseCommands) isRebasing() (bool, error) {
+	for _, dir := range []string{"rebase-merge", "rebase-apply"} {
+		exists, err := self.gitDirPathExists(dir)
+		if err != nil || exists {
+			return exists, err
+		}
+	}
+
+	return false, nil
+}
+
+// if the rebase stopped because of conflicts, returns a *RebaseConflictsError
+// listing them, otherwise returns the given error
+func (self *RebaseCommands) wrapRebaseConflicts(err error) error {
+	conflictedFiles := lo.Filter(
+		self.workingTree.fileLoader.GetStatusFiles(GetStatusFileOptions{}),
+		func(file *models.File, _ int) bool { return file.HasMergeConflicts },
+	)
+	if len(conflictedFiles) > 0 {
+		return &RebaseConflictsError{Files: conflictedFiles}
+	}
+
+	return err
+}
+
 func (self *RebaseCommands) runSkipEditorCommand(cmdObj oscommands.ICmdObj) error {
 	instruction := daemon.NewExitImmediatelyInstruction()
 	lazyGitPath := oscommands.GetLazygitPath()
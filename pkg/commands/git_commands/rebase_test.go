@@ -1,10 +1,13 @@
 package git_commands
 
 import (
+	"os"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"testing"
 
+	"github.com/fsmiamoto/git-todo-parser/todo"
 	"github.com/go-errors/errors"
 	"github.com/jesseduffield/lazygit/pkg/app/daemon"
 	"github.com/jesseduffield/lazygit/pkg/commands/git_config"
@@ -151,7 +154,7 @@ func TestRebaseDiscardOldFileChanges(t *testing.T) {
 			runner: oscommands.NewFakeRunner(t).
 				Expect(`git rebase --interactive --autostash --keep-empty --empty=keep --no-autosquash --rebase-merges abcdef`, "", nil).
 				Expect(`git cat-file -e HEAD^:"test999.txt"`, "", nil).
-				Expect(`git checkout HEAD^ -- "test999.txt"`, "", nil).
+				Expect(`git checkout "HEAD^" -- "test999.txt"`, "", nil).
 				Expect(`git commit --amend --no-edit --allow-empty`, "", nil).
 				Expect(`git rebase --continue`, "", nil),
 			test: func(err error) {
@@ -176,3 +179,468 @@ func TestRebaseDiscardOldFileChanges(t *testing.T) {
 		})
 	}
 }
+
+const rebaseTodoContent = `pick 1fc6c45 first commit
+exec make test
+fixup -C 7b2c91e second commit
+break
+drop a1b2c3d third commit
+# a comment
+`
+
+func writeRebaseTodo(t *testing.T, content string) string {
+	t.Helper()
+
+	dotGitDir := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(dotGitDir, "rebase-merge"), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dotGitDir, "rebase-merge/interactive"), nil, 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dotGitDir, "rebase-merge/git-rebase-todo"), []byte(content), 0o644))
+
+	return dotGitDir
+}
+
+func TestRebaseRebaseTodo(t *testing.T) {
+	instance := buildRebaseCommands(commonDeps{dotGitDir: writeRebaseTodo(t, rebaseTodoContent)})
+
+	entries, err := instance.RebaseTodo()
+	assert.NoError(t, err)
+	assert.EqualValues(t, []*models.RebaseTodoEntry{
+		{Action: todo.Pick, Sha: "1fc6c45", Subject: "first commit"},
+		{Action: todo.Exec, ExecCommand: "make test"},
+		{Action: todo.Fixup, Sha: "7b2c91e", Subject: "second commit", Flag: "-C"},
+		{Action: todo.Break},
+		{Action: todo.Drop, Sha: "a1b2c3d", Subject: "third commit"},
+		{Action: todo.Comment, Comment: " a comment"},
+	}, entries)
+}
+
+func TestRebaseRebaseTodoNotInInteractiveRebase(t *testing.T) {
+	instance := buildRebaseCommands(commonDeps{dotGitDir: t.TempDir()})
+
+	_, err := instance.RebaseTodo()
+	assert.Equal(t, ErrNotInInteractiveRebase, err)
+
+	assert.Equal(t, ErrNotInInteractiveRebase, instance.WriteRebaseTodo(nil))
+}
+
+func TestRebaseRebaseTodoInNonInteractiveRebase(t *testing.T) {
+	// a regular rebase uses the rebase-merge directory too, but without the
+	// 'interactive' marker file
+	dotGitDir := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(dotGitDir, "rebase-merge"), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dotGitDir, "rebase-merge/git-rebase-todo"), []byte(rebaseTodoContent), 0o644))
+	instance := buildRebaseCommands(commonDeps{dotGitDir: dotGitDir})
+
+	isInteractive, err := instance.IsInteractiveRebase()
+	assert.NoError(t, err)
+	assert.False(t, isInteractive)
+
+	_, err = instance.RebaseTodo()
+	assert.Equal(t, ErrNotInInteractiveRebase, err)
+}
+
+func TestRebaseRebaseTodoRoundTrip(t *testing.T) {
+	dotGitDir := writeRebaseTodo(t, rebaseTodoContent)
+	instance := buildRebaseCommands(commonDeps{dotGitDir: dotGitDir})
+
+	entries, err := instance.RebaseTodo()
+	assert.NoError(t, err)
+	assert.NoError(t, instance.WriteRebaseTodo(entries))
+
+	content, err := os.ReadFile(filepath.Join(dotGitDir, "rebase-merge/git-rebase-todo"))
+	assert.NoError(t, err)
+	assert.Equal(t, rebaseTodoContent, string(content))
+}
+
+func TestRebaseWriteRebaseTodo(t *testing.T) {
+	dotGitDir := writeRebaseTodo(t, "")
+	instance := buildRebaseCommands(commonDeps{dotGitDir: dotGitDir})
+
+	assert.NoError(t, instance.WriteRebaseTodo([]*models.RebaseTodoEntry{
+		{Action: todo.Reword, Sha: "1fc6c45", Subject: "first commit"},
+		{Action: todo.Exec, ExecCommand: "make test"},
+		{Action: todo.Break},
+		{Action: todo.Comment, Comment: " a comment"},
+	}))
+
+	content, err := os.ReadFile(filepath.Join(dotGitDir, "rebase-merge/git-rebase-todo"))
+	assert.NoError(t, err)
+	assert.Equal(t, "reword 1fc6c45 first commit\nexec make test\nbreak\n# a comment\n", string(content))
+}
+
+func TestRebaseReorderRebaseTodo(t *testing.T) {
+	type scenario struct {
+		testName        string
+		content         string
+		fromIndex       int
+		toIndex         int
+		expectedContent string
+		expectedError   string
+	}
+
+	scenarios := []scenario{
+		{
+			testName:        "move pick to the end",
+			fromIndex:       0,
+			toIndex:         4,
+			expectedContent: rebaseTodoContent,
+			expectedError:   "cannot fixup the first commit of the rebase",
+		},
+		{
+			testName:  "move fixup to the end",
+			fromIndex: 2,
+			toIndex:   4,
+			expectedContent: `pick 1fc6c45 first commit
+exec make test
+break
+drop a1b2c3d third commit
+fixup -C 7b2c91e second commit
+# a comment
+`,
+		},
+		{
+			testName: "move across a comment",
+			content: `pick 1fc6c45 first commit
+# a comment
+pick 7b2c91e second commit
+pick a1b2c3d third commit
+`,
+			fromIndex: 3,
+			toIndex:   0,
+			expectedContent: `pick a1b2c3d third commit
+# a comment
+pick 1fc6c45 first commit
+pick 7b2c91e second commit
+`,
+		},
+		{
+			testName:  "move drop to the start",
+			fromIndex: 4,
+			toIndex:   0,
+			expectedContent: `drop a1b2c3d third commit
+pick 1fc6c45 first commit
+exec make test
+fixup -C 7b2c91e second commit
+break
+# a comment
+`,
+		},
+		{
+			testName:        "index out of range",
+			fromIndex:       0,
+			toIndex:         6,
+			expectedContent: rebaseTodoContent,
+			expectedError:   "rebase todo index 6 out of range",
+		},
+		{
+			testName:        "destination is a comment",
+			fromIndex:       0,
+			toIndex:         5,
+			expectedContent: rebaseTodoContent,
+			expectedError:   "rebase todo entry 5 is a comment",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			todoContent := s.content
+			if todoContent == "" {
+				todoContent = rebaseTodoContent
+			}
+			dotGitDir := writeRebaseTodo(t, todoContent)
+			instance := buildRebaseCommands(commonDeps{dotGitDir: dotGitDir})
+
+			err := instance.ReorderRebaseTodo(s.fromIndex, s.toIndex)
+			if s.expectedError != "" {
+				assert.EqualError(t, err, s.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			content, err := os.ReadFile(filepath.Join(dotGitDir, "rebase-merge/git-rebase-todo"))
+			assert.NoError(t, err)
+			assert.Equal(t, s.expectedContent, string(content))
+		})
+	}
+}
+
+func TestRebaseSetRebaseTodoAction(t *testing.T) {
+	type scenario struct {
+		testName        string
+		index           int
+		action          todo.TodoCommand
+		expectedContent string
+		expectedError   string
+	}
+
+	scenarios := []scenario{
+		{
+			testName: "squash a later commit",
+			index:    4,
+			action:   todo.Squash,
+			expectedContent: `pick 1fc6c45 first commit
+exec make test
+fixup -C 7b2c91e second commit
+break
+squash a1b2c3d third commit
+# a comment
+`,
+		},
+		{
+			testName: "turn fixup into reword",
+			index:    2,
+			action:   todo.Reword,
+			expectedContent: `pick 1fc6c45 first commit
+exec make test
+reword 7b2c91e second commit
+break
+drop a1b2c3d third commit
+# a comment
+`,
+		},
+		{
+			testName:        "fixup the first commit",
+			index:           0,
+			action:          todo.Fixup,
+			expectedContent: rebaseTodoContent,
+			expectedError:   "cannot fixup the first commit of the rebase",
+		},
+		{
+			testName:        "entry is not a commit",
+			index:           1,
+			action:          todo.Pick,
+			expectedContent: rebaseTodoContent,
+			expectedError:   "rebase todo entry 1 is not a commit",
+		},
+		{
+			testName:        "unsupported action",
+			index:           0,
+			action:          todo.Exec,
+			expectedContent: rebaseTodoContent,
+			expectedError:   "cannot set rebase todo action to 'exec'",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			dotGitDir := writeRebaseTodo(t, rebaseTodoContent)
+			instance := buildRebaseCommands(commonDeps{dotGitDir: dotGitDir})
+
+			err := instance.SetRebaseTodoAction(s.index, s.action)
+			if s.expectedError != "" {
+				assert.EqualError(t, err, s.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			content, err := os.ReadFile(filepath.Join(dotGitDir, "rebase-merge/git-rebase-todo"))
+			assert.NoError(t, err)
+			assert.Equal(t, s.expectedContent, string(content))
+		})
+	}
+}
+
+func TestRebaseAmendAndContinueRebase(t *testing.T) {
+	type scenario struct {
+		testName string
+		runner   *oscommands.FakeCmdObjRunner
+		test     func(error)
+	}
+
+	scenarios := []scenario{
+		{
+			testName: "staged changes are amended before continuing",
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git diff --cached --quiet`, "", errors.New("exit status 1")).
+				Expect(`git commit --amend --no-edit`, "", nil).
+				Expect(`git rebase --continue`, "", nil),
+			test: func(err error) {
+				assert.NoError(t, err)
+			},
+		},
+		{
+			testName: "nothing staged",
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git diff --cached --quiet`, "", nil).
+				Expect(`git rebase --continue`, "", nil),
+			test: func(err error) {
+				assert.NoError(t, err)
+			},
+		},
+		{
+			testName: "checking for staged changes fails",
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git diff --cached --quiet`, "", errors.New("fatal: unable to read index")),
+			test: func(err error) {
+				assert.EqualError(t, err, "fatal: unable to read index")
+			},
+		},
+		{
+			testName: "checking for staged changes fails without output",
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git diff --cached --quiet`, "", errors.New("exit status 128")),
+			test: func(err error) {
+				assert.EqualError(t, err, "exit status 128")
+			},
+		},
+		{
+			testName: "continuing hits conflicts",
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git diff --cached --quiet`, "", nil).
+				Expect(`git rebase --continue`, "", errors.New("conflict")).
+				Expect(`git status --untracked-files=all --porcelain -z`, "UU file1.txt\x00M  file2.txt\x00AA file3.txt", nil),
+			test: func(err error) {
+				var conflictsErr *RebaseConflictsError
+				assert.ErrorAs(t, err, &conflictsErr)
+				assert.Equal(t, []string{"file1.txt", "file3.txt"},
+					lo.Map(conflictsErr.Files, func(file *models.File, _ int) string { return file.Name }))
+			},
+		},
+		{
+			testName: "continuing fails for another reason",
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git diff --cached --quiet`, "", nil).
+				Expect(`git rebase --continue`, "", errors.New("error")).
+				Expect(`git status --untracked-files=all --porcelain -z`, "", nil),
+			test: func(err error) {
+				assert.EqualError(t, err, "error")
+			},
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			instance := buildRebaseCommands(commonDeps{runner: s.runner})
+			s.test(instance.AmendAndContinueRebase())
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestRebaseSkipRebaseCommit(t *testing.T) {
+	type scenario struct {
+		testName  string
+		rebaseDir string
+		runner    *oscommands.FakeCmdObjRunner
+		test      func(error)
+	}
+
+	scenarios := []scenario{
+		{
+			testName:  "not rebasing",
+			rebaseDir: "",
+			runner:    oscommands.NewFakeRunner(t),
+			test: func(err error) {
+				assert.Equal(t, ErrNotRebasing, err)
+			},
+		},
+		{
+			testName:  "rebase finishes",
+			rebaseDir: "rebase-apply",
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git rebase --skip`, "", nil),
+			test: func(err error) {
+				assert.NoError(t, err)
+			},
+		},
+		{
+			testName:  "rebase stops on conflicts",
+			rebaseDir: "rebase-merge",
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git rebase --skip`, "", errors.New("conflict")).
+				Expect(`git status --untracked-files=all --porcelain -z`, "UU file1.txt", nil),
+			test: func(err error) {
+				assert.EqualError(t, err, "rebase stopped due to conflicts in: file1.txt")
+			},
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			dotGitDir := t.TempDir()
+			if s.rebaseDir != "" {
+				assert.NoError(t, os.Mkdir(filepath.Join(dotGitDir, s.rebaseDir), 0o755))
+			}
+
+			instance := buildRebaseCommands(commonDeps{runner: s.runner, dotGitDir: dotGitDir})
+			s.test(instance.SkipRebaseCommit())
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestRebaseCurrentRebaseCommit(t *testing.T) {
+	type scenario struct {
+		testName    string
+		files       map[string]string
+		runner      *oscommands.FakeCmdObjRunner
+		expected    *models.Commit
+		expectedErr error
+	}
+
+	scenarios := []scenario{
+		{
+			testName:    "not rebasing",
+			files:       map[string]string{},
+			runner:      oscommands.NewFakeRunner(t),
+			expectedErr: ErrNotRebasing,
+		},
+		{
+			testName: "interactive rebase stopped on a commit",
+			files: map[string]string{
+				"rebase-merge/stopped-sha": "1fc6c45\n",
+				"rebase-merge/done":        "pick 1fc6c45 first commit\n",
+			},
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git show --no-patch --pretty=format:%H%x00%s 1fc6c45`, "1fc6c45abc\x00first commit", nil),
+			expected: &models.Commit{Sha: "1fc6c45abc", Name: "first commit", Status: models.StatusRebasing},
+		},
+		{
+			testName: "interactive rebase without stopped-sha",
+			files: map[string]string{
+				"rebase-merge/done": "pick 1fc6c45 first commit\nedit 7b2c91e second commit\n",
+			},
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git show --no-patch --pretty=format:%H%x00%s 7b2c91e`, "7b2c91eabc\x00second commit", nil),
+			expected: &models.Commit{Sha: "7b2c91eabc", Name: "second commit", Status: models.StatusRebasing},
+		},
+		{
+			testName: "interactive rebase stopped at a break",
+			files: map[string]string{
+				"rebase-merge/done": "pick 1fc6c45 first commit\nbreak\n",
+			},
+			runner:   oscommands.NewFakeRunner(t),
+			expected: nil,
+		},
+		{
+			testName: "apply backend",
+			files: map[string]string{
+				"rebase-apply/original-commit": "a1b2c3d4e5\n",
+			},
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git show --no-patch --pretty=format:%H%x00%s a1b2c3d4e5`, "a1b2c3d4e5\x00third commit", nil),
+			expected: &models.Commit{Sha: "a1b2c3d4e5", Name: "third commit", Status: models.StatusRebasing},
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			dotGitDir := t.TempDir()
+			for path, content := range s.files {
+				assert.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dotGitDir, path)), 0o755))
+				assert.NoError(t, os.WriteFile(filepath.Join(dotGitDir, path), []byte(content), 0o644))
+			}
+
+			instance := buildRebaseCommands(commonDeps{runner: s.runner, dotGitDir: dotGitDir})
+			commit, err := instance.CurrentRebaseCommit()
+			assert.Equal(t, s.expectedErr, err)
+			assert.Equal(t, s.expected, commit)
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
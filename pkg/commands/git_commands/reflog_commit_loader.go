@@ -75,3 +75,41 @@ func (self *ReflogCommitLoader) GetReflogCommits(lastReflogCommit *models.Commit
 
 	return commits, onlyObtainedNewReflogCommits, nil
 }
+
+// RecentlyDiscardedCommits returns the commits that HEAD was on before each
+// reset in the reflog which moved it, most recent first. A hard reset away
+// from a commit leaves it unreachable, so this is how to find it again. The
+// name of each commit is the reflog message of the entry that took HEAD to it.
+// Resets which didn't move HEAD (e.g. `git reset --hard HEAD`, which is what
+// ResetAndClean does) are left out, as there's nothing to recover from them.
+func (self *ReflogCommitLoader) RecentlyDiscardedCommits() ([]*models.Commit, error) {
+	reflogCommits, _, err := self.GetReflogCommits(nil, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return discardedCommits(reflogCommits), nil
+}
+
+// the reflog is newest first, so the entry after a reset is where HEAD was
+// before it
+func discardedCommits(reflogCommits []*models.Commit) []*models.Commit {
+	result := []*models.Commit{}
+	seen := map[string]bool{}
+
+	for i := 0; i < len(reflogCommits)-1; i++ {
+		if !strings.HasPrefix(reflogCommits[i].Name, "reset: ") {
+			continue
+		}
+
+		previous := reflogCommits[i+1]
+		if previous.Sha == reflogCommits[i].Sha || seen[previous.Sha] {
+			continue
+		}
+
+		seen[previous.Sha] = true
+		result = append(result, previous)
+	}
+
+	return result
+}
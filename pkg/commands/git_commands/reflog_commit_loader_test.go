@@ -5,6 +5,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/jesseduffield/generics/slices"
 	"github.com/jesseduffield/lazygit/pkg/commands/models"
 	"github.com/jesseduffield/lazygit/pkg/commands/oscommands"
 	"github.com/jesseduffield/lazygit/pkg/utils"
@@ -167,3 +168,83 @@ func TestGetReflogCommits(t *testing.T) {
 		})
 	}
 }
+
+func TestRecentlyDiscardedCommits(t *testing.T) {
+	type scenario struct {
+		testName      string
+		reflogOutput  string
+		expectedShas  []string
+		expectedNames []string
+		expectedError error
+	}
+
+	scenarios := []scenario{
+		{
+			testName:      "no reflog entries",
+			reflogOutput:  "",
+			expectedShas:  []string{},
+			expectedNames: []string{},
+		},
+		{
+			testName: "resets which moved HEAD",
+			reflogOutput: strings.Replace(`aaaa|1643150483|reset: moving to HEAD~1|bbbb
+cccc|1643150483|commit: add feature|aaaa
+aaaa|1643150483|checkout: moving from A to master|bbbb
+dddd|1643150483|reset: moving to dddd|eeee
+ffff|1643150483|commit (amend): fix typo|eeee
+`, "|", "\x00", -1),
+			expectedShas:  []string{"cccc", "ffff"},
+			expectedNames: []string{"commit: add feature", "commit (amend): fix typo"},
+		},
+		{
+			testName: "resets which didn't move HEAD are left out",
+			reflogOutput: strings.Replace(`aaaa|1643150483|reset: moving to HEAD|bbbb
+aaaa|1643150483|commit: add feature|bbbb
+`, "|", "\x00", -1),
+			expectedShas:  []string{},
+			expectedNames: []string{},
+		},
+		{
+			testName: "a commit reset away from twice is only given once",
+			reflogOutput: strings.Replace(`aaaa|1643150483|reset: moving to HEAD~1|bbbb
+cccc|1643150483|reset: moving to cccc|aaaa
+aaaa|1643150483|reset: moving to HEAD~1|bbbb
+cccc|1643150483|commit: add feature|aaaa
+`, "|", "\x00", -1),
+			expectedShas:  []string{"cccc", "aaaa"},
+			expectedNames: []string{"reset: moving to cccc", "reset: moving to HEAD~1"},
+		},
+		{
+			testName: "a reset as the oldest entry",
+			reflogOutput: strings.Replace(`aaaa|1643150483|reset: moving to HEAD~1|bbbb
+`, "|", "\x00", -1),
+			expectedShas:  []string{},
+			expectedNames: []string{},
+		},
+		{
+			testName:      "when command returns error",
+			expectedError: errors.New("haha"),
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			runner := oscommands.NewFakeRunner(t).
+				Expect(`git -c log.showSignature=false log -g --abbrev=40 --format="%h%x00%ct%x00%gs%x00%p"`, s.reflogOutput, s.expectedError)
+			loader := &ReflogCommitLoader{
+				Common: utils.NewDummyCommon(),
+				cmd:    oscommands.NewDummyCmdObjBuilder(runner),
+			}
+
+			commits, err := loader.RecentlyDiscardedCommits()
+			assert.Equal(t, s.expectedError, err)
+			if err == nil {
+				assert.Equal(t, s.expectedShas, slices.Map(commits, func(commit *models.Commit) string { return commit.Sha }))
+				assert.Equal(t, s.expectedNames, slices.Map(commits, func(commit *models.Commit) string { return commit.Name }))
+			}
+
+			runner.CheckForMissingCalls()
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package git_commands
+
+import (
+	"strings"
+
+	"github.com/jesseduffield/go-git/v5/plumbing/format/gitignore"
+)
+
+// returns a function telling whether the given path is inside the sparse
+// checkout defined by the given patterns, as output by `git sparse-checkout list`
+func sparseCheckoutMatcher(patterns []string, cone bool) func(path string) bool {
+	if cone {
+		return coneModeMatcher(patterns)
+	}
+
+	return nonConeModeMatcher(patterns)
+}
+
+// In cone mode the patterns are directories. A file is included if it's inside
+// one of those directories, or if it sits directly in one of their ancestors
+// (which includes the top level of the repo).
+func coneModeMatcher(dirs []string) func(path string) bool {
+	return func(path string) bool {
+		parentDir := ""
+		if i := strings.LastIndex(path, "/"); i != -1 {
+			parentDir = path[:i]
+		}
+
+		if parentDir == "" {
+			return true
+		}
+
+		for _, dir := range dirs {
+			dir = strings.TrimSuffix(dir, "/")
+			if strings.HasPrefix(path, dir+"/") || strings.HasPrefix(dir, parentDir+"/") {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// In non-cone mode the patterns use .gitignore syntax, except that a match
+// means the file is included rather than ignored
+func nonConeModeMatcher(patterns []string) func(path string) bool {
+	return gitignoreMatcher(patterns)
+}
+
+// returns a function telling us whether a path matches any of the given
+// patterns, which use .gitignore syntax (including negation with '!')
+func gitignoreMatcher(patterns []string) func(path string) bool {
+	parsedPatterns := make([]gitignore.Pattern, 0, len(patterns))
+	for _, pattern := range patterns {
+		if pattern == "" || strings.HasPrefix(pattern, "#") {
+			continue
+		}
+		parsedPatterns = append(parsedPatterns, gitignore.ParsePattern(pattern, nil))
+	}
+
+	matcher := gitignore.NewMatcher(parsedPatterns)
+
+	return func(path string) bool {
+		return matcher.Match(strings.Split(path, "/"), false)
+	}
+}
@@ -0,0 +1,56 @@
+package git_commands
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSparseCheckoutMatcher(t *testing.T) {
+	type scenario struct {
+		testName string
+		patterns []string
+		cone     bool
+		expected map[string]bool
+	}
+
+	scenarios := []scenario{
+		{
+			testName: "cone mode",
+			patterns: []string{"src/app"},
+			cone:     true,
+			expected: map[string]bool{
+				"README.md":             true,
+				"src/Makefile":          true,
+				"src/app/main.go":       true,
+				"src/app/pkg/util.go":   true,
+				"src/lib/lib.go":        false,
+				"docs/guide.md":         false,
+				"src/application/x.go":  false,
+				"src/app.go":            true,
+				"src/app/nested/dir/fi": true,
+			},
+		},
+		{
+			testName: "non-cone mode",
+			patterns: []string{"# a comment", "/*", "!/*/", "/docs/", "!/docs/internal/"},
+			cone:     false,
+			expected: map[string]bool{
+				"README.md":              true,
+				"docs/guide.md":          true,
+				"docs/internal/notes.md": false,
+				"src/main.go":            false,
+			},
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			isIncluded := sparseCheckoutMatcher(s.patterns, s.cone)
+			for path, expected := range s.expected {
+				assert.Equal(t, expected, isIncluded(path), path)
+			}
+		})
+	}
+}
@@ -4,9 +4,12 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/go-errors/errors"
 	"github.com/jesseduffield/lazygit/pkg/commands/oscommands"
 )
 
+var ErrNoLocalChangesToSave = errors.New("no local changes to save")
+
 type StashCommands struct {
 	*GitCommon
 	fileLoader  *FileLoader
@@ -82,9 +85,14 @@ func (self *StashCommands) StashUnstagedChanges(message string) error {
 	return nil
 }
 
-// SaveStagedChanges stashes only the currently staged changes. This takes a few steps
+// SaveStagedChanges stashes only the currently staged changes. Older versions of
+// git can't do this directly so it takes a few steps
 // shoutouts to Joe on https://stackoverflow.com/questions/14759748/stashing-only-staged-changes-in-git-is-it-possible
 func (self *StashCommands) SaveStagedChanges(message string) error {
+	if self.features.SupportsStashStaged {
+		return self.cmd.New("git stash push --staged -m " + self.cmd.Quote(message)).Run()
+	}
+
 	// wrap in 'writing', which uses a mutex
 	if err := self.cmd.New("git stash --keep-index").Run(); err != nil {
 		return err
@@ -123,6 +131,63 @@ func (self *StashCommands) SaveStagedChanges(message string) error {
 	return nil
 }
 
+type StashOpts struct {
+	// also stash untracked files (-u), removing them from the working tree
+	IncludeUntracked bool
+	// leave the changes that are staged in place after stashing them
+	KeepIndex bool
+	// also stash untracked and ignored files (-a). This takes in everything
+	// IncludeUntracked would, so there's no need to set both.
+	All bool
+}
+
+// StashSave returns the command for stashing local changes, with opts deciding
+// which. If there's nothing for it to stash, ErrNoLocalChangesToSave is
+// returned instead, because git would succeed without creating a stash entry.
+func (self *StashCommands) StashSave(message string, opts StashOpts) (oscommands.ICmdObj, error) {
+	hasChanges, err := self.hasChangesToStash(opts)
+	if err != nil {
+		return nil, err
+	}
+	if !hasChanges {
+		return nil, ErrNoLocalChangesToSave
+	}
+
+	flags := ""
+	// git refuses to be given both
+	if opts.All {
+		flags += " --all"
+	} else if opts.IncludeUntracked {
+		flags += " --include-untracked"
+	}
+	if opts.KeepIndex {
+		flags += " --keep-index"
+	}
+
+	messageArg := ""
+	if message != "" {
+		messageArg = " -m " + self.cmd.Quote(message)
+	}
+
+	return self.cmd.New("git stash push" + flags + messageArg), nil
+}
+
+func (self *StashCommands) hasChangesToStash(opts StashOpts) (bool, error) {
+	args := " --untracked-files=no"
+	if opts.All {
+		args = " --untracked-files=normal --ignored"
+	} else if opts.IncludeUntracked {
+		args = " --untracked-files=normal"
+	}
+
+	output, err := self.cmd.New("git status --porcelain -z" + args).DontLog().RunWithOutput()
+	if err != nil {
+		return false, err
+	}
+
+	return output != "", nil
+}
+
 func (self *StashCommands) StashIncludeUntrackedChanges(message string) error {
 	return self.cmd.New(fmt.Sprintf("git stash save %s --include-untracked", self.cmd.Quote(message))).Run()
 }
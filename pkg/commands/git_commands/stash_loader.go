@@ -55,7 +55,7 @@ outer:
 		currentStashEntry = self.stashEntryFromLine(lines[i], idx)
 		for i+1 < len(lines) && !isAStash(lines[i+1]) {
 			i++
-			if lines[i] == filterPath {
+			if unquoteGitPath(lines[i]) == filterPath {
 				stashEntries = append(stashEntries, currentStashEntry)
 				continue outer
 			}
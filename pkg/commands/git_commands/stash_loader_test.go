@@ -45,6 +45,22 @@ func TestGetStashEntries(t *testing.T) {
 				},
 			},
 		},
+		{
+			"Filtering by a path git quotes",
+			"café.txt",
+			oscommands.NewFakeRunner(t).
+				Expect(
+					`git stash list --name-only`,
+					"stash@{0}: WIP on master: 55c6af2 first\n\"caf\\303\\251.txt\"\nother.txt\nstash@{1}: WIP on master: bb86a3f second\nother.txt\n",
+					nil,
+				),
+			[]*models.StashEntry{
+				{
+					Index: 0,
+					Name:  "stash@{0}: WIP on master: 55c6af2 first",
+				},
+			},
+		},
 	}
 
 	for _, s := range scenarios {
@@ -44,6 +44,84 @@ func TestStashSave(t *testing.T) {
 	runner.CheckForMissingCalls()
 }
 
+func TestStashStashSave(t *testing.T) {
+	type scenario struct {
+		testName      string
+		message       string
+		opts          StashOpts
+		statusCmd     string
+		statusOutput  string
+		expectedCmd   string
+		expectedError error
+	}
+
+	scenarios := []scenario{
+		{
+			testName:     "tracked changes only",
+			message:      "A stash message",
+			statusCmd:    `git status --porcelain -z --untracked-files=no`,
+			statusOutput: " M file.txt\x00",
+			expectedCmd:  `git stash push -m "A stash message"`,
+		},
+		{
+			testName:     "without a message",
+			statusCmd:    `git status --porcelain -z --untracked-files=no`,
+			statusOutput: " M file.txt\x00",
+			expectedCmd:  `git stash push`,
+		},
+		{
+			testName:     "including untracked files and keeping the index",
+			message:      "A stash message",
+			opts:         StashOpts{IncludeUntracked: true, KeepIndex: true},
+			statusCmd:    `git status --porcelain -z --untracked-files=normal`,
+			statusOutput: "?? new.txt\x00",
+			expectedCmd:  `git stash push --include-untracked --keep-index -m "A stash message"`,
+		},
+		{
+			testName:     "all takes precedence over including untracked files",
+			message:      "A stash message",
+			opts:         StashOpts{IncludeUntracked: true, All: true},
+			statusCmd:    `git status --porcelain -z --untracked-files=normal --ignored`,
+			statusOutput: "!! build/\x00",
+			expectedCmd:  `git stash push --all -m "A stash message"`,
+		},
+		{
+			testName:      "nothing to stash",
+			message:       "A stash message",
+			statusCmd:     `git status --porcelain -z --untracked-files=no`,
+			statusOutput:  "",
+			expectedError: ErrNoLocalChangesToSave,
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			runner := oscommands.NewFakeRunner(t).
+				Expect(s.statusCmd, s.statusOutput, nil)
+			instance := buildStashCommands(commonDeps{runner: runner})
+
+			cmdObj, err := instance.StashSave(s.message, s.opts)
+			if s.expectedError != nil {
+				assert.ErrorIs(t, err, s.expectedError)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, s.expectedCmd, cmdObj.ToString())
+			}
+			runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestStashSaveStagedChanges(t *testing.T) {
+	runner := oscommands.NewFakeRunner(t).
+		Expect(`git stash push --staged -m "A stash message"`, "", nil)
+	instance := buildStashCommands(commonDeps{runner: runner, gitVersion: &GitVersion{2, 35, 0, ""}})
+
+	assert.NoError(t, instance.SaveStagedChanges("A stash message"))
+	runner.CheckForMissingCalls()
+}
+
 func TestStashStore(t *testing.T) {
 	type scenario struct {
 		testName string
@@ -1,7 +1,6 @@
 package git_commands
 
 import (
-	"path/filepath"
 	"strconv"
 	"strings"
 
@@ -24,14 +23,14 @@ func NewStatusCommands(
 // RebaseMode returns "" for non-rebase mode, "normal" for normal rebase
 // and "interactive" for interactive rebase
 func (self *StatusCommands) RebaseMode() (enums.RebaseMode, error) {
-	exists, err := self.os.FileExists(filepath.Join(self.dotGitDir, "rebase-apply"))
+	exists, err := self.gitDirPathExists("rebase-apply")
 	if err != nil {
 		return enums.REBASE_MODE_NONE, err
 	}
 	if exists {
 		return enums.REBASE_MODE_NORMAL, nil
 	}
-	exists, err = self.os.FileExists(filepath.Join(self.dotGitDir, "rebase-merge"))
+	exists, err = self.gitDirPathExists("rebase-merge")
 	if exists {
 		return enums.REBASE_MODE_INTERACTIVE, err
 	} else {
@@ -51,6 +50,60 @@ func (self *StatusCommands) WorkingTreeState() enums.RebaseMode {
 	return enums.REBASE_MODE_NONE
 }
 
+// CurrentOperation returns the merge, rebase, cherry-pick etc. that is in
+// progress, based on the state files git leaves in the .git directory.
+func (self *StatusCommands) CurrentOperation() (enums.GitOperation, error) {
+	exists := self.gitDirPathExists
+
+	// rebases are checked first because a rebase can itself stop on a merge or
+	// a cherry-pick, in which case it's the rebase that needs continuing
+	rebasing, err := exists("rebase-merge")
+	if err != nil {
+		return enums.GIT_OPERATION_NONE, err
+	}
+	if rebasing {
+		return enums.GIT_OPERATION_REBASING_MERGE, nil
+	}
+
+	// git am uses the rebase-apply directory too, marking it with an 'applying'
+	// file where a rebase marks it with a 'rebasing' file. If neither is there
+	// (e.g. because git is still setting the directory up) we assume a rebase,
+	// as RebaseMode does.
+	applyDirExists, err := exists("rebase-apply")
+	if err != nil {
+		return enums.GIT_OPERATION_NONE, err
+	}
+	if applyDirExists {
+		applying, err := exists("rebase-apply/applying")
+		if err != nil {
+			return enums.GIT_OPERATION_NONE, err
+		}
+		if applying {
+			return enums.GIT_OPERATION_APPLYING_MAILBOX, nil
+		}
+		return enums.GIT_OPERATION_REBASING_APPLY, nil
+	}
+
+	for _, operation := range []struct {
+		file      string
+		operation enums.GitOperation
+	}{
+		{file: "MERGE_HEAD", operation: enums.GIT_OPERATION_MERGING},
+		{file: "CHERRY_PICK_HEAD", operation: enums.GIT_OPERATION_CHERRY_PICKING},
+		{file: "REVERT_HEAD", operation: enums.GIT_OPERATION_REVERTING},
+	} {
+		inProgress, err := exists(operation.file)
+		if err != nil {
+			return enums.GIT_OPERATION_NONE, err
+		}
+		if inProgress {
+			return operation.operation, nil
+		}
+	}
+
+	return enums.GIT_OPERATION_NONE, nil
+}
+
 func (self *StatusCommands) IsBareRepo() (bool, error) {
 	return IsBareRepo(self.os)
 }
@@ -67,5 +120,5 @@ func IsBareRepo(osCommand *oscommands.OSCommand) (bool, error) {
 
 // IsInMergeState states whether we are still mid-merge
 func (self *StatusCommands) IsInMergeState() (bool, error) {
-	return self.os.FileExists(filepath.Join(self.dotGitDir, "MERGE_HEAD"))
+	return self.gitDirPathExists("MERGE_HEAD")
 }
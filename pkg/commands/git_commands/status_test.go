@@ -0,0 +1,88 @@
+package git_commands
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jesseduffield/lazygit/pkg/commands/types/enums"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatusCurrentOperation(t *testing.T) {
+	type scenario struct {
+		testName string
+		// paths to create in the .git dir; those ending in a slash are directories
+		paths    []string
+		expected enums.GitOperation
+	}
+
+	scenarios := []scenario{
+		{
+			testName: "nothing in progress",
+			paths:    []string{},
+			expected: enums.GIT_OPERATION_NONE,
+		},
+		{
+			testName: "merging",
+			paths:    []string{"MERGE_HEAD"},
+			expected: enums.GIT_OPERATION_MERGING,
+		},
+		{
+			testName: "interactive rebase",
+			paths:    []string{"rebase-merge/", "rebase-merge/git-rebase-todo"},
+			expected: enums.GIT_OPERATION_REBASING_MERGE,
+		},
+		{
+			testName: "rebase stopped on a conflicting merge",
+			paths:    []string{"rebase-merge/", "MERGE_HEAD"},
+			expected: enums.GIT_OPERATION_REBASING_MERGE,
+		},
+		{
+			testName: "apply rebase",
+			paths:    []string{"rebase-apply/", "rebase-apply/rebasing"},
+			expected: enums.GIT_OPERATION_REBASING_APPLY,
+		},
+		{
+			testName: "apply rebase directory without a marker",
+			paths:    []string{"rebase-apply/"},
+			expected: enums.GIT_OPERATION_REBASING_APPLY,
+		},
+		{
+			testName: "git am",
+			paths:    []string{"rebase-apply/", "rebase-apply/applying"},
+			expected: enums.GIT_OPERATION_APPLYING_MAILBOX,
+		},
+		{
+			testName: "cherry-picking",
+			paths:    []string{"CHERRY_PICK_HEAD"},
+			expected: enums.GIT_OPERATION_CHERRY_PICKING,
+		},
+		{
+			testName: "reverting",
+			paths:    []string{"REVERT_HEAD"},
+			expected: enums.GIT_OPERATION_REVERTING,
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			dotGitDir := t.TempDir()
+			for _, path := range s.paths {
+				fullPath := filepath.Join(dotGitDir, path)
+				if path[len(path)-1] == '/' {
+					assert.NoError(t, os.MkdirAll(fullPath, 0o755))
+				} else {
+					assert.NoError(t, os.WriteFile(fullPath, []byte{}, 0o644))
+				}
+			}
+
+			instance := buildStatusCommands(commonDeps{dotGitDir: dotGitDir})
+
+			operation, err := instance.CurrentOperation()
+			assert.NoError(t, err)
+			assert.Equal(t, s.expected, operation)
+		})
+	}
+}
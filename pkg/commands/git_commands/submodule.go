@@ -8,6 +8,7 @@ import (
 	"regexp"
 	"strings"
 
+	"github.com/jesseduffield/generics/slices"
 	"github.com/jesseduffield/lazygit/pkg/commands/models"
 	"github.com/jesseduffield/lazygit/pkg/commands/oscommands"
 )
@@ -27,6 +28,19 @@ func NewSubmoduleCommands(gitCommon *GitCommon) *SubmoduleCommands {
 	}
 }
 
+// IsInsideSubmodule tells us whether the repo we're in is a submodule of
+// another repo and if so, returns the path of the parent repo's working tree
+func (self *SubmoduleCommands) IsInsideSubmodule() (bool, string, error) {
+	output, err := self.cmd.New("git rev-parse --show-superproject-working-tree").DontLog().RunWithOutput()
+	if err != nil {
+		return false, "", err
+	}
+
+	// the output is empty if we're not in a submodule
+	parentPath := strings.TrimSpace(output)
+	return parentPath != "", parentPath, nil
+}
+
 func (self *SubmoduleCommands) GetConfigs() ([]*models.SubmoduleConfig, error) {
 	file, err := os.Open(".gitmodules")
 	if err != nil {
@@ -74,6 +88,101 @@ func (self *SubmoduleCommands) GetConfigs() ([]*models.SubmoduleConfig, error) {
 	return configs, nil
 }
 
+// GetStatuses returns the status of each of the repo's submodules, keyed by path
+func (self *SubmoduleCommands) GetStatuses() (map[string]*models.SubmoduleStatus, error) {
+	configs, err := self.GetConfigs()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(configs) == 0 {
+		return map[string]*models.SubmoduleStatus{}, nil
+	}
+
+	quotedPaths := slices.Map(configs, func(config *models.SubmoduleConfig) string {
+		return self.cmd.Quote(config.Path)
+	})
+
+	// unlike the v1 format, porcelain v2 tells us exactly how a submodule is dirty
+	output, err := self.cmd.New(
+		"git status --porcelain=v2 -z --ignore-submodules=none -- " + strings.Join(quotedPaths, " "),
+	).DontLog().RunWithOutput()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := parseSubmoduleStatuses(output)
+	for _, config := range configs {
+		status, ok := statuses[config.Path]
+		if !ok {
+			status = &models.SubmoduleStatus{}
+			statuses[config.Path] = status
+		}
+
+		if _, err := os.Stat(filepath.Join(config.Path, ".git")); os.IsNotExist(err) {
+			status.Uninitialized = true
+		}
+	}
+
+	return statuses, nil
+}
+
+// parses the output of `git status --porcelain=v2 -z`, returning the statuses of
+// any entries that are submodules. Entries look like this:
+// 1 <XY> <sub> <mH> <mI> <mW> <hH> <hI> <path>
+// 2 <XY> <sub> <mH> <mI> <mW> <hH> <hI> <X><score> <path>\x00<origPath>
+// u <XY> <sub> <m1> <m2> <m3> <mW> <h1> <h2> <h3> <path>
+// where <sub> is 'N...' for regular files or 'S<c><m><u>' for submodules
+func parseSubmoduleStatuses(output string) map[string]*models.SubmoduleStatus {
+	statuses := map[string]*models.SubmoduleStatus{}
+
+	fieldCounts := map[string]int{"1": 9, "2": 10, "u": 11}
+
+	entries := strings.Split(output, "\x00")
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		if entry == "" {
+			continue
+		}
+
+		kind, _, _ := strings.Cut(entry, " ")
+		if kind == "2" {
+			// the original path of the rename is in its own entry
+			i++
+		}
+
+		fieldCount, ok := fieldCounts[kind]
+		if !ok {
+			continue
+		}
+
+		fields := strings.SplitN(entry, " ", fieldCount)
+		if len(fields) < fieldCount {
+			continue
+		}
+
+		if status := parseSubmoduleStatus(fields[2]); status != nil {
+			statuses[fields[fieldCount-1]] = status
+		}
+	}
+
+	return statuses
+}
+
+// parses the <sub> field of a porcelain v2 status entry, returning nil if the
+// entry isn't a submodule
+func parseSubmoduleStatus(sub string) *models.SubmoduleStatus {
+	if len(sub) != 4 || sub[0] != 'S' {
+		return nil
+	}
+
+	return &models.SubmoduleStatus{
+		NewCommits:       sub[1] == 'C',
+		ModifiedContent:  sub[2] == 'M',
+		UntrackedContent: sub[3] == 'U',
+	}
+}
+
 func (self *SubmoduleCommands) Stash(submodule *models.SubmoduleConfig) error {
 	// if the path does not exist then it hasn't yet been initialized so we'll swallow the error
 	// because the intention here is to have no dirty worktree state
@@ -118,7 +227,14 @@ func (self *SubmoduleCommands) Delete(submodule *models.SubmoduleConfig) error {
 		self.Log.Error(err)
 	}
 
-	return os.RemoveAll(filepath.Join(self.dotGitDir, "modules", submodule.Path))
+	// a submodule's git directory lives in the main repo's git directory even
+	// if we're in a linked worktree
+	commonDir, err := self.CommonDir()
+	if err != nil {
+		return err
+	}
+
+	return os.RemoveAll(filepath.Join(commonDir, "modules", submodule.Path))
 }
 
 func (self *SubmoduleCommands) Add(name string, path string, url string) error {
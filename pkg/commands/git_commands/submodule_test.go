@@ -0,0 +1,76 @@
+package git_commands
+
+import (
+	"testing"
+
+	"github.com/go-errors/errors"
+	"github.com/jesseduffield/lazygit/pkg/commands/models"
+	"github.com/jesseduffield/lazygit/pkg/commands/oscommands"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSubmoduleStatuses(t *testing.T) {
+	output := "1 .M N... 100644 100644 100644 3b18e51 3b18e51 file.txt\x00" +
+		"1 .M SC.. 160000 160000 160000 1a2b3c4 1a2b3c4 moved\x00" +
+		"1 .M S.M. 160000 160000 160000 1a2b3c4 1a2b3c4 dirty\x00" +
+		"1 .M SCMU 160000 160000 160000 1a2b3c4 1a2b3c4 with space/both\x00" +
+		"2 R. S... 160000 160000 160000 1a2b3c4 1a2b3c4 R100 renamed\x00original\x00" +
+		"? untracked.txt\x00"
+
+	assert.EqualValues(t, map[string]*models.SubmoduleStatus{
+		"moved":           {NewCommits: true},
+		"dirty":           {ModifiedContent: true},
+		"with space/both": {NewCommits: true, ModifiedContent: true, UntrackedContent: true},
+		"renamed":         {},
+	}, parseSubmoduleStatuses(output))
+}
+
+func TestSubmoduleIsInsideSubmodule(t *testing.T) {
+	type scenario struct {
+		testName           string
+		runner             *oscommands.FakeCmdObjRunner
+		expectedInside     bool
+		expectedParentPath string
+		expectedError      string
+	}
+
+	scenarios := []scenario{
+		{
+			testName: "not a submodule",
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git rev-parse --show-superproject-working-tree`, "", nil),
+			expectedInside:     false,
+			expectedParentPath: "",
+		},
+		{
+			testName: "submodule",
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git rev-parse --show-superproject-working-tree`, "/home/user/parent\n", nil),
+			expectedInside:     true,
+			expectedParentPath: "/home/user/parent",
+		},
+		{
+			testName: "error",
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git rev-parse --show-superproject-working-tree`, "", errors.New("error")),
+			expectedError: "error",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			instance := buildSubmoduleCommands(commonDeps{runner: s.runner})
+
+			inside, parentPath, err := instance.IsInsideSubmodule()
+			if s.expectedError != "" {
+				assert.EqualError(t, err, s.expectedError)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, s.expectedInside, inside)
+				assert.Equal(t, s.expectedParentPath, parentPath)
+			}
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
@@ -36,6 +36,11 @@ func (self *SyncCommands) PushCmdObj(opts PushOpts) (oscommands.ICmdObj, error)
 		cmdStr += " --set-upstream"
 	}
 
+	// skips the pre-push hook
+	if self.BypassHooks() {
+		cmdStr += " --no-verify"
+	}
+
 	if opts.UpstreamRemote != "" {
 		cmdStr += " " + self.cmd.Quote(opts.UpstreamRemote)
 	}
@@ -47,7 +52,7 @@ func (self *SyncCommands) PushCmdObj(opts PushOpts) (oscommands.ICmdObj, error)
 		cmdStr += " " + self.cmd.Quote(opts.UpstreamBranch)
 	}
 
-	cmdObj := self.cmd.New(cmdStr).PromptOnCredentialRequest().WithMutex(self.syncMutex)
+	cmdObj := self.bypassHooksIfSet(self.cmd.New(cmdStr)).PromptOnCredentialRequest().WithMutex(self.syncMutex)
 	return cmdObj, nil
 }
 
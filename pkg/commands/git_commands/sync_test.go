@@ -92,3 +92,14 @@ func TestSyncPush(t *testing.T) {
 		})
 	}
 }
+
+func TestSyncPushBypassingHooks(t *testing.T) {
+	instance := buildSyncCommands(commonDeps{})
+	instance.SetBypassHooks(true)
+
+	cmdObj, err := instance.PushCmdObj(PushOpts{Force: true})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "git push --force-with-lease --no-verify", cmdObj.ToString())
+	assert.Subset(t, cmdObj.GetEnvVars(), []string{"HUSKY=0", "LEFTHOOK=0"})
+}
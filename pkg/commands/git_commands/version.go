@@ -2,6 +2,7 @@ package git_commands
 
 import (
 	"errors"
+	"os/exec"
 	"regexp"
 	"strconv"
 	"strings"
@@ -9,6 +10,8 @@ import (
 	"github.com/jesseduffield/lazygit/pkg/commands/oscommands"
 )
 
+var ErrGitNotFound = errors.New("git executable not found in PATH")
+
 type GitVersion struct {
 	Major, Minor, Patch int
 	Additional          string
@@ -17,6 +20,9 @@ type GitVersion struct {
 func GetGitVersion(osCommand *oscommands.OSCommand) (*GitVersion, error) {
 	versionStr, _, err := osCommand.Cmd.New("git --version").RunWithOutputs()
 	if err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return nil, ErrGitNotFound
+		}
 		return nil, err
 	}
 
@@ -66,6 +72,40 @@ func (v *GitVersion) IsOlderThan(major, minor, patch int) bool {
 	return actual < required
 }
 
+// MeetsMinimum returns true if the version is at least major.minor
+func (v *GitVersion) MeetsMinimum(major, minor int) bool {
+	return !v.IsOlderThan(major, minor, 0)
+}
+
 func (v *GitVersion) IsOlderThanVersion(version *GitVersion) bool {
 	return v.IsOlderThan(version.Major, version.Minor, version.Patch)
 }
+
+// Features records which version-dependent git capabilities are available, so
+// that commands can pick between a modern invocation and a fallback without
+// each doing their own version check
+type Features struct {
+	// `git rebase --empty=keep`, since 2.26
+	SupportsRebaseEmptyKeep bool
+	// `git rebase --rebase-merges`, since 2.22
+	SupportsRebaseMerges bool
+	// `git switch`, since 2.23
+	SupportsSwitch bool
+	// `git restore`, since 2.23
+	SupportsRestore bool
+	// `git stash push --staged`, since 2.35
+	SupportsStashStaged bool
+	// `git merge-tree --write-tree`, since 2.38
+	SupportsMergeTreeWriteTree bool
+}
+
+func NewFeatures(version *GitVersion) *Features {
+	return &Features{
+		SupportsRebaseEmptyKeep:    version.MeetsMinimum(2, 26),
+		SupportsRebaseMerges:       version.MeetsMinimum(2, 22),
+		SupportsSwitch:             version.MeetsMinimum(2, 23),
+		SupportsRestore:            version.MeetsMinimum(2, 23),
+		SupportsStashStaged:        version.MeetsMinimum(2, 35),
+		SupportsMergeTreeWriteTree: version.MeetsMinimum(2, 38),
+	}
+}
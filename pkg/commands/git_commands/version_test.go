@@ -23,6 +23,14 @@ func TestParseGitVersion(t *testing.T) {
 			input:    "git version 2.37 (Apple Git-137.1)",
 			expected: GitVersion{Major: 2, Minor: 37, Patch: 0, Additional: "(Apple Git-137.1)"},
 		},
+		{
+			input:    "git version 2.41.0.windows.1",
+			expected: GitVersion{Major: 2, Minor: 41, Patch: 0, Additional: ".windows.1"},
+		},
+		{
+			input:    "git version 2.39.2.vfs.0.0\r\n",
+			expected: GitVersion{Major: 2, Minor: 39, Patch: 2, Additional: ".vfs.0.0"},
+		},
 	}
 
 	for _, s := range scenarios {
@@ -45,3 +53,32 @@ func TestGitVersionIsOlderThan(t *testing.T) {
 	assert.True(t, (&GitVersion{2, 0, 1, ""}).IsOlderThan(2, 1, 0))
 	assert.True(t, (&GitVersion{2, 0, 1, ""}).IsOlderThan(3, 0, 0))
 }
+
+func TestGitVersionMeetsMinimum(t *testing.T) {
+	assert.True(t, (&GitVersion{2, 20, 0, ""}).MeetsMinimum(2, 20))
+	assert.True(t, (&GitVersion{2, 20, 1, ""}).MeetsMinimum(2, 20))
+	assert.True(t, (&GitVersion{3, 0, 0, ""}).MeetsMinimum(2, 38))
+
+	assert.False(t, (&GitVersion{2, 19, 9, ""}).MeetsMinimum(2, 20))
+	assert.False(t, (&GitVersion{1, 99, 0, ""}).MeetsMinimum(2, 0))
+}
+
+func TestNewFeatures(t *testing.T) {
+	assert.Equal(t, &Features{}, NewFeatures(&GitVersion{2, 20, 0, ""}))
+
+	assert.Equal(t, &Features{
+		SupportsRebaseEmptyKeep: true,
+		SupportsRebaseMerges:    true,
+		SupportsSwitch:          true,
+		SupportsRestore:         true,
+	}, NewFeatures(&GitVersion{2, 34, 1, ""}))
+
+	assert.Equal(t, &Features{
+		SupportsRebaseEmptyKeep:    true,
+		SupportsRebaseMerges:       true,
+		SupportsSwitch:             true,
+		SupportsRestore:            true,
+		SupportsStashStaged:        true,
+		SupportsMergeTreeWriteTree: true,
+	}, NewFeatures(&GitVersion{2, 39, 0, ""}))
+}
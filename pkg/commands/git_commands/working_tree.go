@@ -1,9 +1,16 @@
 package git_commands
 
 import (
+	"bufio"
+	"bytes"
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -12,6 +19,7 @@ import (
 	"github.com/jesseduffield/lazygit/pkg/commands/models"
 	"github.com/jesseduffield/lazygit/pkg/commands/oscommands"
 	"github.com/jesseduffield/lazygit/pkg/utils"
+	"github.com/samber/lo"
 )
 
 type WorkingTreeCommands struct {
@@ -36,10 +44,101 @@ func (self *WorkingTreeCommands) OpenMergeToolCmdObj() oscommands.ICmdObj {
 	return self.cmd.New("git mergetool")
 }
 
+// OpenMergeToolWithCmdObj returns the command for resolving a single file's
+// conflicts with the given merge tool rather than the configured merge.tool. If
+// git doesn't know the tool, it will say so when the command is run.
+func (self *WorkingTreeCommands) OpenMergeToolWithCmdObj(toolName string, fileName string) oscommands.ICmdObj {
+	return self.cmd.New(
+		fmt.Sprintf("git mergetool --tool=%s -- %s", self.cmd.Quote(toolName), self.cmd.Quote(fileName)),
+	)
+}
+
 func (self *WorkingTreeCommands) OpenMergeTool() error {
 	return self.OpenMergeToolCmdObj().Run()
 }
 
+// ExtractConflictVersions writes the common ancestor's version (the base), our
+// version and their version of a file with merge conflicts to temporary files,
+// e.g. to hand them to a merge tool, and returns their paths. A version that
+// doesn't exist, like the base when both sides added the file, gives an empty
+// path. The files are put in a new directory of their own, which the caller
+// must remove once they're done with them, i.e. filepath.Dir of any of the
+// paths.
+func (self *WorkingTreeCommands) ExtractConflictVersions(fileName string) (base string, ours string, theirs string, err error) {
+	output, err := self.cmd.New("git ls-files --unmerged -z -- " + self.cmd.Quote(fileName)).DontLog().RunWithOutput()
+	if err != nil {
+		return "", "", "", err
+	}
+
+	entries := parseUnmergedEntries(output)
+	if len(entries) == 0 {
+		return "", "", "", errors.Errorf("'%s' has no merge conflicts", fileName)
+	}
+
+	if err := os.MkdirAll(self.os.GetTempDir(), 0o755); err != nil {
+		return "", "", "", err
+	}
+	dir, err := os.MkdirTemp(self.os.GetTempDir(), "conflict-versions-")
+	if err != nil {
+		return "", "", "", err
+	}
+
+	// keeping the extension so that tools can tell what kind of file it is
+	ext := filepath.Ext(fileName)
+	stem := strings.TrimSuffix(filepath.Base(fileName), ext)
+
+	paths := [3]string{}
+	for i, label := range []string{"BASE", "OURS", "THEIRS"} {
+		if !entries[0].stages[i] {
+			continue
+		}
+
+		path, err := self.extractIndexStage(fileName, i+1, filepath.Join(dir, stem+"_"+label+ext))
+		if err != nil {
+			_ = os.RemoveAll(dir)
+			return "", "", "", err
+		}
+		paths[i] = path
+	}
+
+	return paths[0], paths[1], paths[2], nil
+}
+
+func (self *WorkingTreeCommands) extractIndexStage(fileName string, stage int, path string) (string, error) {
+	object := fmt.Sprintf(":%d:%s", stage, fileName)
+	content, _, err := self.cmd.New("git cat-file blob " + self.cmd.Quote(object)).DontLog().RunWithOutputs()
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
+
+type IsWorkingTreeCleanOpts struct {
+	// if true, untracked files don't count as changes
+	IgnoreUntracked bool
+}
+
+// IsWorkingTreeClean returns true if there are no staged or unstaged changes.
+// Submodules with a moved pointer or with changes of their own count as changes.
+func (self *WorkingTreeCommands) IsWorkingTreeClean(opts IsWorkingTreeCleanOpts) (bool, error) {
+	untrackedFilesArg := "--untracked-files=normal"
+	if opts.IgnoreUntracked {
+		untrackedFilesArg = "--untracked-files=no"
+	}
+
+	output, err := self.cmd.New("git status --porcelain --ignore-submodules=none " + untrackedFilesArg).DontLog().RunWithOutput()
+	if err != nil {
+		return false, err
+	}
+
+	return strings.TrimSpace(output) == "", nil
+}
+
 // StageFile stages a file
 func (self *WorkingTreeCommands) StageFile(path string) error {
 	return self.StageFiles([]string{path})
@@ -52,195 +151,1532 @@ func (self *WorkingTreeCommands) StageFiles(paths []string) error {
 	return self.cmd.New(fmt.Sprintf("git add -- %s", strings.Join(quotedPaths, " "))).Run()
 }
 
+// StageFileVerified stages a file and then checks the status of that one path
+// to confirm git really did stage it. This catches the rare case (usually on
+// network filesystems) where 'git add' succeeds but the index doesn't change.
+func (self *WorkingTreeCommands) StageFileVerified(fileName string) error {
+	if err := self.StageFile(fileName); err != nil {
+		return err
+	}
+
+	output, err := self.cmd.New(
+		"git status --porcelain -z --untracked-files=all -- " + self.cmd.Quote(fileName),
+	).DontLog().RunWithOutput()
+	if err != nil {
+		return err
+	}
+
+	entries := strings.Split(output, "\x00")
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		if len(entry) < 3 {
+			continue
+		}
+
+		// entries look like 'XY path'; Y is the status of the working tree
+		// relative to the index, which should be clean now
+		if entry[1] != ' ' {
+			return errors.Errorf("'%s' still has unstaged changes after staging it", fileName)
+		}
+
+		// renames and copies are followed by an entry holding the original path
+		if entry[0] == 'R' || entry[0] == 'C' {
+			i++
+		}
+	}
+
+	return nil
+}
+
+// WillNormalizeLineEndings predicts whether staging the file would convert its
+// CRLF line endings to LF, meaning what's staged would differ from what's on
+// disk. Git does this for text files, which are those that .gitattributes marks
+// as text (or gives an eol to) or, failing that, any file when core.autocrlf is
+// 'true' or 'input'. A file marked -text is never converted. When git is left
+// to decide whether the file is text (text=auto or core.autocrlf), it leaves
+// alone files that look binary and files that already have CRLFs in the index.
+func (self *WorkingTreeCommands) WillNormalizeLineEndings(fileName string) (bool, error) {
+	content, err := os.ReadFile(fileName)
+	if err != nil {
+		return false, err
+	}
+
+	if !bytes.Contains(content, []byte("\r\n")) {
+		return false, nil
+	}
+
+	output, err := self.cmd.New("git check-attr -z text eol -- " + self.cmd.Quote(fileName)).DontLog().RunWithOutput()
+	if err != nil {
+		return false, err
+	}
+
+	// output looks like <path>\x00text\x00<value>\x00<path>\x00eol\x00<value>\x00
+	fields := strings.Split(output, "\x00")
+	if len(fields) < 6 {
+		return false, fmt.Errorf("unexpected output from git check-attr: %s", output)
+	}
+	text, eol := fields[2], fields[5]
+
+	switch text {
+	case "set":
+		return true, nil
+	case "unset":
+		return false, nil
+	case "auto":
+		return self.willAutoNormalize(fileName, content)
+	}
+
+	// setting eol implies the file is text
+	if eol != "unspecified" {
+		return true, nil
+	}
+
+	switch self.config.GetCoreAutoCRLF() {
+	case "true", "input":
+		return self.willAutoNormalize(fileName, content)
+	default:
+		return false, nil
+	}
+}
+
+func (self *WorkingTreeCommands) willAutoNormalize(fileName string, content []byte) (bool, error) {
+	isBinary, err := looksBinary(bytes.NewReader(content))
+	if err != nil || isBinary {
+		return false, err
+	}
+
+	// an error means the file isn't in the index, e.g. because it's untracked
+	indexContent, err := self.cmd.New("git cat-file blob " + self.cmd.Quote(":"+fileName)).DontLog().RunWithOutput()
+	if err != nil {
+		return true, nil
+	}
+
+	return !strings.Contains(indexContent, "\r"), nil
+}
+
+// StageAllExcept stages all changes, including untracked files, apart from
+// those in paths matching the given patterns (which use .gitignore syntax).
+// Matching files are never staged in the first place, so an untracked file
+// that matches stays untracked.
+func (self *WorkingTreeCommands) StageAllExcept(patterns []string) error {
+	isExcluded := gitignoreMatcher(patterns)
+
+	// without renames, each file has a single path that we can match against
+	files, err := self.fileLoader.LoadStatusFiles(GetStatusFileOptions{NoRenames: true})
+	if err != nil {
+		return err
+	}
+
+	pathsToStage := []string{}
+	for _, file := range files {
+		if file.HasUnstagedChanges && !isExcluded(file.Name) {
+			pathsToStage = append(pathsToStage, file.Name)
+		}
+	}
+
+	if len(pathsToStage) == 0 {
+		return nil
+	}
+
+	return self.StageFiles(pathsToStage)
+}
+
+type FileChangeType int
+
+const (
+	// files which are new in the working tree: untracked files, and files
+	// added with intent-to-add (`git add -N`)
+	FILE_CHANGE_ADDED FileChangeType = iota
+	FILE_CHANGE_MODIFIED
+	FILE_CHANGE_DELETED
+	// git only detects a rename in the working tree when the new path was
+	// added with intent-to-add. Otherwise the file shows up as the deletion of
+	// the old path and an untracked new path, which count as FILE_CHANGE_DELETED
+	// and FILE_CHANGE_ADDED respectively.
+	FILE_CHANGE_RENAMED
+)
+
+// StageByChangeType stages the unstaged changes of the given type, e.g. all
+// deletions, in one go. Staging a rename stages both its old and new path.
+// Files with merge conflicts are left alone.
+func (self *WorkingTreeCommands) StageByChangeType(changeType FileChangeType) error {
+	files, err := self.fileLoader.LoadStatusFiles(GetStatusFileOptions{})
+	if err != nil {
+		return err
+	}
+
+	pathsToStage := []string{}
+	for _, file := range files {
+		if !file.HasUnstagedChanges || file.HasMergeConflicts {
+			continue
+		}
+
+		fileChangeType, ok := unstagedChangeType(file)
+		if !ok || fileChangeType != changeType {
+			continue
+		}
+
+		if fileChangeType == FILE_CHANGE_RENAMED {
+			pathsToStage = append(pathsToStage, file.Names()...)
+		} else {
+			// for a staged rename with unstaged changes, the old path is
+			// already gone from the index so we only stage the new one
+			pathsToStage = append(pathsToStage, file.Name)
+		}
+	}
+
+	if len(pathsToStage) == 0 {
+		return nil
+	}
+
+	return self.StageFiles(pathsToStage)
+}
+
+// classifies a file's unstaged change by the working tree column of its status
+func unstagedChangeType(file *models.File) (FileChangeType, bool) {
+	switch file.ShortStatus[1] {
+	case '?', 'A':
+		return FILE_CHANGE_ADDED, true
+	case 'M', 'T':
+		return FILE_CHANGE_MODIFIED, true
+	case 'D':
+		return FILE_CHANGE_DELETED, true
+	case 'R':
+		return FILE_CHANGE_RENAMED, true
+	default:
+		return 0, false
+	}
+}
+
 // StageAll stages all files
 func (self *WorkingTreeCommands) StageAll() error {
 	return self.cmd.New("git add -A").Run()
 }
 
-// UnstageAll unstages all files
-func (self *WorkingTreeCommands) UnstageAll() error {
-	return self.cmd.New("git reset").Run()
+// PreviewStageAll returns the files that StageAll would stage, without staging
+// anything. That's every file with unstaged changes, including untracked files
+// whatever the status.showUntrackedFiles config says, but not ignored files.
+// Files with merge conflicts are included, as staging them marks them as
+// resolved. A renamed file comes out as its old path and its new path
+// separately. Submodules only count if their commit has changed, as changes
+// inside a submodule can't be staged from here.
+func (self *WorkingTreeCommands) PreviewStageAll() ([]*models.File, error) {
+	statuses, err := self.fileLoader.GitStatus(GitStatusOptions{
+		NoRenames:         true,
+		UntrackedFilesArg: "--untracked-files=all",
+		// for the state of submodules
+		PorcelainV2: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	files := self.fileLoader.filesFromStatuses(statuses)
+
+	return lo.Filter(files, func(file *models.File, _ int) bool {
+		if file.SubmoduleStatus != nil && !file.SubmoduleStatus.NewCommits {
+			return false
+		}
+
+		return file.HasUnstagedChanges || file.HasMergeConflicts
+	}), nil
+}
+
+// Renormalize stages tracked files under the pathspec (or in the whole repo if
+// it's empty) in the form git would store them if they were added now, e.g.
+// after .gitattributes has been changed to normalize line endings. Only files
+// whose normalized form differs from the index end up with staged changes.
+// Files that git sees as binary are left alone, unless .gitattributes says
+// explicitly that they're text.
+func (self *WorkingTreeCommands) Renormalize(pathspec string) error {
+	quotedPathspec := "."
+	if pathspec != "" {
+		quotedPathspec = self.cmd.Quote(pathspec)
+	}
+
+	return self.cmd.New("git add --renormalize -- " + quotedPathspec).Run()
+}
+
+// StageAllTracked stages all changes to files git already knows about, leaving
+// untracked files alone. Unlike StageAll, new files aren't added, but like
+// StageAll, deleted files are: their deletion is a change to a tracked file.
+func (self *WorkingTreeCommands) StageAllTracked() error {
+	return self.cmd.New("git add -u").Run()
+}
+
+// UnstageAll unstages all files
+func (self *WorkingTreeCommands) UnstageAll() error {
+	return self.cmd.New("git reset").Run()
+}
+
+// UnStageFile unstages a file
+// we accept an array of filenames for the cases where a file has been renamed i.e.
+// we accept the current name and the previous name
+func (self *WorkingTreeCommands) UnStageFile(fileNames []string, reset bool) error {
+	command := "git rm --cached --force -- %s"
+	if reset {
+		command = "git reset HEAD -- %s"
+	}
+
+	for _, name := range fileNames {
+		err := self.cmd.New(fmt.Sprintf(command, self.cmd.Quote(name))).Run()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ToggleStaged stages the file if it has any unstaged changes, including when
+// it's partially staged, and otherwise unstages it. Unstaging a rename resets
+// both its old and new paths so that the rename is undone as a whole.
+func (self *WorkingTreeCommands) ToggleStaged(file *models.File) error {
+	if file.HasUnstagedChanges {
+		return self.StageFile(file.Name)
+	}
+
+	return self.UnStageFile(file.Names(), file.Tracked)
+}
+
+type CommitOpts struct {
+	Amend    bool
+	NoVerify bool
+	SignOff  bool
+	// open the editor with the staged diff below the message, for reference
+	// while writing it. The message, if given, is the editor's starting point.
+	Verbose bool
+	// commit only the changes to these paths, leaving anything else that's
+	// staged in the index
+	Paths []string
+}
+
+// Commit returns a command for committing whatever is staged. We don't run it
+// ourselves because if git is configured to sign commits, gpg may need to ask
+// for a passphrase, meaning the caller will need to run it in a subprocess.
+// Passing an empty message is only allowed when amending, in which case the
+// existing message is kept.
+func (self *WorkingTreeCommands) Commit(message string, opts CommitOpts) (oscommands.ICmdObj, error) {
+	if message == "" && !opts.Amend && !opts.Verbose {
+		return nil, errors.New("commit message must not be empty")
+	}
+
+	flags := ""
+	if opts.Amend {
+		flags += " --amend"
+	}
+	if opts.NoVerify || self.BypassHooks() {
+		flags += " --no-verify"
+	}
+	if opts.SignOff {
+		flags += " --signoff"
+	}
+	if opts.Verbose {
+		// git cuts the diff off at the scissors line when the editor closes, so
+		// it never makes it into the message
+		flags += " --verbose --edit"
+	}
+	// git would sign the commit anyway given the config, but we're explicit
+	// about it so that it's visible in the command log
+	if self.config.GpgSigningEnabled() {
+		flags += " -S"
+	}
+
+	messageArgs := ""
+	if message != "" {
+		messageArgs = commitMessageArgs(self.cmd, message)
+	} else if !opts.Verbose {
+		messageArgs = " --no-edit"
+	}
+
+	pathsArgs := ""
+	if len(opts.Paths) > 0 {
+		pathsArgs = " -- " + strings.Join(slices.Map(opts.Paths, self.cmd.Quote), " ")
+	}
+
+	return self.bypassHooksIfSet(self.cmd.New("git commit" + flags + messageArgs + pathsArgs)), nil
+}
+
+// StageAndCommitFile stages a single file and returns the command for
+// committing it by itself. The commit is limited to the file's path, so other
+// staged changes aren't swept into it. As with Commit, the caller runs the
+// command.
+func (self *WorkingTreeCommands) StageAndCommitFile(fileName string, message string) (oscommands.ICmdObj, error) {
+	if message == "" {
+		return nil, errors.New("commit message must not be empty")
+	}
+
+	if err := self.StageFile(fileName); err != nil {
+		return nil, err
+	}
+
+	return self.Commit(message, CommitOpts{Paths: []string{fileName}})
+}
+
+// AmendWithAllChanges stages all modifications to tracked files and returns the
+// command for amending them into the HEAD commit, keeping its message
+func (self *WorkingTreeCommands) AmendWithAllChanges() (oscommands.ICmdObj, error) {
+	if err := self.cmd.New("git rev-parse --verify --quiet HEAD").DontLog().Run(); err != nil {
+		return nil, errors.New("there is no commit to amend")
+	}
+
+	if err := self.StageAllTracked(); err != nil {
+		return nil, err
+	}
+
+	return self.Commit("", CommitOpts{Amend: true})
+}
+
+// SuggestCommitMessage comes up with a commit message summarising the staged
+// changes, e.g. "Update pkg/commands: +40/-5 across 3 files", for the user to
+// edit before committing
+func (self *WorkingTreeCommands) SuggestCommitMessage() (string, error) {
+	stats, err := self.fileLoader.DiffStats(true)
+	if err != nil {
+		return "", err
+	}
+
+	if len(stats) == 0 {
+		return "", errors.New("there are no staged changes")
+	}
+
+	return suggestCommitMessage(stats), nil
+}
+
+func suggestCommitMessage(stats map[string]DiffStat) string {
+	paths := make([]string, 0, len(stats))
+	linesAdded, linesDeleted := 0, 0
+	for path, stat := range stats {
+		paths = append(paths, path)
+		linesAdded += stat.LinesAdded
+		linesDeleted += stat.LinesDeleted
+	}
+	sort.Strings(paths)
+
+	verb := "Update"
+	if linesDeleted == 0 && linesAdded > 0 {
+		verb = "Add to"
+	} else if linesAdded == 0 && linesDeleted > 0 {
+		verb = "Remove from"
+	}
+
+	lineCounts := fmt.Sprintf("+%d/-%d", linesAdded, linesDeleted)
+
+	if len(paths) == 1 {
+		return fmt.Sprintf("%s %s: %s", verb, paths[0], lineCounts)
+	}
+
+	return fmt.Sprintf("%s %s: %s across %d files", verb, describePaths(paths), lineCounts, len(paths))
+}
+
+// returns the deepest directory containing all of the given (sorted) paths or,
+// if they have nothing in common, a list of their top-level entries
+func describePaths(paths []string) string {
+	commonDir := filepath.Dir(paths[0])
+	for _, path := range paths[1:] {
+		for commonDir != "." && !strings.HasPrefix(path, commonDir+"/") {
+			commonDir = filepath.Dir(commonDir)
+		}
+	}
+	if commonDir != "." {
+		return commonDir
+	}
+
+	topLevelEntries := lo.Uniq(slices.Map(paths, func(path string) string {
+		return strings.Split(path, "/")[0]
+	}))
+	if len(topLevelEntries) > 3 {
+		return fmt.Sprintf("%d top-level paths", len(topLevelEntries))
+	}
+
+	return strings.Join(topLevelEntries, ", ")
+}
+
+func (self *WorkingTreeCommands) BeforeAndAfterFileForRename(file *models.File) (*models.File, *models.File, error) {
+	return self.renameSplitter()(file)
+}
+
+// returns a function which does what BeforeAndAfterFileForRename does, but
+// which loads the status without renames at most once, however many renames
+// it's called for
+func (self *WorkingTreeCommands) renameSplitter() func(file *models.File) (*models.File, *models.File, error) {
+	var filesWithoutRenames []*models.File
+	loaded := false
+
+	return func(file *models.File) (*models.File, *models.File, error) {
+		if !file.IsRename() {
+			return nil, nil, errors.New("Expected renamed file")
+		}
+
+		if beforeFile, afterFile, ok := beforeAndAfterFileFromRenameStatus(file); ok {
+			return beforeFile, afterFile, nil
+		}
+
+		// we've got a file that represents a rename from one file to another. Here
+		// we refetch all files, passing the --no-renames flag, and find the before
+		// file and after file among them.
+		if !loaded {
+			filesWithoutRenames = self.fileLoader.GetStatusFiles(GetStatusFileOptions{NoRenames: true})
+			loaded = true
+		}
+
+		return findBeforeAndAfterFile(file, filesWithoutRenames)
+	}
+}
+
+func findBeforeAndAfterFile(file *models.File, filesWithoutRenames []*models.File) (*models.File, *models.File, error) {
+	var beforeFile *models.File
+	var afterFile *models.File
+	for _, f := range filesWithoutRenames {
+		if f.Name == file.PreviousName {
+			beforeFile = f
+		}
+
+		if f.Name == file.Name {
+			afterFile = f
+		}
+	}
+
+	if beforeFile == nil || afterFile == nil {
+		return nil, nil, errors.New("Could not find deleted file or new file for file rename")
+	}
+
+	if beforeFile.IsRename() || afterFile.IsRename() {
+		// probably won't happen but we want to ensure we don't get an infinite loop
+		return nil, nil, errors.New("Nested rename found")
+	}
+
+	return beforeFile, afterFile, nil
+}
+
+// For a rename that's staged in the index we know without asking git again
+// that, ignoring renames, the old path is a staged deletion and the new path is
+// a staged addition, possibly with unstaged changes on top. Returns false for
+// other kinds of rename (e.g. one that's only in the working tree).
+func beforeAndAfterFileFromRenameStatus(file *models.File) (*models.File, *models.File, bool) {
+	if len(file.ShortStatus) != 2 || file.ShortStatus[0] != 'R' {
+		return nil, nil, false
+	}
+
+	unstagedChange := file.ShortStatus[1:]
+	if !slices.Contains([]string{" ", "M", "D"}, unstagedChange) {
+		return nil, nil, false
+	}
+
+	beforeFile := &models.File{Name: file.PreviousName, DisplayString: "D  " + file.PreviousName, Type: file.Type}
+	models.SetStatusFields(beforeFile, "D ")
+
+	afterFile := &models.File{Name: file.Name, DisplayString: "A" + unstagedChange + " " + file.Name, Type: file.Type}
+	models.SetStatusFields(afterFile, "A"+unstagedChange)
+
+	return beforeFile, afterFile, true
+}
+
+// UnsupportedConflictTypeError is returned for conflicted files whose merge
+// driver doesn't write the usual conflict markers, meaning we can't resolve
+// them inline
+type UnsupportedConflictTypeError struct {
+	Path   string
+	Driver string
+}
+
+func (self *UnsupportedConflictTypeError) Error() string {
+	return fmt.Sprintf("'%s' uses the '%s' merge driver so its conflicts can't be resolved inline", self.Path, self.Driver)
+}
+
+// CheckMergeDriver consults .gitattributes to find out whether the file is
+// merged with git's regular text driver. If it isn't, an
+// *UnsupportedConflictTypeError is returned.
+func (self *WorkingTreeCommands) CheckMergeDriver(path string) error {
+	output, err := self.cmd.New("git check-attr -z merge -- " + self.cmd.Quote(path)).DontLog().RunWithOutput()
+	if err != nil {
+		return err
+	}
+
+	// output looks like <path>\x00merge\x00<value>\x00
+	fields := strings.Split(output, "\x00")
+	if len(fields) < 3 {
+		return fmt.Errorf("unexpected output from git check-attr: %s", output)
+	}
+
+	driver := fields[2]
+	switch driver {
+	case "unspecified", "set", "text":
+		return nil
+	case "unset":
+		// i.e. '-merge' which is how git spells 'binary'
+		driver = "binary"
+	}
+
+	return &UnsupportedConflictTypeError{Path: path, Driver: driver}
+}
+
+type ConflictMarkersRemainError struct {
+	Path        string
+	LineNumbers []int
+}
+
+func (self *ConflictMarkersRemainError) Error() string {
+	lineNumbers := slices.Map(self.LineNumbers, strconv.Itoa)
+	return fmt.Sprintf("'%s' still has conflict markers on lines %s", self.Path, strings.Join(lineNumbers, ", "))
+}
+
+// MarkConflictResolved stages a conflicted file once the user has resolved it
+// themselves, e.g. in their editor. If any conflict markers are left in the
+// file, nothing is staged and a *ConflictMarkersRemainError is returned.
+func (self *WorkingTreeCommands) MarkConflictResolved(fileName string) error {
+	lineNumbers, err := conflictMarkerLineNumbers(fileName)
+	if err != nil {
+		return err
+	}
+
+	if len(lineNumbers) > 0 {
+		return &ConflictMarkersRemainError{Path: fileName, LineNumbers: lineNumbers}
+	}
+
+	return self.StageFile(fileName)
+}
+
+// returns the 1-based line numbers of any conflict markers in the file,
+// including the '|||||||' marker that introduces the common ancestor in diff3
+// style. A line of '=======' only counts inside a conflict, because it's also
+// how some markup languages underline headings.
+func conflictMarkerLineNumbers(fileName string) ([]int, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	isMarker := func(line string, marker string) bool {
+		return line == marker || strings.HasPrefix(line, marker+" ")
+	}
+
+	lineNumbers := []int{}
+	inConflict := false
+
+	// unlike bufio.Scanner, a bufio.Reader has no limit on the length of a
+	// line, and generated files can have very long ones
+	reader := bufio.NewReader(file)
+	for lineNumber := 1; ; lineNumber++ {
+		line, err := reader.ReadString('\n')
+		line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+
+		switch {
+		case isMarker(line, "<<<<<<<"):
+			inConflict = true
+			lineNumbers = append(lineNumbers, lineNumber)
+		case isMarker(line, "|||||||"), line == "=======" && inConflict:
+			lineNumbers = append(lineNumbers, lineNumber)
+		case isMarker(line, ">>>>>>>"):
+			inConflict = false
+			lineNumbers = append(lineNumbers, lineNumber)
+		}
+
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return lineNumbers, nil
+}
+
+// DiscardAllFileChanges directly
+func (self *WorkingTreeCommands) DiscardAllFileChanges(file *models.File) error {
+	if file.IsRename() {
+		beforeFile, afterFile, err := self.BeforeAndAfterFileForRename(file)
+		if err != nil {
+			self.Log.Warningf("falling back to discarding rename of '%s' path by path: %v", file.Name, err)
+			return self.discardRenameFromHead(file)
+		}
+
+		if err := self.DiscardAllFileChanges(beforeFile); err != nil {
+			return err
+		}
+
+		if err := self.DiscardAllFileChanges(afterFile); err != nil {
+			return err
+		}
+
+		return nil
+	}
+
+	quotedFileName := self.cmd.Quote(file.Name)
+
+	if file.ShortStatus == "AA" {
+		if err := self.cmd.New("git checkout --ours --  " + quotedFileName).Run(); err != nil {
+			return err
+		}
+		if err := self.cmd.New("git add -- " + quotedFileName).Run(); err != nil {
+			return err
+		}
+		return nil
+	}
+
+	if file.ShortStatus == "DU" {
+		return self.cmd.New("git rm -- " + quotedFileName).Run()
+	}
+
+	// if the file isn't tracked, we assume you want to delete it
+	if file.HasStagedChanges || file.HasMergeConflicts {
+		if err := self.cmd.New("git reset -- " + quotedFileName).Run(); err != nil {
+			return err
+		}
+	}
+
+	if file.ShortStatus == "DD" || file.ShortStatus == "AU" {
+		return nil
+	}
+
+	if file.Added {
+		return self.removeAddedFile(file.Name)
+	}
+	return self.DiscardUnstagedFileChanges(file)
+}
+
+// discardRenameFromHead discards a rename without knowing what state its old
+// and new paths are in, for when BeforeAndAfterFileForRename can't tell us
+// (e.g. because the paths changed again between the two status loads). Each
+// path is made to match HEAD: restored from it if it's there, and otherwise
+// removed from the index and the working tree.
+func (self *WorkingTreeCommands) discardRenameFromHead(file *models.File) error {
+	for _, path := range []string{file.PreviousName, file.Name} {
+		if err := self.discardPathFromHead(path); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (self *WorkingTreeCommands) discardPathFromHead(path string) error {
+	quotedPath := self.cmd.Quote(path)
+
+	if self.objectExists("HEAD:" + path) {
+		return self.cmd.New("git checkout HEAD -- " + quotedPath).Run()
+	}
+
+	if err := self.cmd.New("git rm --cached --force --ignore-unmatch -- " + quotedPath).Run(); err != nil {
+		return err
+	}
+
+	if _, err := os.Lstat(path); os.IsNotExist(err) {
+		return nil
+	}
+
+	return self.removeAddedFile(path)
+}
+
+// removes a file that git has no copy of, so that the user can't get it back
+// unless we move it to the trash, which they can ask us to do via os.useTrash
+func (self *WorkingTreeCommands) removeAddedFile(path string) error {
+	if self.UserConfig.OS.UseTrash {
+		return self.os.TrashFile(path)
+	}
+
+	return self.os.RemoveFile(path)
+}
+
+// DiscardStagedChanges resets the index entry for the file back to HEAD,
+// leaving any changes in the working tree alone. Unlike UnStageFile, an added
+// file is not removed from disk; it simply becomes untracked again. For a
+// rename, both the old and new paths are reset.
+func (self *WorkingTreeCommands) DiscardStagedChanges(file *models.File) error {
+	quotedFileNames := slices.Map(file.Names(), func(name string) string {
+		return self.cmd.Quote(name)
+	})
+
+	return self.cmd.New("git reset -- " + strings.Join(quotedFileNames, " ")).Run()
+}
+
+type IFileNode interface {
+	ForEachFile(cb func(*models.File) error) error
+	GetFilePathsMatching(test func(*models.File) bool) []string
+	GetPath() string
+}
+
+// DiscardAllDirChanges discards all changes to the files under the given node,
+// in the same way DiscardAllFileChanges does for a single file. Rather than
+// running commands per file, the index entries are reset in one go and the
+// working tree files are restored in one go; only merge conflicts that need
+// resolving first are handled file by file.
+func (self *WorkingTreeCommands) DiscardAllDirChanges(node IFileNode) error {
+	splitRename := self.renameSplitter()
+
+	files := []*models.File{}
+	err := node.ForEachFile(func(file *models.File) error {
+		if !file.IsRename() {
+			files = append(files, file)
+			return nil
+		}
+
+		beforeFile, afterFile, err := splitRename(file)
+		if err != nil {
+			self.Log.Warningf("falling back to discarding rename of '%s' path by path: %v", file.Name, err)
+			return self.discardRenameFromHead(file)
+		}
+		files = append(files, beforeFile, afterFile)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	plan := planDiscard(files)
+
+	for _, file := range plan.conflicted {
+		if err := self.DiscardAllFileChanges(file); err != nil {
+			return err
+		}
+	}
+
+	if len(plan.toReset) > 0 {
+		if err := self.resetFilesCmdObj(plan.toReset).Run(); err != nil {
+			return err
+		}
+	}
+
+	for _, file := range plan.toRemove {
+		if err := self.removeAddedFile(file.Name); err != nil {
+			return err
+		}
+	}
+
+	if len(plan.toRestore) > 0 {
+		if err := self.restoreFilesCmdObj(plan.toRestore).Run(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type DiscardFailure struct {
+	Path string
+	Err  error
+}
+
+type DiscardFilesError struct {
+	Failures []DiscardFailure
+}
+
+func (self *DiscardFilesError) Error() string {
+	failures := slices.Map(self.Failures, func(failure DiscardFailure) string {
+		return fmt.Sprintf("%s: %v", failure.Path, failure.Err)
+	})
+
+	return fmt.Sprintf("could not discard changes to %d file(s):\n%s", len(self.Failures), strings.Join(failures, "\n"))
+}
+
+// DiscardFiles discards all changes to the given files, in the same way
+// DiscardAllFileChanges does for a single file, but with one command for each
+// kind of change rather than one per file. If a file can't be discarded, we
+// carry on with the others and return a *DiscardFilesError listing the ones that
+// failed.
+func (self *WorkingTreeCommands) DiscardFiles(files []*models.File) error {
+	failures := []DiscardFailure{}
+	splitRename := self.renameSplitter()
+
+	expandedFiles := []*models.File{}
+	for _, file := range files {
+		if !file.IsRename() {
+			expandedFiles = append(expandedFiles, file)
+			continue
+		}
+
+		beforeFile, afterFile, err := splitRename(file)
+		if err != nil {
+			self.Log.Warningf("falling back to discarding rename of '%s' path by path: %v", file.Name, err)
+			if err := self.discardRenameFromHead(file); err != nil {
+				failures = append(failures, DiscardFailure{Path: file.Name, Err: err})
+			}
+			continue
+		}
+		expandedFiles = append(expandedFiles, beforeFile, afterFile)
+	}
+
+	plan := planDiscard(expandedFiles)
+
+	for _, file := range plan.conflicted {
+		if err := self.DiscardAllFileChanges(file); err != nil {
+			failures = append(failures, DiscardFailure{Path: file.Name, Err: err})
+		}
+	}
+
+	// if we couldn't reset a file's index entry, we leave its working tree alone
+	resetFailures := self.runBatched(plan.toReset, self.resetFilesCmdObj)
+	failures = append(failures, resetFailures...)
+	failedToReset := lo.SliceToMap(resetFailures, func(failure DiscardFailure) (string, bool) {
+		return failure.Path, true
+	})
+	notFailedToReset := func(file *models.File, _ int) bool {
+		return !failedToReset[file.Name]
+	}
+
+	for _, file := range lo.Filter(plan.toRemove, notFailedToReset) {
+		if err := self.removeAddedFile(file.Name); err != nil {
+			failures = append(failures, DiscardFailure{Path: file.Name, Err: err})
+		}
+	}
+
+	failures = append(failures, self.runBatched(lo.Filter(plan.toRestore, notFailedToReset), self.restoreFilesCmdObj)...)
+
+	if len(failures) > 0 {
+		return &DiscardFilesError{Failures: failures}
+	}
+
+	return nil
+}
+
+// runs the command for all of the files at once, and if that fails, for each
+// file in turn so that we know which of them failed
+func (self *WorkingTreeCommands) runBatched(
+	files []*models.File,
+	getCmdObj func([]*models.File) oscommands.ICmdObj,
+) []DiscardFailure {
+	if len(files) == 0 {
+		return nil
+	}
+
+	err := getCmdObj(files).Run()
+	if err == nil {
+		return nil
+	}
+	if len(files) == 1 {
+		return []DiscardFailure{{Path: files[0].Name, Err: err}}
+	}
+
+	failures := []DiscardFailure{}
+	for _, file := range files {
+		if err := getCmdObj([]*models.File{file}).Run(); err != nil {
+			failures = append(failures, DiscardFailure{Path: file.Name, Err: err})
+		}
+	}
+
+	return failures
+}
+
+// discardPlan sorts files by how DiscardAllFileChanges would discard them, so
+// that we can do the same for many files with a handful of commands
+type discardPlan struct {
+	// merge conflicts that need resolving, which we discard file by file
+	conflicted []*models.File
+	// files whose index entries need resetting
+	toReset []*models.File
+	// files git has no other version of, which we remove once they're reset
+	toRemove []*models.File
+	// files whose working tree changes are undone once they're reset
+	toRestore []*models.File
+}
+
+// renames must already have been split into their before and after files
+func planDiscard(files []*models.File) discardPlan {
+	plan := discardPlan{}
+
+	for _, file := range files {
+		switch file.ShortStatus {
+		case "AA", "DU":
+			plan.conflicted = append(plan.conflicted, file)
+			continue
+		}
+
+		if file.HasStagedChanges || file.HasMergeConflicts {
+			plan.toReset = append(plan.toReset, file)
+		}
+
+		if file.ShortStatus == "DD" || file.ShortStatus == "AU" {
+			continue
+		}
+
+		if file.Added {
+			plan.toRemove = append(plan.toRemove, file)
+		} else {
+			plan.toRestore = append(plan.toRestore, file)
+		}
+	}
+
+	return plan
+}
+
+func (self *WorkingTreeCommands) resetFilesCmdObj(files []*models.File) oscommands.ICmdObj {
+	return self.cmd.New("git reset -- " + self.quotedFileNames(files))
+}
+
+func (self *WorkingTreeCommands) restoreFilesCmdObj(files []*models.File) oscommands.ICmdObj {
+	return self.restoreFromIndexCmdObj(self.quotedFileNames(files))
+}
+
+func (self *WorkingTreeCommands) quotedFileNames(files []*models.File) string {
+	return strings.Join(slices.Map(files, func(file *models.File) string {
+		return self.cmd.Quote(file.Name)
+	}), " ")
+}
+
+func (self *WorkingTreeCommands) DiscardUnstagedDirChanges(node IFileNode) error {
+	if err := self.RemoveUntrackedDirFiles(node); err != nil {
+		return err
+	}
+
+	if err := self.restoreFromIndexCmdObj(self.cmd.Quote(node.GetPath())).Run(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (self *WorkingTreeCommands) RemoveUntrackedDirFiles(node IFileNode) error {
+	untrackedFilePaths := node.GetFilePathsMatching(
+		func(file *models.File) bool { return !file.GetIsTracked() },
+	)
+
+	remove := os.Remove
+	if self.UserConfig.OS.UseTrash {
+		remove = self.os.TrashFile
+	}
+
+	for _, path := range untrackedFilePaths {
+		err := remove(path)
+		if err != nil {
+			return err
+		}
+	}
+
+	// git doesn't track directories, so any directory within the node that is
+	// now empty only held untracked files and can go too
+	for _, path := range untrackedFilePaths {
+		if err := removeEmptyParentDirs(path, node.GetPath()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// removes the directory containing the given path if it's empty, then its
+// parent if that's now empty, and so on, stopping at rootDir (which is itself
+// removed if it ends up empty)
+func removeEmptyParentDirs(path string, rootDir string) error {
+	rootDir = filepath.Clean(rootDir)
+
+	for dir := filepath.Dir(path); dir != "." && dir != filepath.Dir(dir); dir = filepath.Dir(dir) {
+		if rootDir != "." && dir != rootDir && !strings.HasPrefix(dir, rootDir+string(filepath.Separator)) {
+			return nil
+		}
+
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				// already removed via another file in the same directory
+				continue
+			}
+			return err
+		}
+		if len(entries) > 0 {
+			return nil
+		}
+
+		if err := os.Remove(dir); err != nil {
+			return err
+		}
+
+		if dir == rootDir {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// DiscardUnstagedFileChanges directly
+func (self *WorkingTreeCommands) DiscardUnstagedFileChanges(file *models.File) error {
+	return self.DiscardToStaged(file.Name)
+}
+
+// DiscardToStaged puts the file in the working tree back to how it is in the
+// index, dropping any changes made since it was staged. Staged changes are left
+// alone, so unlike discarding all of the file's changes, this doesn't go back
+// to HEAD.
+func (self *WorkingTreeCommands) DiscardToStaged(fileName string) error {
+	return self.RestoreFile(fileName, RestoreOpts{Worktree: true})
+}
+
+type RestoreOpts struct {
+	// the ref to restore the file from. If empty, the working tree is restored
+	// from the index and the index from HEAD.
+	Source string
+	// restore the index
+	Staged bool
+	// restore the working tree. This is the default if Staged is not set.
+	Worktree bool
+}
+
+// RestoreFile restores a file in the index and/or working tree, using
+// `git restore` where available and falling back to the equivalent
+// `git checkout` or `git reset` otherwise
+func (self *WorkingTreeCommands) RestoreFile(fileName string, opts RestoreOpts) error {
+	cmdObj, err := self.restoreCmdObj(self.cmd.Quote(fileName), opts)
+	if err != nil {
+		return err
+	}
+
+	return cmdObj.Run()
+}
+
+func (self *WorkingTreeCommands) restoreCmdObj(quotedPaths string, opts RestoreOpts) (oscommands.ICmdObj, error) {
+	worktree := opts.Worktree || !opts.Staged
+
+	if self.features.SupportsRestore {
+		args := ""
+		if opts.Source != "" {
+			args += " --source=" + self.cmd.Quote(opts.Source)
+		}
+		if opts.Staged {
+			args += " --staged"
+		}
+		// the working tree is restored by default unless --staged is passed
+		if opts.Staged && opts.Worktree {
+			args += " --worktree"
+		}
+		return self.cmd.New("git restore" + args + " -- " + quotedPaths), nil
+	}
+
+	source := opts.Source
+	if source == "" && opts.Staged {
+		source = "HEAD"
+	}
+
+	switch {
+	case !opts.Staged:
+		if source != "" {
+			// 'git checkout <ref> -- <path>' always updates the index too
+			return nil, errors.New("restoring only the working tree from a ref requires git 2.23 or later")
+		}
+		return self.restoreFromIndexCmdObj(quotedPaths), nil
+	case !worktree:
+		return self.cmd.New("git reset -q " + self.cmd.Quote(source) + " -- " + quotedPaths), nil
+	default:
+		return self.cmd.New("git checkout " + self.cmd.Quote(source) + " -- " + quotedPaths), nil
+	}
+}
+
+// returns the command for overwriting the given (already quoted) paths in the
+// working tree with their contents in the index
+func (self *WorkingTreeCommands) restoreFromIndexCmdObj(quotedPaths string) oscommands.ICmdObj {
+	if self.features.SupportsRestore {
+		return self.cmd.New("git restore -- " + quotedPaths)
+	}
+
+	return self.cmd.New("git checkout -- " + quotedPaths)
+}
+
+// Ignore adds a file to the gitignore for the repo
+func (self *WorkingTreeCommands) Ignore(filename string) error {
+	return self.os.AppendLineToFile(".gitignore", filename)
+}
+
+// IgnoreMany adds the given patterns to the gitignore for the repo, skipping
+// any that are already there
+func (self *WorkingTreeCommands) IgnoreMany(patterns []string) error {
+	return self.os.AppendUniqueLinesToFile(".gitignore", patterns)
+}
+
+// SetExecutable marks the file as executable (or not) in the index, and
+// changes the file on disk to match, except on Windows where files don't have
+// an executable bit. The file must already be tracked.
+func (self *WorkingTreeCommands) SetExecutable(fileName string, executable bool) error {
+	chmodArg := "-x"
+	if executable {
+		chmodArg = "+x"
+	}
+
+	if err := self.cmd.New(
+		fmt.Sprintf("git update-index --chmod=%s -- %s", chmodArg, self.cmd.Quote(fileName)),
+	).Run(); err != nil {
+		return err
+	}
+
+	return self.os.SetExecutable(fileName, executable)
+}
+
+// UntrackAndIgnore stops git tracking the file and adds it to the gitignore
+// (unless it's there already), leaving the file itself alone. Without the first
+// step, ignoring the file would do nothing, because ignore rules don't apply to
+// tracked files. Any staged changes to the file are dropped from the index, but
+// the file on disk keeps its content.
+func (self *WorkingTreeCommands) UntrackAndIgnore(fileName string) error {
+	// --force is needed if the file has staged changes, and with --cached it
+	// only ever affects the index
+	if err := self.cmd.New("git rm --cached --force -- " + self.cmd.Quote(fileName)).Run(); err != nil {
+		return err
+	}
+
+	return self.IgnoreMany([]string{fileName})
+}
+
+// ApplyGitignoreTemplate appends one of the bundled gitignore templates (see
+// GitignoreTemplateNames) to the repo's gitignore, skipping any patterns that
+// are already there. Blank lines and comments are kept so that the template's
+// sections stay apart.
+func (self *WorkingTreeCommands) ApplyGitignoreTemplate(name string) error {
+	lines, err := gitignoreTemplateLines(name)
+	if err != nil {
+		return err
+	}
+
+	isPattern := func(line string) bool {
+		trimmed := strings.TrimSpace(line)
+		return trimmed != "" && !strings.HasPrefix(trimmed, "#")
+	}
+
+	return self.os.AppendUniqueLinesToFileWhere(".gitignore", lines, isPattern)
+}
+
+// RemoveFromGitignore removes the first line of the repo's gitignore which
+// exactly matches the given pattern
+func (self *WorkingTreeCommands) RemoveFromGitignore(pattern string) error {
+	return self.os.RemoveLineFromFile(".gitignore", pattern)
+}
+
+// OpenInFileManager shows the given file in the OS's file manager, or with an
+// empty file name, the top level directory of the repo
+func (self *WorkingTreeCommands) OpenInFileManager(fileName string) error {
+	if fileName != "" {
+		return self.os.OpenInFileManager(fileName)
+	}
+
+	repoDir, _, err := self.RepoPaths()
+	if err != nil {
+		return err
+	}
+
+	return self.os.OpenInFileManager(repoDir)
+}
+
+// Exclude adds a file to the .git/info/exclude for the repo
+func (self *WorkingTreeCommands) Exclude(filename string) error {
+	// info/exclude is shared by all worktrees, so for a linked worktree it lives
+	// in the main repo's git dir rather than in GitDir()
+	commonDir, err := self.CommonDir()
+	if err != nil {
+		return err
+	}
+
+	return self.os.AppendLineToFile(filepath.Join(commonDir, "info", "exclude"), filename)
+}
+
+// WorktreeFileDiff returns the diff of a file
+func (self *WorkingTreeCommands) WorktreeFileDiff(file *models.File, plain bool, cached bool, ignoreWhitespace bool) string {
+	// for now we assume an error means the file was deleted
+	s, _ := self.WorktreeFileDiffCmdObj(file, plain, cached, ignoreWhitespace).RunWithOutput()
+	return s
+}
+
+type FileDiffOpts struct {
+	// show the diff of the index against HEAD rather than the working tree
+	// against the index
+	Staged bool
+	// the number of context lines around each change, i.e. the n in -U<n>.
+	// Defaults to the user's configured diff context size if nil. Zero is
+	// allowed, giving hunks made up of nothing but changes.
+	DiffContextSize  *int
+	IgnoreWhitespace bool
+}
+
+// FileDiff returns the uncoloured diff of a single file. For untracked files
+// this is a diff against /dev/null, i.e. the whole file as added lines.
+func (self *WorkingTreeCommands) FileDiff(file *models.File, opts FileDiffOpts) (string, error) {
+	contextSize := self.UserConfig.Git.DiffContextSize
+	if opts.DiffContextSize != nil {
+		contextSize = *opts.DiffContextSize
+	}
+
+	cmdObj := self.worktreeFileDiffCmdObj(file, true, opts.Staged, opts.IgnoreWhitespace, contextSize)
+	stdout, stderr, err := cmdObj.RunWithOutputs()
+	// `git diff --no-index` exits with 1 if there are any differences, which
+	// for an untracked file there always are
+	if err != nil && stderr != "" {
+		return "", err
+	}
+
+	return stdout, nil
 }
 
-// UnStageFile unstages a file
-// we accept an array of filenames for the cases where a file has been renamed i.e.
-// we accept the current name and the previous name
-func (self *WorkingTreeCommands) UnStageFile(fileNames []string, reset bool) error {
-	command := "git rm --cached --force -- %s"
-	if reset {
-		command = "git reset HEAD -- %s"
+// SplitDiff returns the uncoloured staged and unstaged diffs of a file, e.g. to
+// show them next to each other for a partially staged file. A side with no
+// changes gives an empty diff without asking git. An untracked file has no
+// staged diff, and its unstaged diff is the whole file as added lines. For a
+// file with merge conflicts, the unstaged diff is git's combined diff and the
+// staged diff is empty, as nothing of it can be staged until it's resolved.
+func (self *WorkingTreeCommands) SplitDiff(file *models.File) (staged string, unstaged string, err error) {
+	if file.HasMergeConflicts {
+		unstaged, err = self.FileDiff(file, FileDiffOpts{})
+		return "", unstaged, err
 	}
 
-	for _, name := range fileNames {
-		err := self.cmd.New(fmt.Sprintf(command, self.cmd.Quote(name))).Run()
+	if file.HasStagedChanges {
+		staged, err = self.FileDiff(file, FileDiffOpts{Staged: true})
 		if err != nil {
-			return err
+			return "", "", err
 		}
 	}
-	return nil
-}
 
-func (self *WorkingTreeCommands) BeforeAndAfterFileForRename(file *models.File) (*models.File, *models.File, error) {
-	if !file.IsRename() {
-		return nil, nil, errors.New("Expected renamed file")
+	if file.HasUnstagedChanges {
+		unstaged, err = self.FileDiff(file, FileDiffOpts{})
+		if err != nil {
+			return "", "", err
+		}
 	}
 
-	// we've got a file that represents a rename from one file to another. Here we will refetch
-	// all files, passing the --no-renames flag and then recursively call the function
-	// again for the before file and after file.
-
-	filesWithoutRenames := self.fileLoader.GetStatusFiles(GetStatusFileOptions{NoRenames: true})
+	return staged, unstaged, nil
+}
 
-	var beforeFile *models.File
-	var afterFile *models.File
-	for _, f := range filesWithoutRenames {
-		if f.Name == file.PreviousName {
-			beforeFile = f
-		}
+// StagedByteSize returns the total size in bytes of the blobs that are staged,
+// i.e. roughly how much data committing would add. Deleted files and
+// submodules don't count.
+func (self *WorkingTreeCommands) StagedByteSize() (int64, error) {
+	output, err := self.cmd.New("git diff --cached --raw -z --no-abbrev --no-renames").DontLog().RunWithOutput()
+	if err != nil {
+		return 0, err
+	}
 
-		if f.Name == file.Name {
-			afterFile = f
-		}
+	objectIds := parseStagedBlobIds(output)
+	if len(objectIds) == 0 {
+		return 0, nil
 	}
 
-	if beforeFile == nil || afterFile == nil {
-		return nil, nil, errors.New("Could not find deleted file or new file for file rename")
+	cmdObj := self.cmd.New("git cat-file --batch-check=%(objectsize)").DontLog()
+	cmdObj.GetCmd().Stdin = strings.NewReader(strings.Join(objectIds, "\n") + "\n")
+	sizesOutput, err := cmdObj.RunWithOutput()
+	if err != nil {
+		return 0, err
 	}
 
-	if beforeFile.IsRename() || afterFile.IsRename() {
-		// probably won't happen but we want to ensure we don't get an infinite loop
-		return nil, nil, errors.New("Nested rename found")
+	var total int64
+	for _, line := range utils.SplitLines(sizesOutput) {
+		size, err := strconv.ParseInt(line, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("unexpected output from git cat-file: %s", line)
+		}
+		total += size
 	}
 
-	return beforeFile, afterFile, nil
+	return total, nil
 }
 
-// DiscardAllFileChanges directly
-func (self *WorkingTreeCommands) DiscardAllFileChanges(file *models.File) error {
-	if file.IsRename() {
-		beforeFile, afterFile, err := self.BeforeAndAfterFileForRename(file)
-		if err != nil {
-			return err
+// parses the output of `git diff --cached --raw -z` and returns the object ids of
+// the blobs in the index. Each entry looks like
+// :<old mode> <new mode> <old sha> <new sha> <status>\x00<path>\x00
+func parseStagedBlobIds(output string) []string {
+	objectIds := []string{}
+
+	for _, entry := range strings.Split(output, "\x00") {
+		if !strings.HasPrefix(entry, ":") {
+			// either a path or the trailing empty string
+			continue
 		}
 
-		if err := self.DiscardAllFileChanges(beforeFile); err != nil {
-			return err
+		fields := strings.Fields(entry)
+		if len(fields) < 5 {
+			continue
 		}
 
-		if err := self.DiscardAllFileChanges(afterFile); err != nil {
-			return err
+		newMode, newSha := fields[1], fields[3]
+		// a zero mode means the file was deleted and 160000 is a submodule,
+		// neither of which have a blob in the index
+		if newMode == "000000" || newMode == "160000" {
+			continue
 		}
 
-		return nil
+		objectIds = append(objectIds, newSha)
 	}
 
-	quotedFileName := self.cmd.Quote(file.Name)
+	return objectIds
+}
 
-	if file.ShortStatus == "AA" {
-		if err := self.cmd.New("git checkout --ours --  " + quotedFileName).Run(); err != nil {
-			return err
-		}
-		if err := self.cmd.New("git add -- " + quotedFileName).Run(); err != nil {
-			return err
-		}
-		return nil
-	}
+// HasOnlyWhitespaceOrEolChanges returns true if all of the file's unstaged
+// changes are to trailing whitespace or line endings, as commonly happens after
+// changing core.autocrlf. If any hunk has a real change, or the file has no
+// changes at all, it returns false.
+func (self *WorkingTreeCommands) HasOnlyWhitespaceOrEolChanges(fileName string) (bool, error) {
+	quotedFileName := self.cmd.Quote(fileName)
 
-	if file.ShortStatus == "DU" {
-		return self.cmd.New("git rm -- " + quotedFileName).Run()
+	changedFiles, err := self.cmd.New("git diff --name-only -- " + quotedFileName).DontLog().RunWithOutput()
+	if err != nil {
+		return false, err
 	}
-
-	// if the file isn't tracked, we assume you want to delete it
-	if file.HasStagedChanges || file.HasMergeConflicts {
-		if err := self.cmd.New("git reset -- " + quotedFileName).Run(); err != nil {
-			return err
-		}
+	if strings.TrimSpace(changedFiles) == "" {
+		return false, nil
 	}
 
-	if file.ShortStatus == "DD" || file.ShortStatus == "AU" {
-		return nil
+	// git prints nothing at all, not even the file header, when the changes
+	// vanish under these flags. Mode changes and binary files still print one.
+	diff, err := self.cmd.New(
+		"git diff --no-ext-diff --no-color --ignore-space-at-eol --ignore-cr-at-eol -- " + quotedFileName,
+	).DontLog().RunWithOutput()
+	if err != nil {
+		return false, err
 	}
 
-	if file.Added {
-		return self.os.RemoveFile(file.Name)
-	}
-	return self.DiscardUnstagedFileChanges(file)
+	return strings.TrimSpace(diff) == "", nil
 }
 
-type IFileNode interface {
-	ForEachFile(cb func(*models.File) error) error
-	GetFilePathsMatching(test func(*models.File) bool) []string
-	GetPath() string
-}
+// NewMarkersInStagedDiff returns the lines added in the staged changes that
+// contain any of the given markers (e.g. TODO or FIXME), so that we can warn
+// the user about them before they commit
+func (self *WorkingTreeCommands) NewMarkersInStagedDiff(markers []string) ([]*models.MarkerHit, error) {
+	if len(markers) == 0 {
+		return nil, nil
+	}
 
-func (self *WorkingTreeCommands) DiscardAllDirChanges(node IFileNode) error {
-	// this could be more efficient but we would need to handle all the edge cases
-	return node.ForEachFile(self.DiscardAllFileChanges)
+	output, err := self.cmd.New(
+		"git diff --cached --no-ext-diff --no-color --no-prefix --unified=0",
+	).DontLog().RunWithOutput()
+	if err != nil {
+		return nil, err
+	}
+
+	return parseMarkerHits(output, markers), nil
 }
 
-func (self *WorkingTreeCommands) DiscardUnstagedDirChanges(node IFileNode) error {
-	if err := self.RemoveUntrackedDirFiles(node); err != nil {
-		return err
+var hunkHeaderNewStartRegexp = regexp.MustCompile(`^@@ -\d+(?:,\d+)? \+(\d+)`)
+
+// parses the output of `git diff --no-prefix` and returns the added lines that
+// contain one of the markers, along with their line numbers in the new file
+func parseMarkerHits(diff string, markers []string) []*models.MarkerHit {
+	hits := []*models.MarkerHit{}
+
+	fileName := ""
+	inHunk := false
+	lineNumber := 0
+	for _, line := range strings.Split(diff, "\n") {
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			fileName = ""
+			inHunk = false
+		case !inHunk && strings.HasPrefix(line, "+++ "):
+			fileName = parseDiffHeaderPath(strings.TrimPrefix(line, "+++ "))
+		case strings.HasPrefix(line, "@@ "):
+			match := hunkHeaderNewStartRegexp.FindStringSubmatch(line)
+			if match == nil {
+				inHunk = false
+				continue
+			}
+			inHunk = true
+			lineNumber, _ = strconv.Atoi(match[1])
+		case !inHunk || fileName == "":
+			continue
+		case strings.HasPrefix(line, "+"):
+			content := line[1:]
+			if marker, ok := firstMarkerInLine(content, markers); ok {
+				hits = append(hits, &models.MarkerHit{
+					FileName:   fileName,
+					LineNumber: lineNumber,
+					Marker:     marker,
+					Content:    content,
+				})
+			}
+			lineNumber++
+		case strings.HasPrefix(line, " "):
+			lineNumber++
+		}
 	}
 
-	quotedPath := self.cmd.Quote(node.GetPath())
-	if err := self.cmd.New("git checkout -- " + quotedPath).Run(); err != nil {
-		return err
+	return hits
+}
+
+// paths in diff headers are quoted if they contain unusual characters, and the
+// new path is /dev/null if the file was deleted
+func parseDiffHeaderPath(path string) string {
+	if path == "/dev/null" {
+		return ""
 	}
 
-	return nil
+	return unquoteGitPath(path)
 }
 
-func (self *WorkingTreeCommands) RemoveUntrackedDirFiles(node IFileNode) error {
-	untrackedFilePaths := node.GetFilePathsMatching(
-		func(file *models.File) bool { return !file.GetIsTracked() },
-	)
-
-	for _, path := range untrackedFilePaths {
-		err := os.Remove(path)
-		if err != nil {
-			return err
+// returns whichever of the markers appears earliest in the line
+func firstMarkerInLine(line string, markers []string) (string, bool) {
+	found := ""
+	foundIndex := -1
+	for _, marker := range markers {
+		if marker == "" {
+			continue
+		}
+		index := strings.Index(line, marker)
+		if index != -1 && (foundIndex == -1 || index < foundIndex) {
+			found = marker
+			foundIndex = index
 		}
 	}
 
-	return nil
+	return found, foundIndex != -1
 }
 
-// DiscardUnstagedFileChanges directly
-func (self *WorkingTreeCommands) DiscardUnstagedFileChanges(file *models.File) error {
-	quotedFileName := self.cmd.Quote(file.Name)
-	return self.cmd.New("git checkout -- " + quotedFileName).Run()
+type WorktreeFileDiffOpts struct {
+	Plain            bool
+	Cached           bool
+	IgnoreWhitespace bool
+	// If non-zero, we stop reading the diff once it exceeds this many bytes,
+	// so that a huge (e.g. generated) file can't freeze the UI
+	MaxDiffBytes int
 }
 
-// Ignore adds a file to the gitignore for the repo
-func (self *WorkingTreeCommands) Ignore(filename string) error {
-	return self.os.AppendLineToFile(".gitignore", filename)
-}
+// WorktreeFileDiffWithLimit returns the diff of a file, along with whether it
+// was truncated because it exceeded opts.MaxDiffBytes. The diff is streamed
+// and git is killed as soon as we pass the limit, so we never hold more than
+// roughly MaxDiffBytes in memory.
+func (self *WorkingTreeCommands) WorktreeFileDiffWithLimit(file models.IFile, opts WorktreeFileDiffOpts) (string, bool, error) {
+	cmdObj := self.WorktreeFileDiffCmdObj(file, opts.Plain, opts.Cached, opts.IgnoreWhitespace)
+	// `git diff --no-index` exits with 1 if there are any differences, which
+	// for an untracked file there always are
+	noIndex := isNoIndexDiff(file, opts.Cached)
+
+	if opts.MaxDiffBytes <= 0 {
+		stdout, stderr, err := cmdObj.RunWithOutputs()
+		if err != nil && (!noIndex || stderr != "") {
+			return "", false, err
+		}
+		return stdout, false, nil
+	}
 
-// Exclude adds a file to the .git/info/exclude for the repo
-func (self *WorkingTreeCommands) Exclude(filename string) error {
-	return self.os.AppendLineToFile(".git/info/exclude", filename)
-}
+	if !noIndex {
+		cmdObj.CheckExitStatus()
+	}
 
-// WorktreeFileDiff returns the diff of a file
-func (self *WorkingTreeCommands) WorktreeFileDiff(file *models.File, plain bool, cached bool, ignoreWhitespace bool) string {
-	// for now we assume an error means the file was deleted
-	s, _ := self.WorktreeFileDiffCmdObj(file, plain, cached, ignoreWhitespace).RunWithOutput()
-	return s
+	var sb strings.Builder
+	truncated := false
+	err := cmdObj.RunAndProcessLines(func(line string) (bool, error) {
+		if sb.Len()+len(line)+1 > opts.MaxDiffBytes {
+			truncated = true
+			return true, nil
+		}
+		sb.WriteString(line + "\n")
+		return false, nil
+	})
+	if err != nil {
+		return "", false, err
+	}
+
+	return sb.String(), truncated, nil
 }
 
 func (self *WorkingTreeCommands) WorktreeFileDiffCmdObj(node models.IFile, plain bool, cached bool, ignoreWhitespace bool) oscommands.ICmdObj {
+	return self.worktreeFileDiffCmdObj(node, plain, cached, ignoreWhitespace, self.UserConfig.Git.DiffContextSize)
+}
+
+func (self *WorkingTreeCommands) worktreeFileDiffCmdObj(node models.IFile, plain bool, cached bool, ignoreWhitespace bool, contextSize int) oscommands.ICmdObj {
 	cachedArg := ""
 	trackedArg := "--"
 	colorArg := self.UserConfig.Git.Paging.ColorArg
 	quotedPath := self.cmd.Quote(node.GetPath())
 	quotedPrevPath := ""
 	ignoreWhitespaceArg := ""
-	contextSize := self.UserConfig.Git.DiffContextSize
 	if cached {
 		cachedArg = " --cached"
 	}
-	if !node.GetIsTracked() && !node.GetHasStagedChanges() && !cached && node.GetIsFile() {
+	if isNoIndexDiff(node, cached) {
 		trackedArg = "--no-index -- /dev/null"
 	}
 	if plain {
@@ -258,24 +1694,63 @@ func (self *WorkingTreeCommands) WorktreeFileDiffCmdObj(node models.IFile, plain
 	return self.cmd.New(cmdStr).DontLog()
 }
 
-func (self *WorkingTreeCommands) ApplyPatch(patch string, flags ...string) error {
+// an untracked file has nothing in the index to diff against, so we diff it
+// against /dev/null instead
+func isNoIndexDiff(node models.IFile, cached bool) bool {
+	return !node.GetIsTracked() && !node.GetHasStagedChanges() && !cached && node.GetIsFile()
+}
+
+// ApplyPatch applies the patch with git apply. diffContextSize is the number
+// of context lines in the diff that the patch was made from.
+func (self *WorkingTreeCommands) ApplyPatch(patch string, diffContextSize int, flags ...string) error {
 	filepath, err := self.SaveTemporaryPatch(patch)
 	if err != nil {
 		return err
 	}
 
-	return self.ApplyPatchFile(filepath, flags...)
+	return self.ApplyPatchFile(filepath, diffContextSize, flags...)
 }
 
-func (self *WorkingTreeCommands) ApplyPatchFile(filepath string, flags ...string) error {
+func (self *WorkingTreeCommands) ApplyPatchFile(filepath string, diffContextSize int, flags ...string) error {
 	flagStr := ""
 	for _, flag := range flags {
 		flagStr += " --" + flag
 	}
+	// without this git refuses patches whose hunks have no context
+	if diffContextSize == 0 {
+		flagStr += " --unidiff-zero"
+	}
 
 	return self.cmd.New(fmt.Sprintf("git apply%s %s", flagStr, self.cmd.Quote(filepath))).Run()
 }
 
+// DiscardPatch throws away the changes in the given patch (e.g. a single hunk)
+// by applying it in reverse to the working tree, leaving the index and the
+// file's other changes alone. diffContextSize is as for ApplyPatch.
+func (self *WorkingTreeCommands) DiscardPatch(fileName string, patch string, diffContextSize int) error {
+	unmerged, err := self.cmd.New(
+		"git ls-files --unmerged -- " + self.cmd.Quote(fileName),
+	).DontLog().RunWithOutput()
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(unmerged) != "" {
+		return errors.Errorf("cannot discard a patch of '%s' because it has merge conflicts", fileName)
+	}
+
+	patchPath, err := self.SaveTemporaryPatch(patch)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := self.os.RemoveFile(patchPath); err != nil {
+			self.Log.Error(err)
+		}
+	}()
+
+	return self.ApplyPatchFile(patchPath, diffContextSize, "reverse")
+}
+
 func (self *WorkingTreeCommands) SaveTemporaryPatch(patch string) (string, error) {
 	filepath := filepath.Join(self.os.GetTempDir(), utils.GetCurrentRepoName(), time.Now().Format("Jan _2 15.04.05.000000000")+".patch")
 	self.Log.Infof("saving temporary patch to %s", filepath)
@@ -321,28 +1796,191 @@ func (self *WorkingTreeCommands) ShowFileDiffCmdObj(from string, to string, reve
 		DontLog()
 }
 
-// CheckoutFile checks out the file for the given commit
+// CheckoutFile checks out the file for the given commit. We use git checkout
+// even where git restore is available because, given a directory, `git restore
+// --source` deletes any files in it that aren't in the commit, whereas git
+// checkout leaves them alone.
 func (self *WorkingTreeCommands) CheckoutFile(commitSha, fileName string) error {
-	return self.cmd.New(fmt.Sprintf("git checkout %s -- %s", commitSha, self.cmd.Quote(fileName))).Run()
+	return self.cmd.New(fmt.Sprintf("git checkout %s -- %s", self.cmd.Quote(commitSha), self.cmd.Quote(fileName))).Run()
+}
+
+// ApplyStashFile restores a single file from the given stash entry (e.g.
+// stash@{1}) into the working tree and index. If the stash recorded the file as
+// deleted, the file is deleted.
+func (self *WorkingTreeCommands) ApplyStashFile(stashRef string, fileName string) error {
+	if self.objectExists(stashRef + ":" + fileName) {
+		return self.CheckoutFile(stashRef, fileName)
+	}
+
+	// the stash commit's first parent is the commit the stash was created on,
+	// so if the file is there but not in the stash, the stash deleted it
+	if self.objectExists(stashRef + "^1:" + fileName) {
+		return self.cmd.New("git rm -f --ignore-unmatch -- " + self.cmd.Quote(fileName)).Run()
+	}
+
+	return fmt.Errorf("'%s' does not exist in %s", fileName, stashRef)
+}
+
+func (self *WorkingTreeCommands) objectExists(object string) bool {
+	return self.cmd.New("git cat-file -e "+self.cmd.Quote(object)).DontLog().Run() == nil
 }
 
-// DiscardAnyUnstagedFileChanges discards any unstages file changes via `git checkout -- .`
+// DiscardAnyUnstagedFileChanges discards any unstages file changes via `git restore -- .`
 func (self *WorkingTreeCommands) DiscardAnyUnstagedFileChanges() error {
-	return self.cmd.New("git checkout -- .").Run()
+	return self.restoreFromIndexCmdObj(".").Run()
 }
 
-// RemoveTrackedFiles will delete the given file(s) even if they are currently tracked
-func (self *WorkingTreeCommands) RemoveTrackedFiles(name string) error {
-	return self.cmd.New("git rm -r --cached -- " + self.cmd.Quote(name)).Run()
+// RemoveTrackedFiles removes the given paths (recursively) from the index. If
+// keepLocal is true the files are left in the working tree, untracked,
+// otherwise they are deleted from disk as well.
+func (self *WorkingTreeCommands) RemoveTrackedFiles(names []string, keepLocal bool) error {
+	cachedArg := ""
+	if keepLocal {
+		cachedArg = " --cached"
+	}
+
+	quotedNames := slices.Map(names, func(name string) string {
+		return self.cmd.Quote(name)
+	})
+
+	return self.cmd.New(fmt.Sprintf("git rm -r%s -- %s", cachedArg, strings.Join(quotedNames, " "))).Run()
 }
 
-// RemoveUntrackedFiles runs `git clean -fd`
+// RemoveUntrackedFiles runs `git clean -fd`, or with os.useTrash set, moves the
+// files git clean would remove to the trash
 func (self *WorkingTreeCommands) RemoveUntrackedFiles() error {
-	return self.cmd.New("git clean -fd").Run()
+	return self.RemoveUntrackedFilesContext(context.Background())
+}
+
+// RemoveUntrackedFilesContext is like RemoveUntrackedFiles, but git is killed if
+// ctx is cancelled before it's done, in which case we return ctx.Err(). Any
+// files git had already removed stay removed.
+func (self *WorkingTreeCommands) RemoveUntrackedFilesContext(ctx context.Context) error {
+	if self.UserConfig.OS.UseTrash {
+		_, err := self.trashUntrackedFiles(ctx, "", nil)
+		return err
+	}
+
+	return self.cmd.New("git clean -fd").WithContext(ctx).Run()
+}
+
+// RemoveUntrackedFilesWithProgress is like RemoveUntrackedFiles but calls
+// onRemoved with each path as git removes it, so that progress can be shown
+// when there are a lot of untracked files
+func (self *WorkingTreeCommands) RemoveUntrackedFilesWithProgress(onRemoved func(path string)) error {
+	if self.UserConfig.OS.UseTrash {
+		_, err := self.trashUntrackedFiles(context.Background(), "", onRemoved)
+		return err
+	}
+
+	return self.cmd.New("git clean -fd").CheckExitStatus().RunAndProcessLines(func(line string) (bool, error) {
+		if path, ok := parseCleanOutputLine(line); ok {
+			onRemoved(path)
+		}
+		return false, nil
+	})
+}
+
+type CleanOpts struct {
+	// only report what would be removed, without removing anything
+	DryRun bool
+	// untracked files matching any of these patterns (in .gitignore syntax) are
+	// kept, on top of the ignored files that git clean always keeps
+	Exclude []string
+}
+
+// CleanUntrackedInPath removes the untracked files matching the pathspec (e.g.
+// a directory) and returns the paths that were removed, or with opts.DryRun the
+// paths that would be removed. Use RemoveUntrackedFiles to clean the whole repo.
+func (self *WorkingTreeCommands) CleanUntrackedInPath(pathspec string, opts CleanOpts) ([]string, error) {
+	if pathspec == "" {
+		return nil, errors.New("refusing to clean with an empty pathspec")
+	}
+
+	extraArgs := ""
+	for _, pattern := range opts.Exclude {
+		extraArgs += " -e " + self.cmd.Quote(pattern)
+	}
+	extraArgs += " -- " + self.cmd.Quote(pathspec)
+
+	if self.UserConfig.OS.UseTrash && !opts.DryRun {
+		return self.trashUntrackedFiles(context.Background(), extraArgs, nil)
+	}
+
+	flags := "-fd"
+	if opts.DryRun {
+		flags = "-nd"
+	}
+
+	output, err := self.cmd.New(fmt.Sprintf("git clean %s%s", flags, extraArgs)).RunWithOutput()
+	if err != nil {
+		return nil, err
+	}
+
+	paths := []string{}
+	for _, line := range utils.SplitLines(output) {
+		if path, ok := parseCleanOutputLine(line); ok {
+			paths = append(paths, path)
+		}
+	}
+
+	return paths, nil
+}
+
+// git clean has no way of using the trash, so we ask it which files it would
+// remove (given the extra args, e.g. a pathspec) and move those to the trash
+// ourselves, calling onRemoved (if set) with each. Like git clean, we report
+// a directory as 'foo/'. If a file can't be trashed we stop there, returning
+// the paths trashed so far, rather than deleting anything permanently.
+func (self *WorkingTreeCommands) trashUntrackedFiles(ctx context.Context, extraArgs string, onRemoved func(path string)) ([]string, error) {
+	output, err := self.cmd.New("git clean -nd" + extraArgs).WithContext(ctx).DontLog().RunWithOutput()
+	if err != nil {
+		return nil, err
+	}
+
+	paths := []string{}
+	for _, line := range utils.SplitLines(output) {
+		path, ok := parseCleanOutputLine(line)
+		if !ok {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return paths, err
+		}
+
+		if err := self.os.TrashFile(strings.TrimSuffix(path, "/")); err != nil {
+			return paths, err
+		}
+		paths = append(paths, path)
+		if onRemoved != nil {
+			onRemoved(path)
+		}
+	}
+
+	return paths, nil
+}
+
+// parses a line like 'Removing foo/' or 'Would remove foo/' from `git clean`
+func parseCleanOutputLine(line string) (string, bool) {
+	for _, prefix := range []string{"Removing ", "Would remove "} {
+		if strings.HasPrefix(line, prefix) {
+			return unquoteGitPath(strings.TrimPrefix(line, prefix)), true
+		}
+	}
+
+	return "", false
 }
 
-// ResetAndClean removes all unstaged changes and removes all untracked files
-func (self *WorkingTreeCommands) ResetAndClean() error {
+type ResetAndCleanOpts struct {
+	// only discard changes to tracked files, leaving untracked files alone
+	KeepUntracked bool
+	// if set, called with each untracked path as it's removed
+	OnUntrackedFileRemoved func(path string)
+}
+
+// ResetAndClean removes all unstaged changes and, unless opts.KeepUntracked is
+// set, removes all untracked files
+func (self *WorkingTreeCommands) ResetAndClean(opts ResetAndCleanOpts) error {
 	submoduleConfigs, err := self.submodule.GetConfigs()
 	if err != nil {
 		return err
@@ -358,21 +1996,130 @@ func (self *WorkingTreeCommands) ResetAndClean() error {
 		return err
 	}
 
+	if opts.KeepUntracked {
+		return nil
+	}
+
+	if opts.OnUntrackedFileRemoved != nil {
+		return self.RemoveUntrackedFilesWithProgress(opts.OnUntrackedFileRemoved)
+	}
+
 	return self.RemoveUntrackedFiles()
 }
 
-// ResetHardHead runs `git reset --hard`
+type ResetMode int
+
+const (
+	// moves the branch, leaving the index and working tree alone
+	RESET_MODE_SOFT ResetMode = iota
+	// moves the branch and resets the index, leaving the working tree alone
+	RESET_MODE_MIXED
+	// moves the branch and resets the index and working tree, discarding all changes
+	RESET_MODE_HARD
+	// like hard, but keeps local changes, refusing to reset if any of them are
+	// in files that differ between HEAD and the ref
+	RESET_MODE_KEEP
+	// like keep, but also resets files with staged changes or merge conflicts,
+	// for backing out of a merge
+	RESET_MODE_MERGE
+)
+
+func (self ResetMode) flag() (string, error) {
+	switch self {
+	case RESET_MODE_SOFT:
+		return "--soft", nil
+	case RESET_MODE_MIXED:
+		return "--mixed", nil
+	case RESET_MODE_HARD:
+		return "--hard", nil
+	case RESET_MODE_KEEP:
+		return "--keep", nil
+	case RESET_MODE_MERGE:
+		return "--merge", nil
+	default:
+		return "", fmt.Errorf("unknown reset mode: %d", self)
+	}
+}
+
+// ResetWouldOverwriteChangesError is returned when a keep or merge reset is
+// refused because it would overwrite local changes
+type ResetWouldOverwriteChangesError struct {
+	Ref   string
+	Paths []string
+}
+
+func (self *ResetWouldOverwriteChangesError) Error() string {
+	return fmt.Sprintf(
+		"cannot reset to '%s' without overwriting local changes to: %s",
+		self.Ref, strings.Join(self.Paths, ", "),
+	)
+}
+
+var resetNotUpToDateRegex = regexp.MustCompile(`Entry '(.+)' (not uptodate|would be overwritten by merge)\. Cannot merge\.`)
+
+// ResetWorktree runs `git reset` to the given ref with the given mode
+func (self *WorkingTreeCommands) ResetWorktree(ref string, mode ResetMode) error {
+	return self.ResetWorktreeContext(context.Background(), ref, mode)
+}
+
+// ResetWorktreeContext is like ResetWorktree, but git is killed if ctx is
+// cancelled before it's done, in which case we return ctx.Err()
+func (self *WorkingTreeCommands) ResetWorktreeContext(ctx context.Context, ref string, mode ResetMode) error {
+	flag, err := mode.flag()
+	if err != nil {
+		return err
+	}
+
+	err = self.cmd.New(fmt.Sprintf("git reset %s %s", flag, self.cmd.Quote(ref))).
+		WithContext(ctx).
+		Run()
+	if err == nil || (mode != RESET_MODE_KEEP && mode != RESET_MODE_MERGE) {
+		return err
+	}
+
+	matches := resetNotUpToDateRegex.FindAllStringSubmatch(err.Error(), -1)
+	if len(matches) == 0 {
+		return err
+	}
+
+	return &ResetWouldOverwriteChangesError{
+		Ref: ref,
+		Paths: slices.Map(matches, func(match []string) string {
+			return match[1]
+		}),
+	}
+}
+
+// ResetHard runs `git reset --hard <ref>`, discarding all changes
 func (self *WorkingTreeCommands) ResetHard(ref string) error {
-	return self.cmd.New("git reset --hard " + self.cmd.Quote(ref)).Run()
+	return self.ResetWorktree(ref, RESET_MODE_HARD)
 }
 
-// ResetSoft runs `git reset --soft HEAD`
+// ResetSoft runs `git reset --soft <ref>`, moving the branch to the ref while
+// keeping all changes staged. e.g. passing HEAD~1 undoes the last commit.
 func (self *WorkingTreeCommands) ResetSoft(ref string) error {
-	return self.cmd.New("git reset --soft " + self.cmd.Quote(ref)).Run()
+	return self.ResetWorktree(ref, RESET_MODE_SOFT)
+}
+
+// UncommitLast undoes the last commit, leaving its changes (along with any
+// changes that were already staged or unstaged) as unstaged changes in the
+// working tree, and the branch pointing at the commit's parent. Nothing is
+// lost from the working tree.
+func (self *WorkingTreeCommands) UncommitLast() error {
+	if err := self.cmd.New("git rev-parse --verify --quiet HEAD~1").DontLog().Run(); err != nil {
+		return errors.New("cannot uncommit the root commit")
+	}
+
+	if err := self.ResetSoft("HEAD~1"); err != nil {
+		return err
+	}
+
+	return self.UnstageAll()
 }
 
+// ResetMixed runs `git reset --mixed <ref>`, keeping all changes but unstaging them
 func (self *WorkingTreeCommands) ResetMixed(ref string) error {
-	return self.cmd.New("git reset --mixed " + self.cmd.Quote(ref)).Run()
+	return self.ResetWorktree(ref, RESET_MODE_MIXED)
 }
 
 // so that we don't have unnecessary space in our commands we use this helper function to prepend spaces to args so that in the format string we can go '%s%s%s' and if any args are missing we won't have gaps.
@@ -1,18 +1,57 @@
 package git_commands
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"os"
+	"os/exec"
+	"path/filepath"
 	"regexp"
+	"runtime"
+	"strings"
 	"testing"
 
 	"github.com/go-errors/errors"
+	"github.com/jesseduffield/lazygit/pkg/commands/git_config"
 	"github.com/jesseduffield/lazygit/pkg/commands/models"
 	"github.com/jesseduffield/lazygit/pkg/commands/oscommands"
 	"github.com/jesseduffield/lazygit/pkg/config"
+	"github.com/samber/lo"
 	"github.com/stretchr/testify/assert"
 )
 
+func TestWorkingTreeOpenMergeToolWithCmdObj(t *testing.T) {
+	scenarios := []struct {
+		testName     string
+		toolName     string
+		fileName     string
+		expectedArgs []string
+	}{
+		{
+			testName:     "typical case",
+			toolName:     "vimdiff",
+			fileName:     "file.txt",
+			expectedArgs: []string{"mergetool", "--tool=vimdiff", "--", "file.txt"},
+		},
+		{
+			testName:     "file name with spaces",
+			toolName:     "meld",
+			fileName:     "my file; rm -rf.txt",
+			expectedArgs: []string{"mergetool", "--tool=meld", "--", "my file; rm -rf.txt"},
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			instance := buildWorkingTreeCommands(commonDeps{})
+
+			assert.Equal(t, s.expectedArgs, instance.OpenMergeToolWithCmdObj(s.toolName, s.fileName).GetCmd().Args[1:])
+		})
+	}
+}
+
 func TestWorkingTreeStageFile(t *testing.T) {
 	runner := oscommands.NewFakeRunner(t).
 		Expect(`git add -- "test.txt"`, "", nil)
@@ -33,6 +72,343 @@ func TestWorkingTreeStageFiles(t *testing.T) {
 	runner.CheckForMissingCalls()
 }
 
+func TestWorkingTreeUnicodeFileNames(t *testing.T) {
+	runner := oscommands.NewFakeRunner(t).
+		ExpectGitArgs([]string{"add", "--", "café 🎉.txt"}, "", nil).
+		ExpectGitArgs([]string{"reset", "--", "café 🎉.txt"}, "", nil).
+		ExpectGitArgs([]string{"checkout", "--", "café 🎉.txt"}, "", nil)
+
+	instance := buildWorkingTreeCommands(commonDeps{runner: runner})
+
+	assert.NoError(t, instance.StageFile("café 🎉.txt"))
+	assert.NoError(t, instance.DiscardAllFileChanges(&models.File{
+		Name:             "café 🎉.txt",
+		ShortStatus:      "M ",
+		Tracked:          true,
+		HasStagedChanges: true,
+	}))
+	runner.CheckForMissingCalls()
+}
+
+func TestWorkingTreeStageAllTracked(t *testing.T) {
+	// 'git add -u' stages modifications and deletions, but not new files
+	runner := oscommands.NewFakeRunner(t).
+		Expect(`git add -u`, "", nil)
+
+	instance := buildWorkingTreeCommands(commonDeps{runner: runner})
+
+	assert.NoError(t, instance.StageAllTracked())
+	runner.CheckForMissingCalls()
+}
+
+func TestWorkingTreeStageAllTrackedInRepo(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	type scenario struct {
+		testName       string
+		change         func(t *testing.T)
+		expectedStatus string
+	}
+
+	scenarios := []scenario{
+		{
+			testName: "modification",
+			change: func(t *testing.T) {
+				assert.NoError(t, os.WriteFile("tracked.txt", []byte("changed\n"), 0o644))
+			},
+			expectedStatus: "M  tracked.txt\n",
+		},
+		{
+			testName: "deletion",
+			change: func(t *testing.T) {
+				assert.NoError(t, os.Remove("tracked.txt"))
+			},
+			expectedStatus: "D  tracked.txt\n",
+		},
+		{
+			testName: "new file",
+			change: func(t *testing.T) {
+				assert.NoError(t, os.WriteFile("new.txt", []byte("new\n"), 0o644))
+			},
+			expectedStatus: "?? new.txt\n",
+		},
+	}
+
+	git := func(t *testing.T, args ...string) string {
+		output, err := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...).CombinedOutput()
+		assert.NoError(t, err, string(output))
+		return string(output)
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			dir := t.TempDir()
+			wd, err := os.Getwd()
+			assert.NoError(t, err)
+			assert.NoError(t, os.Chdir(dir))
+			defer func() { assert.NoError(t, os.Chdir(wd)) }()
+
+			git(t, "init", "--quiet")
+			assert.NoError(t, os.WriteFile("tracked.txt", []byte("original\n"), 0o644))
+			git(t, "add", "tracked.txt")
+			git(t, "commit", "--quiet", "-m", "initial")
+
+			s.change(t)
+
+			instance := buildWorkingTreeCommands(commonDeps{cmd: oscommands.NewDummyOSCommand().Cmd})
+			assert.NoError(t, instance.StageAllTracked())
+
+			assert.Equal(t, s.expectedStatus, git(t, "status", "--porcelain"))
+		})
+	}
+}
+
+func TestWorkingTreeStageFileVerified(t *testing.T) {
+	type scenario struct {
+		testName      string
+		statusOutput  string
+		expectedError string
+	}
+
+	scenarios := []scenario{
+		{
+			testName:     "fully staged",
+			statusOutput: "M  test.txt\x00",
+		},
+		{
+			testName:     "no changes left at all",
+			statusOutput: "",
+		},
+		{
+			testName:     "staged rename whose original path looks like a status",
+			statusOutput: "R  test.txt\x00AM old.txt\x00",
+		},
+		{
+			testName:      "still has unstaged changes",
+			statusOutput:  "MM test.txt\x00",
+			expectedError: "'test.txt' still has unstaged changes after staging it",
+		},
+		{
+			testName:      "still untracked",
+			statusOutput:  "?? test.txt\x00",
+			expectedError: "'test.txt' still has unstaged changes after staging it",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			runner := oscommands.NewFakeRunner(t).
+				Expect(`git add -- "test.txt"`, "", nil).
+				Expect(`git status --porcelain -z --untracked-files=all -- "test.txt"`, s.statusOutput, nil)
+			instance := buildWorkingTreeCommands(commonDeps{runner: runner})
+
+			err := instance.StageFileVerified("test.txt")
+			if s.expectedError == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, s.expectedError)
+			}
+			runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestWorkingTreeToggleStaged(t *testing.T) {
+	type scenario struct {
+		testName string
+		file     *models.File
+		runner   *oscommands.FakeCmdObjRunner
+	}
+
+	scenarios := []scenario{
+		{
+			testName: "unstaged",
+			file:     &models.File{Name: "test.txt", ShortStatus: " M", Tracked: true, HasUnstagedChanges: true},
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git add -- "test.txt"`, "", nil),
+		},
+		{
+			testName: "partially staged",
+			file:     &models.File{Name: "test.txt", ShortStatus: "MM", Tracked: true, HasStagedChanges: true, HasUnstagedChanges: true},
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git add -- "test.txt"`, "", nil),
+		},
+		{
+			testName: "fully staged",
+			file:     &models.File{Name: "test.txt", ShortStatus: "M ", Tracked: true, HasStagedChanges: true},
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git reset HEAD -- "test.txt"`, "", nil),
+		},
+		{
+			testName: "staged new file",
+			file:     &models.File{Name: "test.txt", ShortStatus: "A ", Added: true, HasStagedChanges: true},
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git rm --cached --force -- "test.txt"`, "", nil),
+		},
+		{
+			testName: "staged rename",
+			file:     &models.File{Name: "new.txt", PreviousName: "old.txt", ShortStatus: "R ", Tracked: true, HasStagedChanges: true},
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git reset HEAD -- "new.txt"`, "", nil).
+				Expect(`git reset HEAD -- "old.txt"`, "", nil),
+		},
+		{
+			testName: "rename with unstaged changes",
+			file:     &models.File{Name: "new.txt", PreviousName: "old.txt", ShortStatus: "RM", Tracked: true, HasStagedChanges: true, HasUnstagedChanges: true},
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git add -- "new.txt"`, "", nil),
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			instance := buildWorkingTreeCommands(commonDeps{runner: s.runner})
+
+			assert.NoError(t, instance.ToggleStaged(s.file))
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestWorkingTreeStageAllExcept(t *testing.T) {
+	type scenario struct {
+		testName     string
+		patterns     []string
+		statusOutput string
+		runner       func(*oscommands.FakeCmdObjRunner) *oscommands.FakeCmdObjRunner
+	}
+
+	statusOutput := " M main.go\x00 M package-lock.json\x00?? notes.txt\x00?? vendor/lib/lib.go\x00" +
+		"M  staged.go\x00MM partly.go\x00 D removed.go\x00"
+
+	scenarios := []scenario{
+		{
+			testName:     "no patterns stages everything with unstaged changes",
+			patterns:     []string{},
+			statusOutput: statusOutput,
+			runner: func(runner *oscommands.FakeCmdObjRunner) *oscommands.FakeCmdObjRunner {
+				return runner.Expect(`git add -- "main.go" "package-lock.json" "notes.txt" "vendor/lib/lib.go" "partly.go" "removed.go"`, "", nil)
+			},
+		},
+		{
+			testName:     "excludes tracked and untracked files matching the patterns",
+			patterns:     []string{"package-lock.json", "*.txt", "vendor/"},
+			statusOutput: statusOutput,
+			runner: func(runner *oscommands.FakeCmdObjRunner) *oscommands.FakeCmdObjRunner {
+				return runner.Expect(`git add -- "main.go" "partly.go" "removed.go"`, "", nil)
+			},
+		},
+		{
+			testName:     "negated pattern",
+			patterns:     []string{"*.go", "!main.go"},
+			statusOutput: statusOutput,
+			runner: func(runner *oscommands.FakeCmdObjRunner) *oscommands.FakeCmdObjRunner {
+				return runner.Expect(`git add -- "main.go" "package-lock.json" "notes.txt"`, "", nil)
+			},
+		},
+		{
+			testName:     "nothing left to stage",
+			patterns:     []string{"*"},
+			statusOutput: statusOutput,
+			runner: func(runner *oscommands.FakeCmdObjRunner) *oscommands.FakeCmdObjRunner {
+				return runner
+			},
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			runner := s.runner(oscommands.NewFakeRunner(t).
+				Expect(`git status --untracked-files=all --porcelain -z --no-renames`, s.statusOutput, nil))
+			instance := buildWorkingTreeCommands(commonDeps{runner: runner})
+
+			assert.NoError(t, instance.StageAllExcept(s.patterns))
+			runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestWorkingTreeStageAllExceptWhenStatusFails(t *testing.T) {
+	runner := oscommands.NewFakeRunner(t).
+		Expect(`git status --untracked-files=all --porcelain -z --no-renames`, "", errors.New("not a git repository"))
+	instance := buildWorkingTreeCommands(commonDeps{runner: runner})
+
+	assert.EqualError(t, instance.StageAllExcept([]string{"*.txt"}), "not a git repository")
+	runner.CheckForMissingCalls()
+}
+
+func TestWorkingTreeStageByChangeType(t *testing.T) {
+	type scenario struct {
+		testName    string
+		changeType  FileChangeType
+		expectedCmd string
+	}
+
+	statusOutput := " M main.go\x00MM partly.go\x00M  staged.go\x00 T link\x00 D removed.go\x00" +
+		"?? notes.txt\x00 A intent.go\x00 R renamed.go\x00original.go\x00RM moved.go\x00was.go\x00" +
+		"UU conflicted.go\x00D  gone.go\x00"
+
+	scenarios := []scenario{
+		{
+			testName:    "added",
+			changeType:  FILE_CHANGE_ADDED,
+			expectedCmd: `git add -- "notes.txt" "intent.go"`,
+		},
+		{
+			testName:    "modified",
+			changeType:  FILE_CHANGE_MODIFIED,
+			expectedCmd: `git add -- "main.go" "partly.go" "link" "moved.go"`,
+		},
+		{
+			testName:    "deleted",
+			changeType:  FILE_CHANGE_DELETED,
+			expectedCmd: `git add -- "removed.go"`,
+		},
+		{
+			testName:    "renamed",
+			changeType:  FILE_CHANGE_RENAMED,
+			expectedCmd: `git add -- "renamed.go" "original.go"`,
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			runner := oscommands.NewFakeRunner(t).
+				Expect(`git status --untracked-files=all --porcelain -z`, statusOutput, nil).
+				Expect(s.expectedCmd, "", nil)
+			instance := buildWorkingTreeCommands(commonDeps{runner: runner})
+
+			assert.NoError(t, instance.StageByChangeType(s.changeType))
+			runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestWorkingTreeStageByChangeTypeWithNothingToStage(t *testing.T) {
+	runner := oscommands.NewFakeRunner(t).
+		Expect(`git status --untracked-files=all --porcelain -z`, "M  staged.go\x00 M main.go\x00", nil)
+	instance := buildWorkingTreeCommands(commonDeps{runner: runner})
+
+	assert.NoError(t, instance.StageByChangeType(FILE_CHANGE_DELETED))
+	runner.CheckForMissingCalls()
+}
+
+func TestWorkingTreeStageByChangeTypeWhenStatusFails(t *testing.T) {
+	runner := oscommands.NewFakeRunner(t).
+		Expect(`git status --untracked-files=all --porcelain -z`, "", errors.New("not a git repository"))
+	instance := buildWorkingTreeCommands(commonDeps{runner: runner})
+
+	assert.EqualError(t, instance.StageByChangeType(FILE_CHANGE_DELETED), "not a git repository")
+	runner.CheckForMissingCalls()
+}
+
 func TestWorkingTreeUnstageFile(t *testing.T) {
 	type scenario struct {
 		testName string
@@ -79,6 +455,8 @@ func TestWorkingTreeDiscardAllFileChanges(t *testing.T) {
 		testName      string
 		file          *models.File
 		removeFile    func(string) error
+		useTrash      bool
+		trashFile     func(string) error
 		runner        *oscommands.FakeCmdObjRunner
 		expectedError string
 	}
@@ -189,12 +567,48 @@ func TestWorkingTreeDiscardAllFileChanges(t *testing.T) {
 			runner:        oscommands.NewFakeRunner(t),
 			expectedError: "",
 		},
+		{
+			testName: "Move to the trash",
+			file: &models.File{
+				Name:    "test",
+				Tracked: false,
+				Added:   true,
+			},
+			useTrash: true,
+			trashFile: func(filename string) error {
+				assert.Equal(t, "test", filename)
+				return nil
+			},
+			runner:        oscommands.NewFakeRunner(t),
+			expectedError: "",
+		},
+		{
+			testName: "Trash unavailable",
+			file: &models.File{
+				Name:    "test",
+				Tracked: false,
+				Added:   true,
+			},
+			useTrash: true,
+			trashFile: func(string) error {
+				return errors.New("invalid cross-device link")
+			},
+			runner:        oscommands.NewFakeRunner(t),
+			expectedError: "could not move 'test' to the trash: invalid cross-device link. Set os.useTrash to false in your config to delete files permanently instead",
+		},
 	}
 
 	for _, s := range scenarios {
 		s := s
 		t.Run(s.testName, func(t *testing.T) {
-			instance := buildWorkingTreeCommands(commonDeps{runner: s.runner, removeFile: s.removeFile})
+			userConfig := config.GetDefaultConfig()
+			userConfig.OS.UseTrash = s.useTrash
+			instance := buildWorkingTreeCommands(commonDeps{
+				runner:     s.runner,
+				userConfig: userConfig,
+				removeFile: s.removeFile,
+				trashFile:  s.trashFile,
+			})
 			err := instance.DiscardAllFileChanges(s.file)
 
 			if s.expectedError == "" {
@@ -207,6 +621,69 @@ func TestWorkingTreeDiscardAllFileChanges(t *testing.T) {
 	}
 }
 
+func TestWorkingTreeDiscardAllFileChangesForUnmatchedRename(t *testing.T) {
+	// a rename in the working tree, which we can't split into a deletion and
+	// an addition without reloading the status, and by the time we do, the new
+	// path has been renamed again so it's nowhere to be found
+	dir := t.TempDir()
+	oldName := filepath.Join(dir, "old.txt")
+	newName := filepath.Join(dir, "new.txt")
+	assert.NoError(t, os.WriteFile(newName, []byte("content"), 0o644))
+
+	file := &models.File{Name: newName, PreviousName: oldName, Tracked: true, HasUnstagedChanges: true}
+	models.SetStatusFields(file, " R")
+
+	type scenario struct {
+		testName      string
+		oldNameInHead bool
+	}
+
+	scenarios := []scenario{
+		{
+			testName:      "restores the old path from HEAD and removes the new one",
+			oldNameInHead: true,
+		},
+		{
+			testName:      "old path isn't in HEAD either",
+			oldNameInHead: false,
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			runner := oscommands.NewFakeRunner(t).
+				Expect(`git status --untracked-files=all --porcelain -z --no-renames`, fmt.Sprintf(" D %s\x00?? %s\x00", oldName, filepath.Join(dir, "newer.txt")), nil)
+			if s.oldNameInHead {
+				runner.
+					ExpectGitArgs([]string{"cat-file", "-e", "HEAD:" + oldName}, "", nil).
+					ExpectGitArgs([]string{"checkout", "HEAD", "--", oldName}, "", nil)
+			} else {
+				runner.
+					ExpectGitArgs([]string{"cat-file", "-e", "HEAD:" + oldName}, "", errors.New("fatal: path does not exist in HEAD")).
+					ExpectGitArgs([]string{"rm", "--cached", "--force", "--ignore-unmatch", "--", oldName}, "", nil)
+			}
+			runner.
+				ExpectGitArgs([]string{"cat-file", "-e", "HEAD:" + newName}, "", errors.New("fatal: path does not exist in HEAD")).
+				ExpectGitArgs([]string{"rm", "--cached", "--force", "--ignore-unmatch", "--", newName}, "", nil)
+
+			removed := []string{}
+			instance := buildWorkingTreeCommands(commonDeps{
+				runner: runner,
+				removeFile: func(path string) error {
+					removed = append(removed, path)
+					return nil
+				},
+			})
+
+			assert.NoError(t, instance.DiscardAllFileChanges(file))
+			// the old path doesn't exist on disk, so there's nothing to remove
+			assert.Equal(t, []string{newName}, removed)
+			runner.CheckForMissingCalls()
+		})
+	}
+}
+
 func TestWorkingTreeDiff(t *testing.T) {
 	type scenario struct {
 		testName         string
@@ -389,11 +866,12 @@ func TestWorkingTreeShowFileDiff(t *testing.T) {
 
 func TestWorkingTreeCheckoutFile(t *testing.T) {
 	type scenario struct {
-		testName  string
-		commitSha string
-		fileName  string
-		runner    *oscommands.FakeCmdObjRunner
-		test      func(error)
+		testName   string
+		gitVersion *GitVersion
+		commitSha  string
+		fileName   string
+		runner     *oscommands.FakeCmdObjRunner
+		test       func(error)
 	}
 
 	scenarios := []scenario{
@@ -402,41 +880,262 @@ func TestWorkingTreeCheckoutFile(t *testing.T) {
 			commitSha: "11af912",
 			fileName:  "test999.txt",
 			runner: oscommands.NewFakeRunner(t).
-				Expect(`git checkout 11af912 -- "test999.txt"`, "", nil),
+				Expect(`git checkout "11af912" -- "test999.txt"`, "", nil),
 			test: func(err error) {
 				assert.NoError(t, err)
 			},
 		},
 		{
-			testName:  "returns error if there is one",
+			// without the '--' git would treat this as a branch
+			testName:  "file named like a branch",
 			commitSha: "11af912",
-			fileName:  "test999.txt",
+			fileName:  "master",
 			runner: oscommands.NewFakeRunner(t).
-				Expect(`git checkout 11af912 -- "test999.txt"`, "", errors.New("error")),
+				ExpectGitArgs([]string{"checkout", "11af912", "--", "master"}, "", nil),
 			test: func(err error) {
-				assert.Error(t, err)
+				assert.NoError(t, err)
 			},
 		},
-	}
-
-	for _, s := range scenarios {
-		s := s
-		t.Run(s.testName, func(t *testing.T) {
-			instance := buildWorkingTreeCommands(commonDeps{runner: s.runner})
-
-			s.test(instance.CheckoutFile(s.commitSha, s.fileName))
+		{
+			testName:  "file name with spaces",
+			commitSha: "11af912",
+			fileName:  "my file; rm -rf.txt",
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"checkout", "11af912", "--", "my file; rm -rf.txt"}, "", nil),
+			test: func(err error) {
+				assert.NoError(t, err)
+			},
+		},
+		{
+			// git restore would delete any files in the directory which
+			// aren't in the commit
+			testName:   "directory, with git restore available",
+			gitVersion: &GitVersion{2, 23, 0, ""},
+			commitSha:  "11af912",
+			fileName:   "my dir",
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"checkout", "11af912", "--", "my dir"}, "", nil),
+			test: func(err error) {
+				assert.NoError(t, err)
+			},
+		},
+		{
+			testName:  "returns error if there is one",
+			commitSha: "11af912",
+			fileName:  "test999.txt",
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git checkout "11af912" -- "test999.txt"`, "", errors.New("error")),
+			test: func(err error) {
+				assert.Error(t, err)
+			},
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			instance := buildWorkingTreeCommands(commonDeps{runner: s.runner, gitVersion: s.gitVersion})
+
+			s.test(instance.CheckoutFile(s.commitSha, s.fileName))
 			s.runner.CheckForMissingCalls()
 		})
 	}
 }
 
-func TestWorkingTreeApplyPatch(t *testing.T) {
+func TestWorkingTreeRestoreFile(t *testing.T) {
+	type scenario struct {
+		testName    string
+		opts        RestoreOpts
+		gitVersion  *GitVersion
+		expectedCmd string
+		expectedErr string
+	}
+
+	modern := &GitVersion{2, 23, 0, ""}
+	old := &GitVersion{2, 22, 0, ""}
+
+	scenarios := []scenario{
+		{
+			testName:    "working tree from the index",
+			opts:        RestoreOpts{Worktree: true},
+			gitVersion:  modern,
+			expectedCmd: `git restore -- "file.txt"`,
+		},
+		{
+			testName:    "index from HEAD",
+			opts:        RestoreOpts{Staged: true},
+			gitVersion:  modern,
+			expectedCmd: `git restore --staged -- "file.txt"`,
+		},
+		{
+			testName:    "index and working tree from a ref",
+			opts:        RestoreOpts{Source: "abc123", Staged: true, Worktree: true},
+			gitVersion:  modern,
+			expectedCmd: `git restore --source="abc123" --staged --worktree -- "file.txt"`,
+		},
+		{
+			testName:    "working tree from a ref",
+			opts:        RestoreOpts{Source: "abc123", Worktree: true},
+			gitVersion:  modern,
+			expectedCmd: `git restore --source="abc123" -- "file.txt"`,
+		},
+		{
+			testName:    "working tree from the index without restore",
+			opts:        RestoreOpts{Worktree: true},
+			gitVersion:  old,
+			expectedCmd: `git checkout -- "file.txt"`,
+		},
+		{
+			testName:    "index from HEAD without restore",
+			opts:        RestoreOpts{Staged: true},
+			gitVersion:  old,
+			expectedCmd: `git reset -q "HEAD" -- "file.txt"`,
+		},
+		{
+			testName:    "index and working tree from a ref without restore",
+			opts:        RestoreOpts{Source: "abc123", Staged: true, Worktree: true},
+			gitVersion:  old,
+			expectedCmd: `git checkout "abc123" -- "file.txt"`,
+		},
+		{
+			testName:    "index and working tree from HEAD without restore",
+			opts:        RestoreOpts{Staged: true, Worktree: true},
+			gitVersion:  old,
+			expectedCmd: `git checkout "HEAD" -- "file.txt"`,
+		},
+		{
+			testName:    "working tree from a ref without restore",
+			opts:        RestoreOpts{Source: "abc123", Worktree: true},
+			gitVersion:  old,
+			expectedErr: "restoring only the working tree from a ref requires git 2.23 or later",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			runner := oscommands.NewFakeRunner(t)
+			if s.expectedCmd != "" {
+				runner.Expect(s.expectedCmd, "", nil)
+			}
+			instance := buildWorkingTreeCommands(commonDeps{runner: runner, gitVersion: s.gitVersion})
+
+			err := instance.RestoreFile("file.txt", s.opts)
+			if s.expectedErr != "" {
+				assert.EqualError(t, err, s.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestWorkingTreeApplyStashFile(t *testing.T) {
 	type scenario struct {
 		testName string
 		runner   *oscommands.FakeCmdObjRunner
 		test     func(error)
 	}
 
+	scenarios := []scenario{
+		{
+			testName: "file modified in stash",
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git cat-file -e "stash@{1}:foo.txt"`, "", nil).
+				Expect(`git checkout "stash@{1}" -- "foo.txt"`, "", nil),
+			test: func(err error) {
+				assert.NoError(t, err)
+			},
+		},
+		{
+			testName: "file deleted in stash",
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git cat-file -e "stash@{1}:foo.txt"`, "", errors.New("error")).
+				Expect(`git cat-file -e "stash@{1}^1:foo.txt"`, "", nil).
+				Expect(`git rm -f --ignore-unmatch -- "foo.txt"`, "", nil),
+			test: func(err error) {
+				assert.NoError(t, err)
+			},
+		},
+		{
+			testName: "file not in stash",
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git cat-file -e "stash@{1}:foo.txt"`, "", errors.New("error")).
+				Expect(`git cat-file -e "stash@{1}^1:foo.txt"`, "", errors.New("error")),
+			test: func(err error) {
+				assert.EqualError(t, err, "'foo.txt' does not exist in stash@{1}")
+			},
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			instance := buildWorkingTreeCommands(commonDeps{runner: s.runner})
+
+			s.test(instance.ApplyStashFile("stash@{1}", "foo.txt"))
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestWorkingTreeCheckMergeDriver(t *testing.T) {
+	type scenario struct {
+		testName      string
+		output        string
+		expectedError string
+	}
+
+	scenarios := []scenario{
+		{
+			testName: "no merge attribute",
+			output:   "foo.txt\x00merge\x00unspecified\x00",
+		},
+		{
+			testName: "text driver",
+			output:   "foo.txt\x00merge\x00text\x00",
+		},
+		{
+			testName:      "binary via -merge",
+			output:        "foo.txt\x00merge\x00unset\x00",
+			expectedError: "'foo.txt' uses the 'binary' merge driver so its conflicts can't be resolved inline",
+		},
+		{
+			testName:      "custom driver",
+			output:        "foo.txt\x00merge\x00ours\x00",
+			expectedError: "'foo.txt' uses the 'ours' merge driver so its conflicts can't be resolved inline",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			runner := oscommands.NewFakeRunner(t).
+				Expect(`git check-attr -z merge -- "foo.txt"`, s.output, nil)
+			instance := buildWorkingTreeCommands(commonDeps{runner: runner})
+
+			err := instance.CheckMergeDriver("foo.txt")
+			if s.expectedError != "" {
+				assert.EqualError(t, err, s.expectedError)
+				var unsupportedErr *UnsupportedConflictTypeError
+				assert.True(t, errors.As(err, &unsupportedErr))
+			} else {
+				assert.NoError(t, err)
+			}
+			runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestWorkingTreeApplyPatch(t *testing.T) {
+	type scenario struct {
+		testName        string
+		diffContextSize int
+		runner          *oscommands.FakeCmdObjRunner
+		test            func(error)
+	}
+
 	expectFn := func(regexStr string, errToReturn error) func(cmdObj oscommands.ICmdObj) (string, error) {
 		return func(cmdObj oscommands.ICmdObj) (string, error) {
 			re := regexp.MustCompile(regexStr)
@@ -457,7 +1156,8 @@ func TestWorkingTreeApplyPatch(t *testing.T) {
 
 	scenarios := []scenario{
 		{
-			testName: "valid case",
+			testName:        "valid case",
+			diffContextSize: 3,
 			runner: oscommands.NewFakeRunner(t).
 				ExpectFunc(expectFn(`git apply --cached "(.*)"`, nil)),
 			test: func(err error) {
@@ -465,31 +1165,119 @@ func TestWorkingTreeApplyPatch(t *testing.T) {
 			},
 		},
 		{
-			testName: "command returns error",
+			testName:        "command returns error",
+			diffContextSize: 3,
 			runner: oscommands.NewFakeRunner(t).
 				ExpectFunc(expectFn(`git apply --cached "(.*)"`, errors.New("error"))),
 			test: func(err error) {
 				assert.Error(t, err)
 			},
 		},
+		{
+			testName:        "patch from a diff without context",
+			diffContextSize: 0,
+			runner: oscommands.NewFakeRunner(t).
+				ExpectFunc(expectFn(`git apply --cached --unidiff-zero "(.*)"`, nil)),
+			test: func(err error) {
+				assert.NoError(t, err)
+			},
+		},
 	}
 
 	for _, s := range scenarios {
 		s := s
 		t.Run(s.testName, func(t *testing.T) {
 			instance := buildWorkingTreeCommands(commonDeps{runner: s.runner})
-			s.test(instance.ApplyPatch("test", "cached"))
+			s.test(instance.ApplyPatch("test", s.diffContextSize, "cached"))
 			s.runner.CheckForMissingCalls()
 		})
 	}
 }
 
+func TestWorkingTreeDiscardPatch(t *testing.T) {
+	type scenario struct {
+		testName        string
+		unmergedOutput  string
+		applyErr        error
+		expectApply     bool
+		expectedErr     string
+		expectedRemoved bool
+	}
+
+	scenarios := []scenario{
+		{
+			testName:        "applies the patch in reverse to the working tree",
+			expectApply:     true,
+			expectedRemoved: true,
+		},
+		{
+			testName:        "removes the patch file when applying fails",
+			expectApply:     true,
+			applyErr:        errors.New("error: patch failed"),
+			expectedErr:     "error: patch failed",
+			expectedRemoved: true,
+		},
+		{
+			testName:       "rejects files with merge conflicts",
+			unmergedOutput: "100644 abc123 1\tfile.txt\n100644 def456 2\tfile.txt\n100644 789abc 3\tfile.txt\n",
+			expectedErr:    "cannot discard a patch of 'file.txt' because it has merge conflicts",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			patchPath := ""
+			runner := oscommands.NewFakeRunner(t).
+				Expect(`git ls-files --unmerged -- "file.txt"`, s.unmergedOutput, nil)
+			if s.expectApply {
+				runner.ExpectFunc(func(cmdObj oscommands.ICmdObj) (string, error) {
+					matches := regexp.MustCompile(`^git apply --reverse "(.*)"$`).FindStringSubmatch(cmdObj.ToString())
+					assert.Equal(t, 2, len(matches), fmt.Sprintf("unexpected command: %s", cmdObj.ToString()))
+
+					patchPath = matches[1]
+					content, err := os.ReadFile(patchPath)
+					assert.NoError(t, err)
+					assert.Equal(t, "test", string(content))
+
+					return "", s.applyErr
+				})
+			}
+
+			removedPaths := []string{}
+			instance := buildWorkingTreeCommands(commonDeps{
+				runner: runner,
+				removeFile: func(path string) error {
+					removedPaths = append(removedPaths, path)
+					return os.Remove(path)
+				},
+			})
+
+			err := instance.DiscardPatch("file.txt", "test", 3)
+			if s.expectedErr != "" {
+				assert.EqualError(t, err, s.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			if s.expectedRemoved {
+				assert.Equal(t, []string{patchPath}, removedPaths)
+				assert.NoFileExists(t, patchPath)
+			} else {
+				assert.Empty(t, removedPaths)
+			}
+			runner.CheckForMissingCalls()
+		})
+	}
+}
+
 func TestWorkingTreeDiscardUnstagedFileChanges(t *testing.T) {
 	type scenario struct {
-		testName string
-		file     *models.File
-		runner   *oscommands.FakeCmdObjRunner
-		test     func(error)
+		testName   string
+		file       *models.File
+		runner     *oscommands.FakeCmdObjRunner
+		gitVersion *GitVersion
+		test       func(error)
 	}
 
 	scenarios := []scenario{
@@ -502,18 +1290,61 @@ func TestWorkingTreeDiscardUnstagedFileChanges(t *testing.T) {
 				assert.NoError(t, err)
 			},
 		},
+		{
+			testName: "git restore is available",
+			file:     &models.File{Name: "test.txt"},
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git restore -- "test.txt"`, "", nil),
+			gitVersion: &GitVersion{2, 23, 0, ""},
+			test: func(err error) {
+				assert.NoError(t, err)
+			},
+		},
 	}
 
 	for _, s := range scenarios {
 		s := s
 		t.Run(s.testName, func(t *testing.T) {
-			instance := buildWorkingTreeCommands(commonDeps{runner: s.runner})
+			instance := buildWorkingTreeCommands(commonDeps{runner: s.runner, gitVersion: s.gitVersion})
 			s.test(instance.DiscardUnstagedFileChanges(s.file))
 			s.runner.CheckForMissingCalls()
 		})
 	}
 }
 
+func TestWorkingTreeDiscardToStaged(t *testing.T) {
+	type scenario struct {
+		testName    string
+		gitVersion  *GitVersion
+		expectedCmd string
+	}
+
+	scenarios := []scenario{
+		{
+			testName:    "with git restore",
+			gitVersion:  &GitVersion{2, 23, 0, ""},
+			expectedCmd: `git restore -- "test.txt"`,
+		},
+		{
+			testName:    "without git restore",
+			gitVersion:  &GitVersion{2, 22, 0, ""},
+			expectedCmd: `git checkout -- "test.txt"`,
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			runner := oscommands.NewFakeRunner(t).
+				Expect(s.expectedCmd, "", nil)
+			instance := buildWorkingTreeCommands(commonDeps{runner: runner, gitVersion: s.gitVersion})
+
+			assert.NoError(t, instance.DiscardToStaged("test.txt"))
+			runner.CheckForMissingCalls()
+		})
+	}
+}
+
 func TestWorkingTreeDiscardAnyUnstagedFileChanges(t *testing.T) {
 	type scenario struct {
 		testName string
@@ -570,23 +1401,102 @@ func TestWorkingTreeRemoveUntrackedFiles(t *testing.T) {
 	}
 }
 
-func TestWorkingTreeResetHard(t *testing.T) {
+func TestWorkingTreeRemoveUntrackedFilesWithProgress(t *testing.T) {
 	type scenario struct {
-		testName string
-		ref      string
-		runner   *oscommands.FakeCmdObjRunner
-		test     func(error)
+		testName        string
+		output          string
+		err             error
+		expectedRemoved []string
+		expectedError   string
 	}
 
 	scenarios := []scenario{
 		{
-			"valid case",
-			"HEAD",
-			oscommands.NewFakeRunner(t).
-				Expect(`git reset --hard "HEAD"`, "", nil),
-			func(err error) {
+			testName:        "all removed",
+			output:          "Removing a.txt\nRemoving big dir/\nwarning: could not open directory 'x'\n",
+			expectedRemoved: []string{"a.txt", "big dir/"},
+		},
+		{
+			testName:        "clean fails",
+			output:          "Removing a.txt\n",
+			err:             errors.New("error: could not lstat b.txt"),
+			expectedRemoved: []string{},
+			expectedError:   "error: could not lstat b.txt",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			runner := oscommands.NewFakeRunner(t).
+				ExpectFunc(func(cmdObj oscommands.ICmdObj) (string, error) {
+					assert.Equal(t, "git clean -fd", cmdObj.ToString())
+					// otherwise the real runner would drop the error
+					assert.True(t, cmdObj.ShouldCheckExitStatus())
+					return s.output, s.err
+				})
+			instance := buildWorkingTreeCommands(commonDeps{runner: runner})
+
+			removed := []string{}
+			err := instance.RemoveUntrackedFilesWithProgress(func(path string) {
+				removed = append(removed, path)
+			})
+			if s.expectedError != "" {
+				assert.EqualError(t, err, s.expectedError)
+			} else {
 				assert.NoError(t, err)
-			},
+			}
+			assert.Equal(t, s.expectedRemoved, removed)
+			runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestWorkingTreeCleanUntrackedInPath(t *testing.T) {
+	type scenario struct {
+		testName      string
+		pathspec      string
+		opts          CleanOpts
+		runner        *oscommands.FakeCmdObjRunner
+		expectedPaths []string
+		expectedError string
+	}
+
+	scenarios := []scenario{
+		{
+			testName: "clean a directory",
+			pathspec: "my dir",
+			opts:     CleanOpts{},
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git clean -fd -- "my dir"`, "Removing my dir/a.txt\nRemoving my dir/nested/\n", nil),
+			expectedPaths: []string{"my dir/a.txt", "my dir/nested/"},
+		},
+		{
+			testName: "dry run",
+			pathspec: "my dir",
+			opts:     CleanOpts{DryRun: true},
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git clean -nd -- "my dir"`, "Would remove my dir/a.txt\n", nil),
+			expectedPaths: []string{"my dir/a.txt"},
+		},
+		{
+			testName: "excluding patterns",
+			pathspec: ".",
+			opts:     CleanOpts{Exclude: []string{"*.local", "config dir/", "$HOME's file"}},
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs(
+					[]string{"clean", "-fd", "-e", "*.local", "-e", "config dir/", "-e", "$HOME's file", "--", "."},
+					"Removing build/\n",
+					nil,
+				),
+			expectedPaths: []string{"build/"},
+		},
+		{
+			testName:      "empty pathspec",
+			pathspec:      "",
+			opts:          CleanOpts{},
+			runner:        oscommands.NewFakeRunner(t),
+			expectedError: "refusing to clean with an empty pathspec",
 		},
 	}
 
@@ -594,7 +1504,2070 @@ func TestWorkingTreeResetHard(t *testing.T) {
 		s := s
 		t.Run(s.testName, func(t *testing.T) {
 			instance := buildWorkingTreeCommands(commonDeps{runner: s.runner})
-			s.test(instance.ResetHard(s.ref))
+			paths, err := instance.CleanUntrackedInPath(s.pathspec, s.opts)
+			if s.expectedError != "" {
+				assert.EqualError(t, err, s.expectedError)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, s.expectedPaths, paths)
+			}
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestWorkingTreeCleanWithTrash(t *testing.T) {
+	type scenario struct {
+		testName        string
+		runner          *oscommands.FakeCmdObjRunner
+		run             func(*WorkingTreeCommands) error
+		trashFails      string
+		expectedTrashed []string
+		expectedError   string
+	}
+
+	const cleanOutput = "Would remove a.txt\nWould remove \"caf\\303\\251.txt\"\nWould remove build/\n"
+
+	scenarios := []scenario{
+		{
+			testName: "remove untracked files",
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git clean -nd`, cleanOutput, nil),
+			run: func(instance *WorkingTreeCommands) error {
+				return instance.RemoveUntrackedFiles()
+			},
+			expectedTrashed: []string{"a.txt", "café.txt", "build"},
+		},
+		{
+			testName: "remove untracked files with progress",
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git clean -nd`, cleanOutput, nil),
+			run: func(instance *WorkingTreeCommands) error {
+				removed := []string{}
+				err := instance.RemoveUntrackedFilesWithProgress(func(path string) {
+					removed = append(removed, path)
+				})
+				assert.Equal(t, []string{"a.txt", "café.txt", "build/"}, removed)
+				return err
+			},
+			expectedTrashed: []string{"a.txt", "café.txt", "build"},
+		},
+		{
+			testName: "clean a directory",
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git clean -nd -e "*.local" -- "my dir"`, "Would remove my dir/a.txt\n", nil),
+			run: func(instance *WorkingTreeCommands) error {
+				paths, err := instance.CleanUntrackedInPath("my dir", CleanOpts{Exclude: []string{"*.local"}})
+				assert.Equal(t, []string{"my dir/a.txt"}, paths)
+				return err
+			},
+			expectedTrashed: []string{"my dir/a.txt"},
+		},
+		{
+			testName: "dry run trashes nothing",
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git clean -nd -- "my dir"`, "Would remove my dir/a.txt\n", nil),
+			run: func(instance *WorkingTreeCommands) error {
+				_, err := instance.CleanUntrackedInPath("my dir", CleanOpts{DryRun: true})
+				return err
+			},
+			expectedTrashed: []string{},
+		},
+		{
+			testName: "reset and clean",
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git reset --hard "HEAD"`, "", nil).
+				Expect(`git clean -nd`, "Would remove a.txt\n", nil),
+			run: func(instance *WorkingTreeCommands) error {
+				return instance.ResetAndClean(ResetAndCleanOpts{})
+			},
+			expectedTrashed: []string{"a.txt"},
+		},
+		{
+			testName: "stops at a file that can't be trashed",
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git clean -nd`, cleanOutput, nil),
+			run: func(instance *WorkingTreeCommands) error {
+				return instance.RemoveUntrackedFiles()
+			},
+			trashFails:      "café.txt",
+			expectedTrashed: []string{"a.txt"},
+			expectedError:   "could not move 'café.txt' to the trash: no trash here. Set os.useTrash to false in your config to delete files permanently instead",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			userConfig := config.GetDefaultConfig()
+			userConfig.OS.UseTrash = true
+
+			trashed := []string{}
+			instance := buildWorkingTreeCommands(commonDeps{
+				runner:     s.runner,
+				userConfig: userConfig,
+				trashFile: func(path string) error {
+					if path == s.trashFails {
+						return errors.New("no trash here")
+					}
+					trashed = append(trashed, path)
+					return nil
+				},
+			})
+
+			err := s.run(instance)
+			if s.expectedError != "" {
+				assert.EqualError(t, err, s.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, s.expectedTrashed, trashed)
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestWorkingTreeResetAndClean(t *testing.T) {
+	type scenario struct {
+		testName      string
+		opts          ResetAndCleanOpts
+		runner        *oscommands.FakeCmdObjRunner
+		expectedError string
+	}
+
+	scenarios := []scenario{
+		{
+			testName: "reset and clean",
+			opts:     ResetAndCleanOpts{},
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git reset --hard "HEAD"`, "", nil).
+				Expect(`git clean -fd`, "", nil),
+		},
+		{
+			testName: "keep untracked files",
+			opts:     ResetAndCleanOpts{KeepUntracked: true},
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git reset --hard "HEAD"`, "", nil),
+		},
+		{
+			testName: "clean fails while reporting progress",
+			opts:     ResetAndCleanOpts{OnUntrackedFileRemoved: func(string) {}},
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git reset --hard "HEAD"`, "", nil).
+				ExpectFunc(func(cmdObj oscommands.ICmdObj) (string, error) {
+					assert.True(t, cmdObj.ShouldCheckExitStatus())
+					return "", errors.New("error: could not lstat b.txt")
+				}),
+			expectedError: "error: could not lstat b.txt",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			instance := buildWorkingTreeCommands(commonDeps{runner: s.runner})
+			err := instance.ResetAndClean(s.opts)
+			if s.expectedError != "" {
+				assert.EqualError(t, err, s.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestWorkingTreeResetHard(t *testing.T) {
+	type scenario struct {
+		testName string
+		ref      string
+		runner   *oscommands.FakeCmdObjRunner
+		test     func(error)
+	}
+
+	scenarios := []scenario{
+		{
+			"valid case",
+			"HEAD",
+			oscommands.NewFakeRunner(t).
+				Expect(`git reset --hard "HEAD"`, "", nil),
+			func(err error) {
+				assert.NoError(t, err)
+			},
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			instance := buildWorkingTreeCommands(commonDeps{runner: s.runner})
+			s.test(instance.ResetHard(s.ref))
+		})
+	}
+}
+
+func TestWorkingTreeResetSoft(t *testing.T) {
+	runner := oscommands.NewFakeRunner(t).
+		Expect(`git reset --soft "HEAD~1"`, "", nil)
+
+	instance := buildWorkingTreeCommands(commonDeps{runner: runner})
+
+	assert.NoError(t, instance.ResetSoft("HEAD~1"))
+	runner.CheckForMissingCalls()
+}
+
+func TestWorkingTreeResetWorktree(t *testing.T) {
+	type scenario struct {
+		testName      string
+		mode          ResetMode
+		runner        *oscommands.FakeCmdObjRunner
+		expectedError error
+	}
+
+	notUpToDateOutput := "error: Entry 'a.txt' not uptodate. Cannot merge.\n" +
+		"error: Entry 'dir/b.txt' not uptodate. Cannot merge.\n" +
+		"fatal: Could not reset index file to revision 'HEAD~1'.\n"
+
+	scenarios := []scenario{
+		{
+			testName: "soft",
+			mode:     RESET_MODE_SOFT,
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git reset --soft "HEAD~1"`, "", nil),
+		},
+		{
+			testName: "mixed",
+			mode:     RESET_MODE_MIXED,
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git reset --mixed "HEAD~1"`, "", nil),
+		},
+		{
+			testName: "hard",
+			mode:     RESET_MODE_HARD,
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git reset --hard "HEAD~1"`, "", nil),
+		},
+		{
+			testName: "keep",
+			mode:     RESET_MODE_KEEP,
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git reset --keep "HEAD~1"`, "", nil),
+		},
+		{
+			testName: "merge",
+			mode:     RESET_MODE_MERGE,
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git reset --merge "HEAD~1"`, "", nil),
+		},
+		{
+			testName: "keep refused because of local changes",
+			mode:     RESET_MODE_KEEP,
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git reset --keep "HEAD~1"`, "", errors.New(notUpToDateOutput)),
+			expectedError: &ResetWouldOverwriteChangesError{
+				Ref:   "HEAD~1",
+				Paths: []string{"a.txt", "dir/b.txt"},
+			},
+		},
+		{
+			testName: "keep failing for another reason",
+			mode:     RESET_MODE_KEEP,
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git reset --keep "HEAD~1"`, "", errors.New("fatal: ambiguous argument 'HEAD~1'")),
+			expectedError: errors.New("fatal: ambiguous argument 'HEAD~1'"),
+		},
+		{
+			testName:      "unknown mode",
+			mode:          ResetMode(42),
+			runner:        oscommands.NewFakeRunner(t),
+			expectedError: errors.New("unknown reset mode: 42"),
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			instance := buildWorkingTreeCommands(commonDeps{runner: s.runner})
+
+			err := instance.ResetWorktree("HEAD~1", s.mode)
+			if s.expectedError == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, s.expectedError.Error())
+				var overwriteErr *ResetWouldOverwriteChangesError
+				_, expectOverwriteErr := s.expectedError.(*ResetWouldOverwriteChangesError)
+				assert.Equal(t, expectOverwriteErr, errors.As(err, &overwriteErr))
+			}
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestWorkingTreeUncommitLast(t *testing.T) {
+	type scenario struct {
+		testName string
+		runner   *oscommands.FakeCmdObjRunner
+		test     func(error)
+	}
+
+	scenarios := []scenario{
+		{
+			testName: "has parent commit",
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git rev-parse --verify --quiet HEAD~1`, "abc123\n", nil).
+				Expect(`git reset --soft "HEAD~1"`, "", nil).
+				Expect(`git reset`, "", nil),
+			test: func(err error) {
+				assert.NoError(t, err)
+			},
+		},
+		{
+			testName: "root commit",
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git rev-parse --verify --quiet HEAD~1`, "", errors.New("exit status 1")),
+			test: func(err error) {
+				assert.EqualError(t, err, "cannot uncommit the root commit")
+			},
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			instance := buildWorkingTreeCommands(commonDeps{runner: s.runner})
+			s.test(instance.UncommitLast())
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestWorkingTreeIsWorkingTreeClean(t *testing.T) {
+	type scenario struct {
+		testName        string
+		opts            IsWorkingTreeCleanOpts
+		runner          *oscommands.FakeCmdObjRunner
+		expectedIsClean bool
+	}
+
+	scenarios := []scenario{
+		{
+			testName: "clean",
+			opts:     IsWorkingTreeCleanOpts{},
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git status --porcelain --ignore-submodules=none --untracked-files=normal`, "", nil),
+			expectedIsClean: true,
+		},
+		{
+			testName: "untracked file",
+			opts:     IsWorkingTreeCleanOpts{},
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git status --porcelain --ignore-submodules=none --untracked-files=normal`, "?? new.txt\n", nil),
+			expectedIsClean: false,
+		},
+		{
+			testName: "ignoring untracked files",
+			opts:     IsWorkingTreeCleanOpts{IgnoreUntracked: true},
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git status --porcelain --ignore-submodules=none --untracked-files=no`, "", nil),
+			expectedIsClean: true,
+		},
+		{
+			testName: "dirty submodule",
+			opts:     IsWorkingTreeCleanOpts{IgnoreUntracked: true},
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git status --porcelain --ignore-submodules=none --untracked-files=no`, " M sub\n", nil),
+			expectedIsClean: false,
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			instance := buildWorkingTreeCommands(commonDeps{runner: s.runner})
+			isClean, err := instance.IsWorkingTreeClean(s.opts)
+			assert.NoError(t, err)
+			assert.Equal(t, s.expectedIsClean, isClean)
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestWorkingTreeCommit(t *testing.T) {
+	type scenario struct {
+		testName      string
+		message       string
+		opts          CommitOpts
+		gitConfig     map[string]string
+		expectedCmd   string
+		expectedError string
+	}
+
+	scenarios := []scenario{
+		{
+			testName:    "plain commit",
+			message:     "test",
+			expectedCmd: `git commit -m "test"`,
+		},
+		{
+			testName:    "commit with description",
+			message:     "test\nline 2",
+			expectedCmd: `git commit -m "test" -m "line 2"`,
+		},
+		{
+			testName:    "all options",
+			message:     "test",
+			opts:        CommitOpts{Amend: true, NoVerify: true, SignOff: true},
+			expectedCmd: `git commit --amend --no-verify --signoff -m "test"`,
+		},
+		{
+			testName:    "amend keeping the message",
+			message:     "",
+			opts:        CommitOpts{Amend: true},
+			expectedCmd: `git commit --amend --no-edit`,
+		},
+		{
+			testName:    "signing enabled",
+			message:     "test",
+			gitConfig:   map[string]string{"commit.gpgsign": "true"},
+			expectedCmd: `git commit -S -m "test"`,
+		},
+		{
+			testName:      "empty message",
+			message:       "",
+			expectedError: "commit message must not be empty",
+		},
+		{
+			testName:    "verbose with a message to start from",
+			message:     "test",
+			opts:        CommitOpts{Verbose: true},
+			expectedCmd: `git commit --verbose --edit -m "test"`,
+		},
+		{
+			testName:    "verbose without a message",
+			message:     "",
+			opts:        CommitOpts{Verbose: true},
+			expectedCmd: `git commit --verbose --edit`,
+		},
+		{
+			testName:    "verbose amend keeping the message",
+			message:     "",
+			opts:        CommitOpts{Amend: true, Verbose: true},
+			expectedCmd: `git commit --amend --verbose --edit`,
+		},
+		{
+			testName:    "limited to paths",
+			message:     "test",
+			opts:        CommitOpts{Paths: []string{"a.txt", "dir/b c.txt"}},
+			expectedCmd: `git commit -m "test" -- "a.txt" "dir/b c.txt"`,
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			instance := buildWorkingTreeCommands(commonDeps{gitConfig: git_config.NewFakeGitConfig(s.gitConfig)})
+
+			cmdObj, err := instance.Commit(s.message, s.opts)
+			if s.expectedError != "" {
+				assert.EqualError(t, err, s.expectedError)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, s.expectedCmd, cmdObj.ToString())
+		})
+	}
+}
+
+func TestWorkingTreeStageAndCommitFile(t *testing.T) {
+	type scenario struct {
+		testName      string
+		message       string
+		runner        *oscommands.FakeCmdObjRunner
+		expectedCmd   string
+		expectedError string
+	}
+
+	scenarios := []scenario{
+		{
+			testName: "stages the file and commits only its path",
+			message:  "quick fix",
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"add", "--", "test.txt"}, "", nil),
+			expectedCmd: `git commit -m "quick fix" -- "test.txt"`,
+		},
+		{
+			testName: "staging fails",
+			message:  "quick fix",
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"add", "--", "test.txt"}, "", errors.New("error")),
+			expectedError: "error",
+		},
+		{
+			testName:      "empty message",
+			message:       "",
+			runner:        oscommands.NewFakeRunner(t),
+			expectedError: "commit message must not be empty",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			instance := buildWorkingTreeCommands(commonDeps{runner: s.runner})
+
+			cmdObj, err := instance.StageAndCommitFile("test.txt", s.message)
+			if s.expectedError != "" {
+				assert.EqualError(t, err, s.expectedError)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, s.expectedCmd, cmdObj.ToString())
+			}
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestWorkingTreeAmendWithAllChanges(t *testing.T) {
+	type scenario struct {
+		testName      string
+		gitConfig     map[string]string
+		runner        *oscommands.FakeCmdObjRunner
+		expectedCmd   string
+		expectedError string
+	}
+
+	scenarios := []scenario{
+		{
+			testName:  "amend",
+			gitConfig: map[string]string{},
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git rev-parse --verify --quiet HEAD`, "", nil).
+				Expect(`git add -u`, "", nil),
+			expectedCmd: `git commit --amend --no-edit`,
+		},
+		{
+			testName:  "amend with signing",
+			gitConfig: map[string]string{"commit.gpgsign": "true"},
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git rev-parse --verify --quiet HEAD`, "", nil).
+				Expect(`git add -u`, "", nil),
+			expectedCmd: `git commit --amend -S --no-edit`,
+		},
+		{
+			testName:  "no commits yet",
+			gitConfig: map[string]string{},
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git rev-parse --verify --quiet HEAD`, "", errors.New("error")),
+			expectedError: "there is no commit to amend",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			instance := buildWorkingTreeCommands(commonDeps{
+				runner:    s.runner,
+				gitConfig: git_config.NewFakeGitConfig(s.gitConfig),
+			})
+
+			cmdObj, err := instance.AmendWithAllChanges()
+			if s.expectedError != "" {
+				assert.EqualError(t, err, s.expectedError)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, s.expectedCmd, cmdObj.ToString())
+			}
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestWorkingTreeDiscardStagedChanges(t *testing.T) {
+	type scenario struct {
+		testName string
+		file     *models.File
+		runner   *oscommands.FakeCmdObjRunner
+	}
+
+	scenarios := []scenario{
+		{
+			testName: "regular file",
+			file:     &models.File{Name: "test.txt", HasStagedChanges: true},
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git reset -- "test.txt"`, "", nil),
+		},
+		{
+			testName: "renamed file",
+			file:     &models.File{Name: "new.txt", PreviousName: "old.txt", HasStagedChanges: true},
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git reset -- "new.txt" "old.txt"`, "", nil),
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			instance := buildWorkingTreeCommands(commonDeps{runner: s.runner})
+			assert.NoError(t, instance.DiscardStagedChanges(s.file))
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestWorkingTreeFileDiff(t *testing.T) {
+	type scenario struct {
+		testName string
+		file     *models.File
+		opts     FileDiffOpts
+		runner   *oscommands.FakeCmdObjRunner
+	}
+
+	const expectedResult = "pretend this is an actual git diff"
+
+	scenarios := []scenario{
+		{
+			testName: "unstaged",
+			file:     &models.File{Name: "test.txt", Tracked: true},
+			opts:     FileDiffOpts{},
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git diff --submodule --no-ext-diff --unified=3 --color=never -- "test.txt"`, expectedResult, nil),
+		},
+		{
+			testName: "staged with custom context and ignoring whitespace",
+			file:     &models.File{Name: "test.txt", Tracked: true, HasStagedChanges: true},
+			opts:     FileDiffOpts{Staged: true, DiffContextSize: lo.ToPtr(1), IgnoreWhitespace: true},
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git diff --submodule --no-ext-diff --unified=1 --color=never --ignore-all-space --cached -- "test.txt"`, expectedResult, nil),
+		},
+		{
+			testName: "zero context",
+			file:     &models.File{Name: "test.txt", Tracked: true},
+			opts:     FileDiffOpts{DiffContextSize: lo.ToPtr(0)},
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git diff --submodule --no-ext-diff --unified=0 --color=never -- "test.txt"`, expectedResult, nil),
+		},
+		{
+			testName: "untracked",
+			file:     &models.File{Name: "test.txt", Tracked: false},
+			opts:     FileDiffOpts{},
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git diff --submodule --no-ext-diff --unified=3 --color=never --no-index -- /dev/null "test.txt"`, expectedResult, errors.New("exit status 1")),
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			userConfig := config.GetDefaultConfig()
+			userConfig.Git.DiffContextSize = 3
+			instance := buildWorkingTreeCommands(commonDeps{runner: s.runner, userConfig: userConfig})
+
+			diff, err := instance.FileDiff(s.file, s.opts)
+			assert.NoError(t, err)
+			assert.Equal(t, expectedResult, diff)
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestWorkingTreeSplitDiff(t *testing.T) {
+	type scenario struct {
+		testName         string
+		file             *models.File
+		runner           *oscommands.FakeCmdObjRunner
+		expectedStaged   string
+		expectedUnstaged string
+	}
+
+	const stagedCmd = `git diff --submodule --no-ext-diff --unified=3 --color=never --cached -- "test.txt"`
+	const unstagedCmd = `git diff --submodule --no-ext-diff --unified=3 --color=never -- "test.txt"`
+
+	scenarios := []scenario{
+		{
+			testName: "partially staged",
+			file:     &models.File{Name: "test.txt", Tracked: true, HasStagedChanges: true, HasUnstagedChanges: true},
+			runner: oscommands.NewFakeRunner(t).
+				Expect(stagedCmd, "staged diff", nil).
+				Expect(unstagedCmd, "unstaged diff", nil),
+			expectedStaged:   "staged diff",
+			expectedUnstaged: "unstaged diff",
+		},
+		{
+			testName: "only staged",
+			file:     &models.File{Name: "test.txt", Tracked: true, HasStagedChanges: true},
+			runner: oscommands.NewFakeRunner(t).
+				Expect(stagedCmd, "staged diff", nil),
+			expectedStaged:   "staged diff",
+			expectedUnstaged: "",
+		},
+		{
+			testName: "untracked",
+			file:     &models.File{Name: "test.txt", Tracked: false, HasUnstagedChanges: true},
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git diff --submodule --no-ext-diff --unified=3 --color=never --no-index -- /dev/null "test.txt"`, "whole file", errors.New("exit status 1")),
+			expectedStaged:   "",
+			expectedUnstaged: "whole file",
+		},
+		{
+			testName: "merge conflict",
+			file:     &models.File{Name: "test.txt", Tracked: true, HasStagedChanges: true, HasUnstagedChanges: true, HasMergeConflicts: true},
+			runner: oscommands.NewFakeRunner(t).
+				Expect(unstagedCmd, "combined diff", nil),
+			expectedStaged:   "",
+			expectedUnstaged: "combined diff",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			userConfig := config.GetDefaultConfig()
+			userConfig.Git.DiffContextSize = 3
+			instance := buildWorkingTreeCommands(commonDeps{runner: s.runner, userConfig: userConfig})
+
+			staged, unstaged, err := instance.SplitDiff(s.file)
+			assert.NoError(t, err)
+			assert.Equal(t, s.expectedStaged, staged)
+			assert.Equal(t, s.expectedUnstaged, unstaged)
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestWorkingTreeDiffWithLimit(t *testing.T) {
+	type scenario struct {
+		testName          string
+		untracked         bool
+		maxDiffBytes      int
+		err               error
+		expectedDiff      string
+		expectedTruncated bool
+		expectedError     string
+	}
+
+	const diff = "diff --git a/test.txt b/test.txt\n+line 1\n+line 2\n"
+	const cmdStr = `git diff --submodule --no-ext-diff --unified=3 --color=never -- "test.txt"`
+	const untrackedCmdStr = `git diff --submodule --no-ext-diff --unified=3 --color=never --no-index -- /dev/null "test.txt"`
+
+	scenarios := []scenario{
+		{
+			testName:          "no limit",
+			maxDiffBytes:      0,
+			expectedDiff:      diff,
+			expectedTruncated: false,
+		},
+		{
+			testName:          "diff within limit",
+			maxDiffBytes:      len(diff),
+			expectedDiff:      diff,
+			expectedTruncated: false,
+		},
+		{
+			testName:          "diff exceeding limit",
+			maxDiffBytes:      len(diff) - 1,
+			expectedDiff:      "diff --git a/test.txt b/test.txt\n+line 1\n",
+			expectedTruncated: true,
+		},
+		{
+			testName:      "no limit, git fails",
+			maxDiffBytes:  0,
+			err:           errors.New("fatal: bad object"),
+			expectedError: "fatal: bad object",
+		},
+		{
+			testName:      "git fails",
+			maxDiffBytes:  len(diff),
+			err:           errors.New("fatal: bad object"),
+			expectedError: "fatal: bad object",
+		},
+		{
+			testName:          "untracked file, no limit",
+			untracked:         true,
+			maxDiffBytes:      0,
+			err:               errors.New("exit status 1"),
+			expectedDiff:      diff,
+			expectedTruncated: false,
+		},
+		{
+			testName:          "untracked file within limit",
+			untracked:         true,
+			maxDiffBytes:      len(diff),
+			expectedDiff:      diff,
+			expectedTruncated: false,
+		},
+		{
+			testName:          "untracked file exceeding limit",
+			untracked:         true,
+			maxDiffBytes:      len(diff) - 1,
+			expectedDiff:      "diff --git a/test.txt b/test.txt\n+line 1\n",
+			expectedTruncated: true,
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			expectedCmdStr := cmdStr
+			if s.untracked {
+				expectedCmdStr = untrackedCmdStr
+			}
+			runner := oscommands.NewFakeRunner(t).
+				ExpectFunc(func(cmdObj oscommands.ICmdObj) (string, error) {
+					assert.Equal(t, expectedCmdStr, cmdObj.ToString())
+					if s.maxDiffBytes > 0 {
+						// `git diff --no-index` exits with 1 whenever there's
+						// a diff, so we can't check its exit status
+						assert.Equal(t, !s.untracked, cmdObj.ShouldCheckExitStatus())
+					}
+					return diff, s.err
+				})
+			instance := buildWorkingTreeCommands(commonDeps{runner: runner})
+
+			file := &models.File{Name: "test.txt", Tracked: !s.untracked, HasUnstagedChanges: true}
+			result, truncated, err := instance.WorktreeFileDiffWithLimit(file, WorktreeFileDiffOpts{
+				Plain:        true,
+				MaxDiffBytes: s.maxDiffBytes,
+			})
+			if s.expectedError != "" {
+				assert.EqualError(t, err, s.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, s.expectedDiff, result)
+			assert.Equal(t, s.expectedTruncated, truncated)
+			runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestWorkingTreeRemoveTrackedFiles(t *testing.T) {
+	type scenario struct {
+		testName  string
+		names     []string
+		keepLocal bool
+		runner    *oscommands.FakeCmdObjRunner
+	}
+
+	scenarios := []scenario{
+		{
+			testName:  "keep local files",
+			names:     []string{"dir/file with space.txt"},
+			keepLocal: true,
+			runner: oscommands.NewFakeRunner(t).
+				ExpectArgs([]string{"git", "rm", "-r", "--cached", "--", "dir/file with space.txt"}, "", nil),
+		},
+		{
+			testName:  "delete local files",
+			names:     []string{"a.txt", "b dir"},
+			keepLocal: false,
+			runner: oscommands.NewFakeRunner(t).
+				ExpectArgs([]string{"git", "rm", "-r", "--", "a.txt", "b dir"}, "", nil),
+		},
+		{
+			testName:  "path that looks like a flag",
+			names:     []string{"-rf"},
+			keepLocal: true,
+			runner: oscommands.NewFakeRunner(t).
+				ExpectArgs([]string{"git", "rm", "-r", "--cached", "--", "-rf"}, "", nil),
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			instance := buildWorkingTreeCommands(commonDeps{runner: s.runner})
+			assert.NoError(t, instance.RemoveTrackedFiles(s.names, s.keepLocal))
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestWorkingTreeStagedByteSize(t *testing.T) {
+	rawOutput := ":100644 100644 1111111111111111111111111111111111111111 aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa M\x00modified.txt\x00" +
+		":000000 100755 0000000000000000000000000000000000000000 bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb A\x00image.png\x00" +
+		":100644 000000 2222222222222222222222222222222222222222 0000000000000000000000000000000000000000 D\x00deleted.txt\x00" +
+		":160000 160000 3333333333333333333333333333333333333333 cccccccccccccccccccccccccccccccccccccccc M\x00submodule\x00"
+
+	type scenario struct {
+		testName     string
+		runner       *oscommands.FakeCmdObjRunner
+		expectedSize int64
+	}
+
+	scenarios := []scenario{
+		{
+			testName: "nothing staged",
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git diff --cached --raw -z --no-abbrev --no-renames`, "", nil),
+			expectedSize: 0,
+		},
+		{
+			testName: "modified, added, deleted and submodule entries",
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git diff --cached --raw -z --no-abbrev --no-renames`, rawOutput, nil).
+				ExpectFunc(func(cmdObj oscommands.ICmdObj) (string, error) {
+					assert.Equal(t, `git cat-file --batch-check=%(objectsize)`, cmdObj.ToString())
+					stdin, err := io.ReadAll(cmdObj.GetCmd().Stdin)
+					assert.NoError(t, err)
+					assert.Equal(t,
+						"aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa\nbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb\n",
+						string(stdin),
+					)
+					return "120\n4096\n", nil
+				}),
+			expectedSize: 4216,
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			instance := buildWorkingTreeCommands(commonDeps{runner: s.runner})
+			size, err := instance.StagedByteSize()
+			assert.NoError(t, err)
+			assert.Equal(t, s.expectedSize, size)
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestWorkingTreeHasOnlyWhitespaceOrEolChanges(t *testing.T) {
+	type scenario struct {
+		testName string
+		runner   *oscommands.FakeCmdObjRunner
+		expected bool
+	}
+
+	scenarios := []scenario{
+		{
+			testName: "only line ending changes",
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git diff --name-only -- "file.txt"`, "file.txt\n", nil).
+				Expect(`git diff --no-ext-diff --no-color --ignore-space-at-eol --ignore-cr-at-eol -- "file.txt"`, "", nil),
+			expected: true,
+		},
+		{
+			testName: "some hunks have real changes",
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git diff --name-only -- "file.txt"`, "file.txt\n", nil).
+				Expect(
+					`git diff --no-ext-diff --no-color --ignore-space-at-eol --ignore-cr-at-eol -- "file.txt"`,
+					"diff --git a/file.txt b/file.txt\nindex b77b4eb..9f2decb 100644\n--- a/file.txt\n+++ b/file.txt\n@@ -1,2 +1,2 @@\n x\n-y\n+z\n",
+					nil,
+				),
+			expected: false,
+		},
+		{
+			testName: "no changes at all",
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git diff --name-only -- "file.txt"`, "", nil),
+			expected: false,
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			instance := buildWorkingTreeCommands(commonDeps{runner: s.runner})
+
+			result, err := instance.HasOnlyWhitespaceOrEolChanges("file.txt")
+			assert.NoError(t, err)
+			assert.Equal(t, s.expected, result)
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestWorkingTreeNewMarkersInStagedDiff(t *testing.T) {
+	diff := `diff --git pkg/app.go pkg/app.go
+index 1234567..89abcde 100644
+--- pkg/app.go
++++ pkg/app.go
+@@ -3 +3,2 @@ func main() {
+-	// TODO: this was already here
++	run() // FIXME: handle the error
++	// TODO: remove this once run() is fixed
+@@ -20,0 +22 @@ func run() {
++	return nil
+diff --git old.go old.go
+deleted file mode 100644
+index 1234567..0000000
+--- old.go
++++ /dev/null
+@@ -1 +0,0 @@
+-// TODO: deleted along with the file
+diff --git "docs/with\ttab.md" "docs/with\ttab.md"
+new file mode 100644
+index 0000000..1234567
+--- /dev/null
++++ "docs/with\ttab.md"
+@@ -0,0 +1,2 @@
++# Notes
++Something to do (TODO), and a FIXME too
+\ No newline at end of file
+diff --git image.png image.png
+index 1234567..89abcde 100644
+Binary files image.png and image.png differ
+`
+
+	type scenario struct {
+		testName     string
+		markers      []string
+		runner       *oscommands.FakeCmdObjRunner
+		expectedHits []*models.MarkerHit
+	}
+
+	scenarios := []scenario{
+		{
+			testName: "finds markers in added lines only",
+			markers:  []string{"TODO", "FIXME"},
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git diff --cached --no-ext-diff --no-color --no-prefix --unified=0`, diff, nil),
+			expectedHits: []*models.MarkerHit{
+				{FileName: "pkg/app.go", LineNumber: 3, Marker: "FIXME", Content: "\trun() // FIXME: handle the error"},
+				{FileName: "pkg/app.go", LineNumber: 4, Marker: "TODO", Content: "\t// TODO: remove this once run() is fixed"},
+				{FileName: "docs/with\ttab.md", LineNumber: 2, Marker: "TODO", Content: "Something to do (TODO), and a FIXME too"},
+			},
+		},
+		{
+			testName: "only looks for the given markers",
+			markers:  []string{"FIXME"},
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git diff --cached --no-ext-diff --no-color --no-prefix --unified=0`, diff, nil),
+			expectedHits: []*models.MarkerHit{
+				{FileName: "pkg/app.go", LineNumber: 3, Marker: "FIXME", Content: "\trun() // FIXME: handle the error"},
+				{FileName: "docs/with\ttab.md", LineNumber: 2, Marker: "FIXME", Content: "Something to do (TODO), and a FIXME too"},
+			},
+		},
+		{
+			testName:     "no markers",
+			markers:      []string{},
+			runner:       oscommands.NewFakeRunner(t),
+			expectedHits: nil,
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			instance := buildWorkingTreeCommands(commonDeps{runner: s.runner})
+
+			hits, err := instance.NewMarkersInStagedDiff(s.markers)
+			assert.NoError(t, err)
+			assert.Equal(t, s.expectedHits, hits)
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestWorkingTreeBeforeAndAfterFileForRename(t *testing.T) {
+	type scenario struct {
+		testName       string
+		file           *models.File
+		runner         *oscommands.FakeCmdObjRunner
+		expectedBefore string
+		expectedAfter  string
+	}
+
+	scenarios := []scenario{
+		{
+			testName:       "staged rename is derived without reloading the status",
+			file:           &models.File{Name: "new.txt", PreviousName: "old.txt", ShortStatus: "RM"},
+			runner:         oscommands.NewFakeRunner(t),
+			expectedBefore: "D ",
+			expectedAfter:  "AM",
+		},
+		{
+			testName: "other renames reload the status without renames",
+			file:     &models.File{Name: "new.txt", PreviousName: "old.txt", ShortStatus: " R"},
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git status --untracked-files=all --porcelain -z --no-renames`, " D old.txt\x00 A new.txt", nil),
+			expectedBefore: " D",
+			expectedAfter:  " A",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			instance := buildWorkingTreeCommands(commonDeps{runner: s.runner})
+
+			beforeFile, afterFile, err := instance.BeforeAndAfterFileForRename(s.file)
+			assert.NoError(t, err)
+			assert.Equal(t, "old.txt", beforeFile.Name)
+			assert.Equal(t, s.expectedBefore, beforeFile.ShortStatus)
+			assert.Equal(t, "new.txt", afterFile.Name)
+			assert.Equal(t, s.expectedAfter, afterFile.ShortStatus)
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
+
+type fakeFileNode struct {
+	path  string
+	files []*models.File
+}
+
+func (self *fakeFileNode) ForEachFile(cb func(*models.File) error) error {
+	for _, file := range self.files {
+		if err := cb(file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (self *fakeFileNode) GetFilePathsMatching(test func(*models.File) bool) []string {
+	paths := []string{}
+	for _, file := range self.files {
+		if test(file) {
+			paths = append(paths, file.Name)
+		}
+	}
+	return paths
+}
+
+func (self *fakeFileNode) GetPath() string {
+	return self.path
+}
+
+func TestWorkingTreeDiscardAllFileChangesForAddedSymlink(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("creating symlinks on windows requires extra privileges")
+	}
+
+	for _, targetIsDir := range []bool{false, true} {
+		targetIsDir := targetIsDir
+		t.Run(fmt.Sprintf("target is dir: %v", targetIsDir), func(t *testing.T) {
+			dir := t.TempDir()
+			target := filepath.Join(dir, "target")
+			if targetIsDir {
+				assert.NoError(t, os.Mkdir(target, 0o755))
+				assert.NoError(t, os.WriteFile(filepath.Join(target, "file"), []byte("content"), 0o644))
+			} else {
+				assert.NoError(t, os.WriteFile(target, []byte("content"), 0o644))
+			}
+			link := filepath.Join(dir, "link")
+			assert.NoError(t, os.Symlink(target, link))
+
+			runner := oscommands.NewFakeRunner(t)
+			instance := buildWorkingTreeCommands(commonDeps{
+				runner:     runner,
+				removeFile: oscommands.NewDummyOSCommand().RemoveFile,
+			})
+
+			file := &models.File{Name: link, ShortStatus: "??", Added: true, HasUnstagedChanges: true}
+			assert.NoError(t, instance.DiscardAllFileChanges(file))
+			runner.CheckForMissingCalls()
+
+			_, err := os.Lstat(link)
+			assert.True(t, os.IsNotExist(err))
+
+			if targetIsDir {
+				_, err = os.Stat(filepath.Join(target, "file"))
+			} else {
+				_, err = os.Stat(target)
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestWorkingTreeDiscardAllDirChanges(t *testing.T) {
+	type scenario struct {
+		testName        string
+		files           []*models.File
+		runner          *oscommands.FakeCmdObjRunner
+		expectedRemoved []string
+		expectedError   string
+	}
+
+	scenarios := []scenario{
+		{
+			testName: "mixed file states",
+			files: []*models.File{
+				{Name: "dir/modified", ShortStatus: " M", Tracked: true, HasUnstagedChanges: true},
+				{Name: "dir/staged", ShortStatus: "M ", Tracked: true, HasStagedChanges: true},
+				{Name: "dir/untracked", ShortStatus: "??", Added: true, HasUnstagedChanges: true},
+				{Name: "dir/added", ShortStatus: "A ", Added: true, HasStagedChanges: true},
+				{Name: "dir/both-deleted", ShortStatus: "DD", Tracked: true, HasMergeConflicts: true},
+				{Name: "dir/both-added", ShortStatus: "AA", Tracked: true, HasMergeConflicts: true},
+				{Name: "dir/new", PreviousName: "dir/old", ShortStatus: "R ", Tracked: true, HasStagedChanges: true},
+			},
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git checkout --ours --  "dir/both-added"`, "", nil).
+				Expect(`git add -- "dir/both-added"`, "", nil).
+				Expect(`git reset -- "dir/staged" "dir/added" "dir/both-deleted" "dir/old" "dir/new"`, "", nil).
+				Expect(`git checkout -- "dir/modified" "dir/staged" "dir/old"`, "", nil),
+			expectedRemoved: []string{"dir/untracked", "dir/added", "dir/new"},
+		},
+		{
+			testName: "unstaged changes only",
+			files: []*models.File{
+				{Name: "dir/a", ShortStatus: " M", Tracked: true, HasUnstagedChanges: true},
+				{Name: "dir/b", ShortStatus: " D", Tracked: true, HasUnstagedChanges: true},
+			},
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git checkout -- "dir/a" "dir/b"`, "", nil),
+			expectedRemoved: []string{},
+		},
+		{
+			testName: "working tree renames only reload the status once",
+			files: []*models.File{
+				{Name: "dir/new1", PreviousName: "dir/old1", ShortStatus: " R", Tracked: true, HasUnstagedChanges: true},
+				{Name: "dir/new2", PreviousName: "dir/old2", ShortStatus: " R", Tracked: true, HasUnstagedChanges: true},
+			},
+			runner: oscommands.NewFakeRunner(t).
+				Expect(
+					`git status --untracked-files=all --porcelain -z --no-renames`,
+					" D dir/old1\x00 A dir/new1\x00 D dir/old2\x00 A dir/new2",
+					nil,
+				).
+				Expect(`git checkout -- "dir/old1" "dir/old2"`, "", nil),
+			expectedRemoved: []string{"dir/new1", "dir/new2"},
+		},
+		{
+			testName: "deleted by us",
+			files: []*models.File{
+				{Name: "dir/a", ShortStatus: "DU", Tracked: true, HasMergeConflicts: true},
+			},
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git rm -- "dir/a"`, "", nil),
+			expectedRemoved: []string{},
+		},
+		{
+			testName: "error when resetting",
+			files: []*models.File{
+				{Name: "dir/a", ShortStatus: "M ", Tracked: true, HasStagedChanges: true},
+			},
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git reset -- "dir/a"`, "", errors.New("error")),
+			expectedRemoved: []string{},
+			expectedError:   "error",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			removed := []string{}
+			instance := buildWorkingTreeCommands(commonDeps{
+				runner: s.runner,
+				removeFile: func(path string) error {
+					removed = append(removed, path)
+					return nil
+				},
+			})
+
+			err := instance.DiscardAllDirChanges(&fakeFileNode{path: "dir", files: s.files})
+
+			if s.expectedError == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, s.expectedError)
+			}
+			assert.Equal(t, s.expectedRemoved, removed)
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestWorkingTreeDiscardFiles(t *testing.T) {
+	type scenario struct {
+		testName        string
+		files           []*models.File
+		runner          *oscommands.FakeCmdObjRunner
+		removeFile      func(string) error
+		expectedRemoved []string
+		expectedError   string
+	}
+
+	scenarios := []scenario{
+		{
+			testName: "mixed file states",
+			files: []*models.File{
+				{Name: "modified", ShortStatus: " M", Tracked: true, HasUnstagedChanges: true},
+				{Name: "staged", ShortStatus: "M ", Tracked: true, HasStagedChanges: true},
+				{Name: "untracked", ShortStatus: "??", Added: true, HasUnstagedChanges: true},
+				{Name: "added", ShortStatus: "A ", Added: true, HasStagedChanges: true},
+				{Name: "both-added", ShortStatus: "AA", Tracked: true, HasMergeConflicts: true},
+				{Name: "new", PreviousName: "old", ShortStatus: "R ", Tracked: true, HasStagedChanges: true},
+			},
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git checkout --ours --  "both-added"`, "", nil).
+				Expect(`git add -- "both-added"`, "", nil).
+				Expect(`git reset -- "staged" "added" "old" "new"`, "", nil).
+				Expect(`git checkout -- "modified" "staged" "old"`, "", nil),
+			expectedRemoved: []string{"untracked", "added", "new"},
+		},
+		{
+			testName: "a failed reset leaves that file alone and carries on with the others",
+			files: []*models.File{
+				{Name: "a", ShortStatus: "M ", Tracked: true, HasStagedChanges: true},
+				{Name: "b", ShortStatus: "A ", Added: true, HasStagedChanges: true},
+				{Name: "c", ShortStatus: "MM", Tracked: true, HasStagedChanges: true, HasUnstagedChanges: true},
+			},
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git reset -- "a" "b" "c"`, "", errors.New("error")).
+				Expect(`git reset -- "a"`, "", nil).
+				Expect(`git reset -- "b"`, "", errors.New("could not reset b")).
+				Expect(`git reset -- "c"`, "", nil).
+				Expect(`git checkout -- "a" "c"`, "", nil),
+			expectedRemoved: []string{},
+			expectedError:   "could not discard changes to 1 file(s):\nb: could not reset b",
+		},
+		{
+			testName: "failures are collected from every step",
+			files: []*models.File{
+				{Name: "a", ShortStatus: " M", Tracked: true, HasUnstagedChanges: true},
+				{Name: "b", ShortStatus: "??", Added: true, HasUnstagedChanges: true},
+				{Name: "c", ShortStatus: "??", Added: true, HasUnstagedChanges: true},
+			},
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git checkout -- "a"`, "", errors.New("could not restore a")),
+			removeFile: func(path string) error {
+				if path == "b" {
+					return errors.New("could not remove b")
+				}
+				return nil
+			},
+			expectedRemoved: []string{"b", "c"},
+			expectedError:   "could not discard changes to 2 file(s):\nb: could not remove b\na: could not restore a",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			removed := []string{}
+			instance := buildWorkingTreeCommands(commonDeps{
+				runner: s.runner,
+				removeFile: func(path string) error {
+					removed = append(removed, path)
+					if s.removeFile != nil {
+						return s.removeFile(path)
+					}
+					return nil
+				},
+			})
+
+			err := instance.DiscardFiles(s.files)
+
+			if s.expectedError == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, s.expectedError)
+			}
+			assert.Equal(t, s.expectedRemoved, removed)
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestWorkingTreeOpenInFileManager(t *testing.T) {
+	repoDir := t.TempDir()
+	filePath := filepath.Join(repoDir, "file.txt")
+	assert.NoError(t, os.WriteFile(filePath, []byte("content"), 0o644))
+
+	type scenario struct {
+		testName string
+		fileName string
+		runner   *oscommands.FakeCmdObjRunner
+	}
+
+	// the dummy platform is macOS
+	scenarios := []scenario{
+		{
+			testName: "file",
+			fileName: filePath,
+			runner: oscommands.NewFakeRunner(t).
+				ExpectArgs([]string{"open", "-R", filePath}, "", nil),
+		},
+		{
+			testName: "repo root",
+			fileName: "",
+			runner: oscommands.NewFakeRunner(t).
+				Expect(`git rev-parse --is-bare-repository --absolute-git-dir --git-common-dir`, "false\n"+repoDir+"/.git\n.git\n", nil).
+				Expect(`git rev-parse --show-toplevel`, repoDir+"\n", nil).
+				ExpectArgs([]string{"open", "-R", repoDir}, "", nil),
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			instance := buildWorkingTreeCommands(commonDeps{runner: s.runner, resolveRepoPaths: true})
+
+			assert.NoError(t, instance.OpenInFileManager(s.fileName))
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestWorkingTreeApplyGitignoreTemplate(t *testing.T) {
+	type scenario struct {
+		testName          string
+		gitignore         string
+		expectedGitignore string
+	}
+
+	scenarios := []scenario{
+		{
+			testName:  "empty gitignore",
+			gitignore: "",
+			expectedGitignore: "# Build output\ntarget/\n\n# Backup files from rustfmt\n**/*.rs.bk\n\n" +
+				"# Debug info on windows\n*.pdb\n",
+		},
+		{
+			testName:  "skips patterns that are already there, keeping blank lines and comments",
+			gitignore: "# Build output\ntarget/\n\n*.pdb\n",
+			expectedGitignore: "# Build output\ntarget/\n\n*.pdb\n" +
+				"# Build output\n\n# Backup files from rustfmt\n**/*.rs.bk\n\n# Debug info on windows\n",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			dir := t.TempDir()
+			wd, err := os.Getwd()
+			assert.NoError(t, err)
+			assert.NoError(t, os.Chdir(dir))
+			defer func() { assert.NoError(t, os.Chdir(wd)) }()
+
+			assert.NoError(t, os.WriteFile(".gitignore", []byte(s.gitignore), 0o644))
+
+			instance := buildWorkingTreeCommands(commonDeps{})
+			assert.NoError(t, instance.ApplyGitignoreTemplate("Rust"))
+
+			content, err := os.ReadFile(".gitignore")
+			assert.NoError(t, err)
+			assert.Equal(t, s.expectedGitignore, string(content))
+		})
+	}
+}
+
+func TestWorkingTreeUntrackAndIgnore(t *testing.T) {
+	type scenario struct {
+		testName          string
+		gitignore         string
+		rmErr             error
+		expectedGitignore string
+		expectedError     string
+	}
+
+	scenarios := []scenario{
+		{
+			testName:          "adds the file to the gitignore",
+			gitignore:         "*.log\n",
+			expectedGitignore: "*.log\nsecrets.env\n",
+		},
+		{
+			testName:          "file is already in the gitignore",
+			gitignore:         "secrets.env\n",
+			expectedGitignore: "secrets.env\n",
+		},
+		{
+			testName:          "leaves the gitignore alone if untracking fails",
+			gitignore:         "*.log\n",
+			rmErr:             errors.New("fatal: pathspec 'secrets.env' did not match any files"),
+			expectedGitignore: "*.log\n",
+			expectedError:     "fatal: pathspec 'secrets.env' did not match any files",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			dir := t.TempDir()
+			wd, err := os.Getwd()
+			assert.NoError(t, err)
+			assert.NoError(t, os.Chdir(dir))
+			defer func() { assert.NoError(t, os.Chdir(wd)) }()
+
+			assert.NoError(t, os.WriteFile(".gitignore", []byte(s.gitignore), 0o644))
+
+			runner := oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"rm", "--cached", "--force", "--", "secrets.env"}, "", s.rmErr)
+			instance := buildWorkingTreeCommands(commonDeps{runner: runner})
+
+			err = instance.UntrackAndIgnore("secrets.env")
+			if s.expectedError != "" {
+				assert.EqualError(t, err, s.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			content, err := os.ReadFile(".gitignore")
+			assert.NoError(t, err)
+			assert.Equal(t, s.expectedGitignore, string(content))
+			runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestWorkingTreeExclude(t *testing.T) {
+	// in a linked worktree, info/exclude lives in the main repo's git dir
+	commonDir := t.TempDir()
+	excludePath := filepath.Join(commonDir, "info", "exclude")
+	assert.NoError(t, os.Mkdir(filepath.Dir(excludePath), 0o755))
+	assert.NoError(t, os.WriteFile(excludePath, []byte("existing\n"), 0o644))
+
+	runner := oscommands.NewFakeRunner(t).
+		Expect(`git rev-parse --is-bare-repository --absolute-git-dir --git-common-dir`, "false\n"+commonDir+"/worktrees/feature\n"+commonDir+"\n", nil).
+		Expect(`git rev-parse --show-toplevel`, "/home/user/feature\n", nil)
+	instance := buildWorkingTreeCommands(commonDeps{runner: runner, resolveRepoPaths: true})
+
+	assert.NoError(t, instance.Exclude("toExclude"))
+	runner.CheckForMissingCalls()
+
+	content, err := os.ReadFile(excludePath)
+	assert.NoError(t, err)
+	assert.Equal(t, "existing\ntoExclude\n", string(content))
+}
+
+func TestWorkingTreeRemoveUntrackedDirFiles(t *testing.T) {
+	type scenario struct {
+		testName        string
+		nodePath        string
+		trackedFiles    []string
+		untrackedFiles  []string
+		expectedRemoved []string
+		expectedKept    []string
+	}
+
+	scenarios := []scenario{
+		{
+			testName:     "removes directories that only held untracked files",
+			nodePath:     "dir",
+			trackedFiles: []string{"dir/tracked.txt", "dir/mixed/tracked.txt"},
+			untrackedFiles: []string{
+				"dir/untracked/a.txt",
+				"dir/untracked/nested/b.txt",
+				"dir/mixed/untracked.txt",
+			},
+			expectedRemoved: []string{"dir/untracked", "dir/mixed/untracked.txt"},
+			expectedKept:    []string{"dir/tracked.txt", "dir/mixed/tracked.txt"},
+		},
+		{
+			testName:        "removes the node's own directory if it was fully untracked",
+			nodePath:        "dir/new",
+			trackedFiles:    []string{"dir/tracked.txt"},
+			untrackedFiles:  []string{"dir/new/a.txt", "dir/new/nested/b.txt"},
+			expectedRemoved: []string{"dir/new"},
+			expectedKept:    []string{"dir/tracked.txt"},
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			root := t.TempDir()
+			files := []*models.File{}
+			for _, path := range append(append([]string{}, s.trackedFiles...), s.untrackedFiles...) {
+				fullPath := filepath.Join(root, path)
+				assert.NoError(t, os.MkdirAll(filepath.Dir(fullPath), 0o755))
+				assert.NoError(t, os.WriteFile(fullPath, []byte("content"), 0o644))
+			}
+			for _, path := range s.untrackedFiles {
+				files = append(files, &models.File{Name: filepath.Join(root, path), ShortStatus: "??"})
+			}
+
+			instance := buildWorkingTreeCommands(commonDeps{})
+			node := &fakeFileNode{path: filepath.Join(root, s.nodePath), files: files}
+
+			assert.NoError(t, instance.RemoveUntrackedDirFiles(node))
+
+			for _, path := range s.expectedRemoved {
+				assert.NoFileExists(t, filepath.Join(root, path))
+				assert.NoDirExists(t, filepath.Join(root, path))
+			}
+			for _, path := range s.expectedKept {
+				assert.FileExists(t, filepath.Join(root, path))
+			}
+			assert.DirExists(t, filepath.Join(root, "dir"))
+		})
+	}
+}
+
+func TestWorkingTreeSuggestCommitMessage(t *testing.T) {
+	type scenario struct {
+		testName        string
+		numstat         string
+		expectedMessage string
+		expectedErr     string
+	}
+
+	scenarios := []scenario{
+		{
+			testName:    "no staged changes",
+			numstat:     "",
+			expectedErr: "there are no staged changes",
+		},
+		{
+			testName:        "single file",
+			numstat:         "3\t1\tpkg/commands/file.go\x00",
+			expectedMessage: "Update pkg/commands/file.go: +3/-1",
+		},
+		{
+			testName:        "files sharing a directory",
+			numstat:         "30\t5\tpkg/commands/git_commands/file.go\x008\t0\tpkg/commands/models/file.go\x002\t0\tpkg/commands/os.go\x00",
+			expectedMessage: "Update pkg/commands: +40/-5 across 3 files",
+		},
+		{
+			testName:        "purely additive",
+			numstat:         "4\t0\tdocs/a.md\x006\t0\tdocs/b.md\x00",
+			expectedMessage: "Add to docs: +10/-0 across 2 files",
+		},
+		{
+			testName:        "purely deletions",
+			numstat:         "0\t4\tdocs/a.md\x000\t6\tdocs/b.md\x00",
+			expectedMessage: "Remove from docs: +0/-10 across 2 files",
+		},
+		{
+			testName:        "no common directory",
+			numstat:         "1\t1\tREADME.md\x00-\t-\tdocs/image.png\x002\t2\tpkg/app/app.go\x00",
+			expectedMessage: "Update README.md, docs, pkg: +3/-3 across 3 files",
+		},
+		{
+			testName:        "many top-level entries",
+			numstat:         "1\t0\ta.go\x001\t0\tb.go\x001\t0\tc.go\x001\t0\td/e.go\x00",
+			expectedMessage: "Add to 4 top-level paths: +4/-0 across 4 files",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			runner := oscommands.NewFakeRunner(t).
+				Expect(`git diff --cached --numstat -z`, s.numstat, nil)
+			instance := buildWorkingTreeCommands(commonDeps{runner: runner})
+
+			message, err := instance.SuggestCommitMessage()
+			if s.expectedErr != "" {
+				assert.EqualError(t, err, s.expectedErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, s.expectedMessage, message)
+			}
+			runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestWorkingTreeMarkConflictResolved(t *testing.T) {
+	type scenario struct {
+		testName    string
+		content     string
+		expectStage bool
+		expectedErr string
+	}
+
+	scenarios := []scenario{
+		{
+			testName:    "resolved",
+			content:     "Title\n=======\n\nresolved line\n",
+			expectStage: true,
+		},
+		{
+			testName:    "conflict markers remain",
+			content:     "line one\n<<<<<<< HEAD\nours\n=======\ntheirs\n>>>>>>> other-branch\n",
+			expectedErr: "'file.txt' still has conflict markers on lines 2, 4, 6",
+		},
+		{
+			testName:    "diff3 style markers remain",
+			content:     "<<<<<<< HEAD\r\nours\r\n||||||| merged common ancestors\r\nbase\r\n=======\r\ntheirs\r\n>>>>>>> other-branch\r\n",
+			expectedErr: "'file.txt' still has conflict markers on lines 1, 3, 5, 7",
+		},
+		{
+			testName:    "stray end marker",
+			content:     "ours\n>>>>>>> other-branch\n",
+			expectedErr: "'file.txt' still has conflict markers on lines 2",
+		},
+		{
+			testName:    "markers after a line longer than 64KB",
+			content:     strings.Repeat("a", 100*1024) + "\n<<<<<<< HEAD\nours\n=======\ntheirs\n>>>>>>> other-branch",
+			expectedErr: "'file.txt' still has conflict markers on lines 2, 4, 6",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			dir := t.TempDir()
+			fileName := filepath.Join(dir, "file.txt")
+			assert.NoError(t, os.WriteFile(fileName, []byte(s.content), 0o644))
+
+			runner := oscommands.NewFakeRunner(t)
+			if s.expectStage {
+				runner.ExpectGitArgs([]string{"add", "--", fileName}, "", nil)
+			}
+			instance := buildWorkingTreeCommands(commonDeps{runner: runner})
+
+			err := instance.MarkConflictResolved(fileName)
+			if s.expectedErr != "" {
+				assert.EqualError(t, err, strings.Replace(s.expectedErr, "file.txt", fileName, 1))
+			} else {
+				assert.NoError(t, err)
+			}
+			runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestWorkingTreeWillNormalizeLineEndings(t *testing.T) {
+	type scenario struct {
+		testName     string
+		content      string
+		text         string
+		eol          string
+		autocrlf     string
+		indexContent string
+		notInIndex   bool
+		expected     bool
+	}
+
+	scenarios := []scenario{
+		{
+			testName: "no CRLFs",
+			content:  "a\nb\n",
+			expected: false,
+		},
+		{
+			testName: "marked as text",
+			content:  "a\r\nb\r\n",
+			text:     "set",
+			eol:      "unspecified",
+			expected: true,
+		},
+		{
+			testName: "marked as -text",
+			content:  "a\r\nb\r\n",
+			text:     "unset",
+			eol:      "unspecified",
+			autocrlf: "true",
+			expected: false,
+		},
+		{
+			testName: "given an eol",
+			content:  "a\r\nb\r\n",
+			text:     "unspecified",
+			eol:      "crlf",
+			expected: true,
+		},
+		{
+			testName:     "text=auto",
+			content:      "a\r\nb\r\n",
+			text:         "auto",
+			eol:          "unspecified",
+			indexContent: "a\nb\n",
+			expected:     true,
+		},
+		{
+			testName:     "text=auto with CRLFs already in the index",
+			content:      "a\r\nb\r\nc\r\n",
+			text:         "auto",
+			eol:          "unspecified",
+			indexContent: "a\r\nb\r\n",
+			expected:     false,
+		},
+		{
+			testName: "text=auto on a binary file",
+			content:  "a\r\n\x00b\r\n",
+			text:     "auto",
+			eol:      "unspecified",
+			expected: false,
+		},
+		{
+			testName:   "core.autocrlf=input on an untracked file",
+			content:    "a\r\nb\r\n",
+			text:       "unspecified",
+			eol:        "unspecified",
+			autocrlf:   "input",
+			notInIndex: true,
+			expected:   true,
+		},
+		{
+			testName: "no attributes or autocrlf",
+			content:  "a\r\nb\r\n",
+			text:     "unspecified",
+			eol:      "unspecified",
+			autocrlf: "false",
+			expected: false,
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			dir := t.TempDir()
+			fileName := filepath.Join(dir, "file.txt")
+			assert.NoError(t, os.WriteFile(fileName, []byte(s.content), 0o644))
+
+			runner := oscommands.NewFakeRunner(t)
+			if s.text != "" {
+				runner.ExpectGitArgs([]string{"check-attr", "-z", "text", "eol", "--", fileName},
+					fmt.Sprintf("%s\x00text\x00%s\x00%s\x00eol\x00%s\x00", fileName, s.text, fileName, s.eol), nil)
+			}
+			if s.indexContent != "" {
+				runner.ExpectGitArgs([]string{"cat-file", "blob", ":" + fileName}, s.indexContent, nil)
+			}
+			if s.notInIndex {
+				runner.ExpectGitArgs([]string{"cat-file", "blob", ":" + fileName}, "", errors.New("fatal: path not in the index"))
+			}
+			instance := buildWorkingTreeCommands(commonDeps{
+				runner:    runner,
+				gitConfig: git_config.NewFakeGitConfig(map[string]string{"core.autocrlf": s.autocrlf}),
+			})
+
+			result, err := instance.WillNormalizeLineEndings(fileName)
+			assert.NoError(t, err)
+			assert.Equal(t, s.expected, result)
+			runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestWorkingTreeSetExecutable(t *testing.T) {
+	type scenario struct {
+		testName     string
+		mode         os.FileMode
+		executable   bool
+		gitArgs      []string
+		gitErr       error
+		expectedMode os.FileMode
+		expectedErr  string
+	}
+
+	scenarios := []scenario{
+		{
+			testName:     "make executable",
+			mode:         0o644,
+			executable:   true,
+			gitArgs:      []string{"update-index", "--chmod=+x", "--"},
+			expectedMode: 0o755,
+		},
+		{
+			testName:     "make non-executable",
+			mode:         0o755,
+			executable:   false,
+			gitArgs:      []string{"update-index", "--chmod=-x", "--"},
+			expectedMode: 0o644,
+		},
+		{
+			testName:     "leaves the file alone if updating the index fails",
+			mode:         0o644,
+			executable:   true,
+			gitArgs:      []string{"update-index", "--chmod=+x", "--"},
+			gitErr:       errors.New("fatal: Unable to mark file script.sh"),
+			expectedMode: 0o644,
+			expectedErr:  "fatal: Unable to mark file script.sh",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "script.sh")
+			assert.NoError(t, os.WriteFile(path, []byte("echo hi"), s.mode))
+			assert.NoError(t, os.Chmod(path, s.mode))
+
+			runner := oscommands.NewFakeRunner(t).
+				ExpectGitArgs(append(s.gitArgs, path), "", s.gitErr)
+			instance := buildWorkingTreeCommands(commonDeps{runner: runner})
+
+			err := instance.SetExecutable(path, s.executable)
+			if s.expectedErr != "" {
+				assert.EqualError(t, err, s.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			info, err := os.Stat(path)
+			assert.NoError(t, err)
+			assert.Equal(t, s.expectedMode, info.Mode().Perm())
+			runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestWorkingTreeCommandsWithContext(t *testing.T) {
+	type scenario struct {
+		testName        string
+		expectedGitArgs []string
+		run             func(ctx context.Context, instance *WorkingTreeCommands) error
+	}
+
+	scenarios := []scenario{
+		{
+			testName:        "RemoveUntrackedFilesContext",
+			expectedGitArgs: []string{"clean", "-fd"},
+			run: func(ctx context.Context, instance *WorkingTreeCommands) error {
+				return instance.RemoveUntrackedFilesContext(ctx)
+			},
+		},
+		{
+			testName:        "ResetWorktreeContext",
+			expectedGitArgs: []string{"reset", "--hard", "HEAD"},
+			run: func(ctx context.Context, instance *WorkingTreeCommands) error {
+				return instance.ResetWorktreeContext(ctx, "HEAD", RESET_MODE_HARD)
+			},
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			runner := oscommands.NewFakeRunner(t).
+				ExpectFunc(func(cmdObj oscommands.ICmdObj) (string, error) {
+					assert.Equal(t, s.expectedGitArgs, cmdObj.GetCmd().Args[1:])
+					assert.Equal(t, ctx, cmdObj.Context())
+					return "", nil
+				})
+			instance := buildWorkingTreeCommands(commonDeps{runner: runner})
+
+			assert.NoError(t, s.run(ctx, instance))
+			runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestWorkingTreePreviewStageAll(t *testing.T) {
+	const mode = "100644 100644 100644"
+	const hashes = "de980441c3ab03a8c07dda1ad27b8a11f39deb1e 53a4a4a4d1f3e1ef5b9dc2ef2d0dc0fae3e0d0bb"
+
+	output := "1 .M N... " + mode + " " + hashes + " modified.txt\x00" +
+		// fully staged already, so there's nothing left to stage
+		"1 M. N... " + mode + " " + hashes + " staged.txt\x00" +
+		"1 .D N... " + mode + " " + hashes + " deleted.txt\x00" +
+		// changes inside a submodule can't be staged from the outer repo
+		"1 .M S.M. 160000 160000 160000 " + hashes + " dirty-sub\x00" +
+		"1 .M SC.. 160000 160000 160000 " + hashes + " moved-sub\x00" +
+		"u UU N... " + mode + " 100644 " + hashes + " " + hashes[:40] + " conflict.txt\x00" +
+		"? dir/untracked.txt\x00"
+
+	runner := oscommands.NewFakeRunner(t).
+		ExpectGitArgs([]string{"status", "--untracked-files=all", "--porcelain=v2", "-z", "--no-renames"}, output, nil)
+	instance := buildWorkingTreeCommands(commonDeps{runner: runner})
+
+	files, err := instance.PreviewStageAll()
+	assert.NoError(t, err)
+	assert.Equal(t,
+		[]string{"modified.txt", "deleted.txt", "moved-sub", "conflict.txt", "dir/untracked.txt"},
+		lo.Map(files, func(file *models.File, _ int) string { return file.Name }),
+	)
+	runner.CheckForMissingCalls()
+}
+
+func TestWorkingTreeRenormalize(t *testing.T) {
+	type scenario struct {
+		testName    string
+		pathspec    string
+		expectedCmd string
+	}
+
+	scenarios := []scenario{
+		{
+			testName:    "whole repo",
+			pathspec:    "",
+			expectedCmd: `git add --renormalize -- .`,
+		},
+		{
+			testName:    "pathspec",
+			pathspec:    "src/*.txt",
+			expectedCmd: `git add --renormalize -- "src/*.txt"`,
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			runner := oscommands.NewFakeRunner(t).
+				Expect(s.expectedCmd, "", nil)
+			instance := buildWorkingTreeCommands(commonDeps{runner: runner})
+
+			assert.NoError(t, instance.Renormalize(s.pathspec))
+			runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestWorkingTreeExtractConflictVersions(t *testing.T) {
+	const lsFilesCmd = `git ls-files --unmerged -z -- "src/app.go"`
+
+	type scenario struct {
+		testName         string
+		runner           *oscommands.FakeCmdObjRunner
+		expectedContents [3]string
+		expectedFiles    [3]string
+		expectedErr      string
+	}
+
+	scenarios := []scenario{
+		{
+			testName: "both modified",
+			runner: oscommands.NewFakeRunner(t).
+				Expect(lsFilesCmd, "100644 aaa 1\tsrc/app.go\x00100644 bbb 2\tsrc/app.go\x00100644 ccc 3\tsrc/app.go\x00", nil).
+				Expect(`git cat-file blob ":1:src/app.go"`, "base\n", nil).
+				Expect(`git cat-file blob ":2:src/app.go"`, "ours\n", nil).
+				Expect(`git cat-file blob ":3:src/app.go"`, "theirs\n", nil),
+			expectedContents: [3]string{"base\n", "ours\n", "theirs\n"},
+			expectedFiles:    [3]string{"app_BASE.go", "app_OURS.go", "app_THEIRS.go"},
+		},
+		{
+			testName: "both added, so there's no base",
+			runner: oscommands.NewFakeRunner(t).
+				Expect(lsFilesCmd, "100644 bbb 2\tsrc/app.go\x00100644 ccc 3\tsrc/app.go\x00", nil).
+				Expect(`git cat-file blob ":2:src/app.go"`, "ours\n", nil).
+				Expect(`git cat-file blob ":3:src/app.go"`, "theirs\n", nil),
+			expectedContents: [3]string{"", "ours\n", "theirs\n"},
+			expectedFiles:    [3]string{"", "app_OURS.go", "app_THEIRS.go"},
+		},
+		{
+			testName: "no conflicts",
+			runner: oscommands.NewFakeRunner(t).
+				Expect(lsFilesCmd, "", nil),
+			expectedErr: "'src/app.go' has no merge conflicts",
+		},
+		{
+			testName: "reading a version fails",
+			runner: oscommands.NewFakeRunner(t).
+				Expect(lsFilesCmd, "100644 bbb 2\tsrc/app.go\x00100644 ccc 3\tsrc/app.go\x00", nil).
+				Expect(`git cat-file blob ":2:src/app.go"`, "", errors.New("fatal: bad object")),
+			expectedErr: "fatal: bad object",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			instance := buildWorkingTreeCommands(commonDeps{runner: s.runner})
+
+			base, ours, theirs, err := instance.ExtractConflictVersions("src/app.go")
+			if s.expectedErr != "" {
+				assert.EqualError(t, err, s.expectedErr)
+				s.runner.CheckForMissingCalls()
+				return
+			}
+			assert.NoError(t, err)
+			defer os.RemoveAll(filepath.Dir(ours))
+
+			for i, path := range []string{base, ours, theirs} {
+				if s.expectedFiles[i] == "" {
+					assert.Equal(t, "", path)
+					continue
+				}
+
+				assert.Equal(t, s.expectedFiles[i], filepath.Base(path))
+				content, err := os.ReadFile(path)
+				assert.NoError(t, err)
+				assert.Equal(t, s.expectedContents[i], string(content))
+			}
+			s.runner.CheckForMissingCalls()
 		})
 	}
 }
@@ -6,7 +6,6 @@ import (
 	"testing"
 	"time"
 
-	"github.com/go-errors/errors"
 	gogit "github.com/jesseduffield/go-git/v5"
 	"github.com/jesseduffield/lazygit/pkg/commands/git_commands"
 	"github.com/jesseduffield/lazygit/pkg/commands/git_config"
@@ -227,79 +226,3 @@ func TestNewGitCommand(t *testing.T) {
 		})
 	}
 }
-
-func TestFindDotGitDir(t *testing.T) {
-	type scenario struct {
-		testName string
-		stat     func(string) (os.FileInfo, error)
-		readFile func(filename string) ([]byte, error)
-		test     func(string, error)
-	}
-
-	scenarios := []scenario{
-		{
-			".git is a directory",
-			func(dotGit string) (os.FileInfo, error) {
-				assert.Equal(t, ".git", dotGit)
-				return os.Stat("testdata/a_dir")
-			},
-			func(dotGit string) ([]byte, error) {
-				assert.Fail(t, "readFile should not be called if .git is a directory")
-				return nil, nil
-			},
-			func(gitDir string, err error) {
-				assert.NoError(t, err)
-				assert.Equal(t, ".git", gitDir)
-			},
-		},
-		{
-			".git is a file",
-			func(dotGit string) (os.FileInfo, error) {
-				assert.Equal(t, ".git", dotGit)
-				return os.Stat("testdata/a_file")
-			},
-			func(dotGit string) ([]byte, error) {
-				assert.Equal(t, ".git", dotGit)
-				return []byte("gitdir: blah\n"), nil
-			},
-			func(gitDir string, err error) {
-				assert.NoError(t, err)
-				assert.Equal(t, "blah", gitDir)
-			},
-		},
-		{
-			"os.Stat returns an error",
-			func(dotGit string) (os.FileInfo, error) {
-				assert.Equal(t, ".git", dotGit)
-				return nil, errors.New("error")
-			},
-			func(dotGit string) ([]byte, error) {
-				assert.Fail(t, "readFile should not be called os.Stat returns an error")
-				return nil, nil
-			},
-			func(gitDir string, err error) {
-				assert.Error(t, err)
-			},
-		},
-		{
-			"readFile returns an error",
-			func(dotGit string) (os.FileInfo, error) {
-				assert.Equal(t, ".git", dotGit)
-				return os.Stat("testdata/a_file")
-			},
-			func(dotGit string) ([]byte, error) {
-				return nil, errors.New("error")
-			},
-			func(gitDir string, err error) {
-				assert.Error(t, err)
-			},
-		},
-	}
-
-	for _, s := range scenarios {
-		s := s
-		t.Run(s.testName, func(t *testing.T) {
-			s.test(findDotGitDir(s.stat, s.readFile))
-		})
-	}
-}
@@ -0,0 +1,12 @@
+package models
+
+// BlameLine is a single line of a file along with the commit that last changed it
+type BlameLine struct {
+	// all zeroes if the line has not been committed yet
+	Sha           string
+	AuthorName    string
+	UnixTimestamp int64
+	// 1-based line number in the current version of the file
+	LineNumber int
+	Content    string
+}
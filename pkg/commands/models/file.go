@@ -20,6 +20,42 @@ type File struct {
 	DisplayString           string
 	Type                    string // one of 'file', 'directory', and 'other'
 	ShortStatus             string // e.g. 'AD', ' A', 'M ', '??'
+
+	// only set if the file is a submodule and the status was loaded with
+	// IncludeSubmoduleStatuses or porcelain v2. Porcelain v2 can't tell us
+	// whether the submodule is uninitialized.
+	SubmoduleStatus *SubmoduleStatus
+
+	// true if the repo is a sparse checkout and the file is outside of it
+	SparseExcluded bool
+
+	// true if git ignores the file. Only ignored files have this, and they're
+	// only loaded if asked for.
+	Ignored bool
+
+	// how similar (0-100) a renamed or copied file is to the file it came from.
+	// Only set when the status was loaded with porcelain v2.
+	RenameScore int
+
+	// the number of lines added and deleted across the file's staged and
+	// unstaged changes. Only set when the status was loaded with diff stats.
+	LinesAdded   int
+	LinesDeleted int
+	// true if the file is tracked and its changes only add lines. Only set when
+	// the status was loaded with diff stats.
+	PurelyAdditive bool
+
+	// true if the file's mode (e.g. whether it's executable) differs from HEAD,
+	// whether or not its content has changed too. Only set when the status was
+	// loaded with mode changes.
+	ModeChanged bool
+	// the file's mode in the working tree, e.g. '100755'. Only set if
+	// ModeChanged is.
+	NewMode string
+
+	// true if this is an untracked directory which git has listed as a whole
+	// rather than file by file, in which case Name ends in a slash
+	IsCollapsedDir bool
 }
 
 // sometimes we need to deal with either a node (which contains a file) or an actual file
@@ -105,6 +141,7 @@ type StatusFields struct {
 	Added                   bool
 	HasMergeConflicts       bool
 	HasInlineMergeConflicts bool
+	Ignored                 bool
 	ShortStatus             string
 }
 
@@ -118,6 +155,7 @@ func SetStatusFields(file *File, shortStatus string) {
 	file.Added = derived.Added
 	file.HasMergeConflicts = derived.HasMergeConflicts
 	file.HasInlineMergeConflicts = derived.HasInlineMergeConflicts
+	file.Ignored = derived.Ignored
 	file.ShortStatus = derived.ShortStatus
 }
 
@@ -125,19 +163,21 @@ func SetStatusFields(file *File, shortStatus string) {
 func deriveStatusFields(shortStatus string) StatusFields {
 	stagedChange := shortStatus[0:1]
 	unstagedChange := shortStatus[1:2]
-	tracked := !lo.Contains([]string{"??", "A ", "AM"}, shortStatus)
-	hasStagedChanges := !lo.Contains([]string{" ", "U", "?"}, stagedChange)
+	ignored := shortStatus == "!!"
+	tracked := !ignored && !lo.Contains([]string{"??", "A ", "AM"}, shortStatus)
+	hasStagedChanges := !lo.Contains([]string{" ", "U", "?", "!"}, stagedChange)
 	hasInlineMergeConflicts := lo.Contains([]string{"UU", "AA"}, shortStatus)
 	hasMergeConflicts := hasInlineMergeConflicts || lo.Contains([]string{"DD", "AU", "UA", "UD", "DU"}, shortStatus)
 
 	return StatusFields{
 		HasStagedChanges:        hasStagedChanges,
-		HasUnstagedChanges:      unstagedChange != " ",
+		HasUnstagedChanges:      unstagedChange != " " && !ignored,
 		Tracked:                 tracked,
 		Deleted:                 unstagedChange == "D" || stagedChange == "D",
-		Added:                   unstagedChange == "A" || !tracked,
+		Added:                   unstagedChange == "A" || (!tracked && !ignored),
 		HasMergeConflicts:       hasMergeConflicts,
 		HasInlineMergeConflicts: hasInlineMergeConflicts,
+		Ignored:                 ignored,
 		ShortStatus:             shortStatus,
 	}
 }
@@ -0,0 +1,11 @@
+package models
+
+// MarkerHit is a line added in a diff that contains a marker like TODO or FIXME
+type MarkerHit struct {
+	FileName string
+	// 1-based line number in the new version of the file
+	LineNumber int
+	// the marker that was found on the line
+	Marker  string
+	Content string
+}
@@ -0,0 +1,28 @@
+package models
+
+import "github.com/fsmiamoto/git-todo-parser/todo"
+
+// RebaseTodoEntry is a single line of the git-rebase-todo file of an
+// in-progress interactive rebase
+type RebaseTodoEntry struct {
+	Action todo.TodoCommand
+	Sha    string
+	// git ignores this when reading the todo but it's nice to show the user
+	Subject string
+
+	// only set for 'exec' entries
+	ExecCommand string
+	// only set for 'label', 'reset' and 'merge' entries
+	Label string
+	// only set for 'update-ref' entries
+	Ref string
+	// '-C' or '-c' for 'merge' and 'fixup' entries that have it
+	Flag string
+	// only set for comment lines
+	Comment string
+}
+
+// returns true if the entry refers to a commit i.e. it's a pick, reword, edit etc
+func (self *RebaseTodoEntry) IsCommit() bool {
+	return self.Sha != "" && self.Action != todo.Merge
+}
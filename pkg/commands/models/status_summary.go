@@ -0,0 +1,38 @@
+package models
+
+// StatusSummary counts the files in the working tree by the kind of change
+// they have. A file can fall into more than one category e.g. a file with
+// both staged and unstaged changes counts as staged and as modified. Files
+// with merge conflicts are only counted as conflicted.
+type StatusSummary struct {
+	Staged     int
+	Modified   int
+	Untracked  int
+	Conflicted int
+}
+
+// Add counts the given file towards the summary
+func (self *StatusSummary) Add(file *File) {
+	// ignored files aren't changes
+	if file.Ignored {
+		return
+	}
+
+	if file.HasMergeConflicts {
+		self.Conflicted++
+		return
+	}
+
+	// not using file.Tracked here because that's also false for newly added files
+	if file.ShortStatus == "??" {
+		self.Untracked++
+		return
+	}
+
+	if file.HasStagedChanges {
+		self.Staged++
+	}
+	if file.HasUnstagedChanges {
+		self.Modified++
+	}
+}
@@ -17,3 +17,17 @@ func (r *SubmoduleConfig) ID() string {
 func (r *SubmoduleConfig) Description() string {
 	return r.RefName()
 }
+
+// SubmoduleStatus describes how a submodule's working tree differs from what
+// the parent repo has recorded. More than one of these can be true at once.
+type SubmoduleStatus struct {
+	// the submodule has a different commit checked out than the one recorded
+	// in the parent repo i.e. its pointer has moved
+	NewCommits bool
+	// the submodule has changes to its tracked files
+	ModifiedContent bool
+	// the submodule has untracked files
+	UntrackedContent bool
+	// the submodule has not been cloned yet
+	Uninitialized bool
+}
@@ -0,0 +1,137 @@
+package oscommands
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/jesseduffield/lazygit/pkg/utils"
+)
+
+// askpassServer answers credential requests from lazygit instances that git has
+// invoked as its GIT_ASKPASS/SSH_ASKPASS helper (see guiIO.askpassEnvVarsFn).
+// Each request is a single connection on a unix socket: the helper sends the
+// prompt on one line and we reply with the credential before closing.
+type askpassServer struct {
+	dir      string
+	listener net.Listener
+	prompts  map[*regexp.Regexp]CredentialType
+
+	promptUserForCredential func(CredentialType) string
+}
+
+func startAskpassServer(promptUserForCredential func(CredentialType) string) (*askpassServer, error) {
+	dir, err := os.MkdirTemp("", "lazygit-askpass-")
+	if err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen("unix", filepath.Join(dir, "socket"))
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		return nil, err
+	}
+
+	server := &askpassServer{
+		dir:                     dir,
+		listener:                listener,
+		prompts:                 compileCredentialPrompts(),
+		promptUserForCredential: promptUserForCredential,
+	}
+
+	go utils.Safe(server.serve)
+
+	return server, nil
+}
+
+func (self *askpassServer) socketPath() string {
+	return self.listener.Addr().String()
+}
+
+// the env vars that make git (and ssh, when git uses it) ask us for credentials
+func (self *askpassServer) envVars(askpassEnvVarsFn func(socketPath string) []string) []string {
+	lazygitPath := GetLazygitPath()
+
+	return append(
+		[]string{
+			"GIT_ASKPASS=" + lazygitPath,
+			"SSH_ASKPASS=" + lazygitPath,
+			// without this, ssh only uses SSH_ASKPASS when there's no terminal
+			"SSH_ASKPASS_REQUIRE=force",
+			"GIT_TERMINAL_PROMPT=0",
+		},
+		askpassEnvVarsFn(self.socketPath())...,
+	)
+}
+
+func (self *askpassServer) serve() {
+	for {
+		conn, err := self.listener.Accept()
+		if err != nil {
+			// the listener has been closed
+			return
+		}
+
+		self.handle(conn)
+	}
+}
+
+func (self *askpassServer) handle(conn net.Conn) {
+	defer conn.Close()
+
+	prompt, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return
+	}
+
+	askFor, ok := self.credentialTypeForPrompt(strings.TrimSpace(prompt))
+	if !ok {
+		// we don't know what we're being asked (e.g. ssh asking whether to trust
+		// a host), so we send nothing back, which git treats as a refusal
+		return
+	}
+
+	_, _ = conn.Write([]byte(self.promptUserForCredential(askFor)))
+}
+
+func (self *askpassServer) credentialTypeForPrompt(prompt string) (CredentialType, bool) {
+	for pattern, askFor := range self.prompts {
+		if pattern.MatchString(prompt) {
+			return askFor, true
+		}
+	}
+
+	return 0, false
+}
+
+func (self *askpassServer) close() error {
+	err := self.listener.Close()
+	_ = os.RemoveAll(self.dir)
+	return err
+}
+
+// runWithAskpass runs a command which may ask for credentials, having git ask
+// for them via lazygit as its askpass helper rather than on a terminal
+func (self *cmdObjRunner) runWithAskpass(
+	cmdObj ICmdObj,
+	promptUserForCredential func(CredentialType) string,
+) error {
+	server, err := startAskpassServer(promptUserForCredential)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := server.close(); closeErr != nil {
+			self.log.Error(closeErr)
+		}
+	}()
+
+	// setting the output to english so we can parse the prompts we're sent
+	cmdObj.AddEnvVars("LANG=en_US.UTF-8", "LC_ALL=en_US.UTF-8")
+	cmdObj.AddEnvVars(server.envVars(self.guiIO.askpassEnvVarsFn)...)
+
+	return self.runAndStream(cmdObj)
+}
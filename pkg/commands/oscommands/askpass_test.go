@@ -0,0 +1,80 @@
+package oscommands
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAskpassServer(t *testing.T) {
+	scenarios := []struct {
+		name           string
+		prompt         string
+		expectedAskFor CredentialType
+		expectedAnswer string
+	}{
+		{
+			name:           "https username",
+			prompt:         "Username for 'https://github.com': ",
+			expectedAskFor: Username,
+			expectedAnswer: "username\n",
+		},
+		{
+			name:           "https password",
+			prompt:         "Password for 'https://user@github.com': ",
+			expectedAskFor: Password,
+			expectedAnswer: "password\n",
+		},
+		{
+			name:           "ssh key passphrase",
+			prompt:         "Enter passphrase for key '/home/user/.ssh/id_ed25519': ",
+			expectedAskFor: Passphrase,
+			expectedAnswer: "passphrase\n",
+		},
+		{
+			name:           "unknown prompt",
+			prompt:         "Are you sure you want to continue connecting (yes/no/[fingerprint])? ",
+			expectedAnswer: "",
+		},
+	}
+
+	for _, scenario := range scenarios {
+		scenario := scenario
+		t.Run(scenario.name, func(t *testing.T) {
+			askedFor := []CredentialType{}
+			server, err := startAskpassServer(func(askFor CredentialType) string {
+				askedFor = append(askedFor, askFor)
+				switch askFor {
+				case Username:
+					return "username\n"
+				case Password:
+					return "password\n"
+				default:
+					return "passphrase\n"
+				}
+			})
+			assert.NoError(t, err)
+			defer server.close()
+
+			conn, err := net.Dial("unix", server.socketPath())
+			assert.NoError(t, err)
+			defer conn.Close()
+
+			_, err = fmt.Fprintln(conn, scenario.prompt)
+			assert.NoError(t, err)
+
+			answer, err := io.ReadAll(conn)
+			assert.NoError(t, err)
+			assert.Equal(t, scenario.expectedAnswer, string(answer))
+
+			if scenario.expectedAnswer == "" {
+				assert.Empty(t, askedFor)
+			} else {
+				assert.Equal(t, []CredentialType{scenario.expectedAskFor}, askedFor)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package oscommands
 
 import (
+	"context"
 	"os/exec"
 
 	"github.com/sasha-s/go-deadlock"
@@ -51,12 +52,27 @@ type ICmdObj interface {
 	// returns true if IgnoreEmptyError() was called
 	ShouldIgnoreEmptyError() bool
 
+	// by default RunAndProcessLines ignores how the command exited, because
+	// e.g. 'git log' fails in a repo without any commits and we'd rather show
+	// nothing than an error. If you call this, RunAndProcessLines returns an
+	// error if the command fails, unless the callback asked us to stop it
+	CheckExitStatus() ICmdObj
+	// returns true if CheckExitStatus() was called
+	ShouldCheckExitStatus() bool
+
 	PromptOnCredentialRequest() ICmdObj
 	FailOnCredentialRequest() ICmdObj
 
 	WithMutex(mutex *deadlock.Mutex) ICmdObj
 	Mutex() *deadlock.Mutex
 
+	// if the context is cancelled while the command is running, we kill the
+	// command and return the context's error, so that e.g. a wedged git command
+	// doesn't hold things up once we no longer care about its result
+	WithContext(ctx context.Context) ICmdObj
+	// returns nil if WithContext() wasn't called
+	Context() context.Context
+
 	GetCredentialStrategy() CredentialStrategy
 }
 
@@ -75,11 +91,17 @@ type CmdObj struct {
 	// see IgnoreEmptyError()
 	ignoreEmptyError bool
 
+	// see CheckExitStatus()
+	checkExitStatus bool
+
 	// if set to true, it means we might be asked to enter a username/password by this command.
 	credentialStrategy CredentialStrategy
 
 	// can be set so that we don't run certain commands simultaneously
 	mutex *deadlock.Mutex
+
+	// see WithContext()
+	ctx context.Context
 }
 
 type CredentialStrategy int
@@ -142,6 +164,16 @@ func (self *CmdObj) IgnoreEmptyError() ICmdObj {
 	return self
 }
 
+func (self *CmdObj) CheckExitStatus() ICmdObj {
+	self.checkExitStatus = true
+
+	return self
+}
+
+func (self *CmdObj) ShouldCheckExitStatus() bool {
+	return self.checkExitStatus
+}
+
 func (self *CmdObj) Mutex() *deadlock.Mutex {
 	return self.mutex
 }
@@ -152,6 +184,16 @@ func (self *CmdObj) WithMutex(mutex *deadlock.Mutex) ICmdObj {
 	return self
 }
 
+func (self *CmdObj) WithContext(ctx context.Context) ICmdObj {
+	self.ctx = ctx
+
+	return self
+}
+
+func (self *CmdObj) Context() context.Context {
+	return self.ctx
+}
+
 func (self *CmdObj) ShouldIgnoreEmptyError() bool {
 	return self.ignoreEmptyError
 }
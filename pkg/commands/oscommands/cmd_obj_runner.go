@@ -28,9 +28,80 @@ const (
 	PIN
 )
 
+type CredentialErrorKind int
+
+const (
+	// git needed a username or password but had no way to ask for one
+	CREDENTIALS_REQUIRED CredentialErrorKind = iota
+	// the remote rejected the username/password or token we gave it
+	AUTHENTICATION_FAILED
+	// the remote rejected our SSH key, or we don't trust the remote's host key
+	SSH_AUTHENTICATION_FAILED
+)
+
+// CredentialError is returned when a command fails because of missing or
+// rejected credentials, so that callers can offer something more helpful than
+// git's raw output. Its message is that output, unchanged.
+type CredentialError struct {
+	Kind   CredentialErrorKind
+	Output string
+}
+
+func (self *CredentialError) Error() string {
+	return self.Output
+}
+
+// failures that have nothing to do with credentials, even though the output
+// can look like it: e.g. ssh ends with 'Could not read from remote repository'
+// whatever went wrong, and a remote may say it can't find a repo that we're
+// not allowed to see
+var nonCredentialErrorPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)repository not found`),
+	regexp.MustCompile(`does not appear to be a git repository`),
+	regexp.MustCompile(`Could not resolve host`),
+}
+
+// these are checked in order, so the patterns for a specific failure come
+// before the generic ones for a credential prompt, which git may have echoed
+// before the failure
+var credentialErrorPatterns = []struct {
+	pattern *regexp.Regexp
+	kind    CredentialErrorKind
+}{
+	{regexp.MustCompile(`Authentication failed for`), AUTHENTICATION_FAILED},
+	{regexp.MustCompile(`Invalid username or password`), AUTHENTICATION_FAILED},
+	{regexp.MustCompile(`Permission denied \(publickey`), SSH_AUTHENTICATION_FAILED},
+	{regexp.MustCompile(`Host key verification failed`), SSH_AUTHENTICATION_FAILED},
+	{regexp.MustCompile(`could not read (Username|Password) for`), CREDENTIALS_REQUIRED},
+	{regexp.MustCompile(`terminal prompts disabled`), CREDENTIALS_REQUIRED},
+	{regexp.MustCompile(`(Username|Password)\s*for\s*'.+':`), CREDENTIALS_REQUIRED},
+}
+
+// returns an error for the given output of a failed command, which will be a
+// *CredentialError if the output tells us the failure was down to credentials
+func errorFromCommandOutput(output string) error {
+	for _, pattern := range nonCredentialErrorPatterns {
+		if pattern.MatchString(output) {
+			return errors.New(output)
+		}
+	}
+
+	for _, p := range credentialErrorPatterns {
+		if p.pattern.MatchString(output) {
+			return &CredentialError{Kind: p.kind, Output: output}
+		}
+	}
+
+	return errors.New(output)
+}
+
 type cmdObjRunner struct {
 	log   *logrus.Entry
 	guiIO *guiIO
+
+	// if this returns true, commands that may ask for credentials get them via
+	// lazygit as git's askpass helper rather than via a pseudo-terminal
+	useAskpassFn func() bool
 }
 
 var _ ICmdObjRunner = &cmdObjRunner{}
@@ -110,7 +181,16 @@ func (self *cmdObjRunner) RunWithOutputAux(cmdObj ICmdObj) (string, error) {
 		self.logCmdObj(cmdObj)
 	}
 
-	output, err := sanitisedCommandOutput(cmdObj.GetCmd().CombinedOutput())
+	var outputBuffer bytes.Buffer
+	cmd := cmdObj.GetCmd()
+	cmd.Stdout = &outputBuffer
+	cmd.Stderr = &outputBuffer
+	err := runCmd(cmdObj)
+	if isCancellation(cmdObj, err) {
+		return "", err
+	}
+
+	output, err := sanitisedCommandOutput(outputBuffer.Bytes(), err)
 	if err != nil {
 		self.log.WithField("command", cmdObj.ToString()).Error(output)
 	}
@@ -128,7 +208,10 @@ func (self *cmdObjRunner) RunWithOutputsAux(cmdObj ICmdObj) (string, string, err
 	cmd := cmdObj.GetCmd()
 	cmd.Stdout = &outBuffer
 	cmd.Stderr = &errBuffer
-	err := cmd.Run()
+	err := runCmd(cmdObj)
+	if isCancellation(cmdObj, err) {
+		return "", "", err
+	}
 
 	stdout := outBuffer.String()
 	stderr, err := sanitisedCommandOutput(errBuffer.Bytes(), err)
@@ -158,30 +241,119 @@ func (self *cmdObjRunner) RunAndProcessLines(cmdObj ICmdObj, onLine func(line st
 	if err != nil {
 		return err
 	}
+	var errBuffer bytes.Buffer
+	if cmdObj.ShouldCheckExitStatus() {
+		cmd.Stderr = &errBuffer
+	}
+
+	if err := contextErr(cmdObj); err != nil {
+		return err
+	}
 
 	scanner := bufio.NewScanner(stdoutPipe)
 	scanner.Split(bufio.ScanLines)
 	if err := cmd.Start(); err != nil {
 		return err
 	}
+	stopWatching := killOnCancel(cmdObj)
 
+	stopped := false
 	for scanner.Scan() {
 		line := scanner.Text()
 		stop, err := onLine(line)
 		if err != nil {
+			stopWatching()
 			return err
 		}
 		if stop {
 			_ = Kill(cmd)
+			stopped = true
 			break
 		}
 	}
 
-	_ = cmd.Wait()
+	err = cmd.Wait()
+
+	if killed := stopWatching(); killed {
+		return cmdObj.Context().Err()
+	}
+
+	// if we killed the command ourselves then it failing is no surprise
+	if stopped || !cmdObj.ShouldCheckExitStatus() {
+		return nil
+	}
+
+	_, err = sanitisedCommandOutput(errBuffer.Bytes(), err)
+	if err != nil {
+		self.log.WithField("command", cmdObj.ToString()).Error(err.Error())
+	}
+	return err
+}
+
+// returns the error of the command's context, if it has one and it's been
+// cancelled
+func contextErr(cmdObj ICmdObj) error {
+	if ctx := cmdObj.Context(); ctx != nil {
+		return ctx.Err()
+	}
 
 	return nil
 }
 
+func isCancellation(cmdObj ICmdObj, err error) bool {
+	return err != nil && err == contextErr(cmdObj)
+}
+
+// runs the command, killing it if its context is cancelled before it finishes,
+// in which case we return the context's error
+func runCmd(cmdObj ICmdObj) error {
+	if err := contextErr(cmdObj); err != nil {
+		return err
+	}
+
+	cmd := cmdObj.GetCmd()
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	stopWatching := killOnCancel(cmdObj)
+
+	err := cmd.Wait()
+
+	if killed := stopWatching(); killed {
+		return cmdObj.Context().Err()
+	}
+
+	return err
+}
+
+// must be called once the command has started. Until the returned function is
+// called, we kill the command if its context is cancelled. The returned
+// function tells us whether we did.
+func killOnCancel(cmdObj ICmdObj) func() bool {
+	ctx := cmdObj.Context()
+	// e.g. context.Background() can never be cancelled
+	if ctx == nil || ctx.Done() == nil {
+		return func() bool { return false }
+	}
+
+	done := make(chan struct{})
+	killed := make(chan bool, 1)
+	go utils.Safe(func() {
+		select {
+		case <-ctx.Done():
+			_ = Kill(cmdObj.GetCmd())
+			killed <- true
+		case <-done:
+			killed <- false
+		}
+	})
+
+	return func() bool {
+		close(done)
+		return <-killed
+	}
+}
+
 // Whenever we're asked for a password we just enter a newline, which will
 // eventually cause the command to fail.
 var failPromptFn = func(CredentialType) string { return "\n" }
@@ -199,6 +371,10 @@ func (self *cmdObjRunner) runWithCredentialHandling(cmdObj ICmdObj) error {
 		return errors.New("runWithCredentialHandling called but cmdObj does not have a credential strategy")
 	}
 
+	if self.useAskpassFn != nil && self.useAskpassFn() {
+		return self.runWithAskpass(cmdObj, promptFn)
+	}
+
 	return self.runAndDetectCredentialRequest(cmdObj, promptFn)
 }
 
@@ -214,7 +390,7 @@ func sanitisedCommandOutput(output []byte, err error) (string, error) {
 		if outputString == "" {
 			return "", utils.WrapError(err)
 		}
-		return outputString, errors.New(outputString)
+		return outputString, errorFromCommandOutput(outputString)
 	}
 	return outputString, nil
 }
@@ -256,6 +432,10 @@ func (self *cmdObjRunner) runAndStreamAux(
 	cmdObj ICmdObj,
 	onRun func(*cmdHandler, io.Writer),
 ) error {
+	if err := contextErr(cmdObj); err != nil {
+		return err
+	}
+
 	// if we're streaming this we don't want any fancy terminal stuff
 	cmdObj.AddEnvVars("TERM=dumb")
 
@@ -284,13 +464,18 @@ func (self *cmdObjRunner) runAndStreamAux(
 		}
 	}()
 
+	stopWatching := killOnCancel(cmdObj)
+
 	onRun(handler, cmdWriter)
 
 	err = cmd.Wait()
+	if killed := stopWatching(); killed {
+		return cmdObj.Context().Err()
+	}
 	if err != nil {
 		errStr := stderr.String()
 		if errStr != "" {
-			return errors.New(errStr)
+			return errorFromCommandOutput(errStr)
 		}
 
 		if cmdObj.ShouldIgnoreEmptyError() {
@@ -320,23 +505,28 @@ func (self *cmdObjRunner) processOutput(reader io.Reader, writer io.Writer, prom
 	}
 }
 
-// having a function that returns a function because we need to maintain some state inbetween calls hence the closure
-func (self *cmdObjRunner) getCheckForCredentialRequestFunc() func([]byte) (CredentialType, bool) {
-	var ttyText strings.Builder
-	prompts := map[string]CredentialType{
-		`Password:`:                              Password,
-		`.+'s password:`:                         Password,
-		`Password\s*for\s*'.+':`:                 Password,
-		`Username\s*for\s*'.+':`:                 Username,
-		`Enter\s*passphrase\s*for\s*key\s*'.+':`: Passphrase,
-		`Enter\s*PIN\s*for\s*.+\s*key\s*.+:`:     PIN,
-	}
+var credentialPrompts = map[string]CredentialType{
+	`Password:`:                              Password,
+	`.+'s password:`:                         Password,
+	`Password\s*for\s*'.+':`:                 Password,
+	`Username\s*for\s*'.+':`:                 Username,
+	`Enter\s*passphrase\s*for\s*key\s*'.+':`: Passphrase,
+	`Enter\s*PIN\s*for\s*.+\s*key\s*.+:`:     PIN,
+}
 
+func compileCredentialPrompts() map[*regexp.Regexp]CredentialType {
 	compiledPrompts := map[*regexp.Regexp]CredentialType{}
-	for pattern, askFor := range prompts {
+	for pattern, askFor := range credentialPrompts {
 		compiledPattern := regexp.MustCompile(pattern)
 		compiledPrompts[compiledPattern] = askFor
 	}
+	return compiledPrompts
+}
+
+// having a function that returns a function because we need to maintain some state inbetween calls hence the closure
+func (self *cmdObjRunner) getCheckForCredentialRequestFunc() func([]byte) (CredentialType, bool) {
+	var ttyText strings.Builder
+	compiledPrompts := compileCredentialPrompts()
 
 	newlineRegex := regexp.MustCompile("\n")
 
@@ -0,0 +1,141 @@
+//go:build !windows
+// +build !windows
+
+package oscommands
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCmdObjRunnerKillsCommandOnCancel(t *testing.T) {
+	type scenario struct {
+		testName string
+		run      func(cmdObj ICmdObj) error
+	}
+
+	scenarios := []scenario{
+		{
+			testName: "Run",
+			run: func(cmdObj ICmdObj) error {
+				return cmdObj.Run()
+			},
+		},
+		{
+			testName: "RunWithOutputs",
+			run: func(cmdObj ICmdObj) error {
+				_, _, err := cmdObj.RunWithOutputs()
+				return err
+			},
+		},
+		{
+			testName: "RunAndProcessLines",
+			run: func(cmdObj ICmdObj) error {
+				return cmdObj.RunAndProcessLines(func(line string) (bool, error) {
+					return false, nil
+				})
+			},
+		},
+		{
+			testName: "streaming",
+			run: func(cmdObj ICmdObj) error {
+				return cmdObj.StreamOutput().Run()
+			},
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			builder := NewDummyCmdObjBuilder(getRunner())
+
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+
+			start := time.Now()
+			err := s.run(builder.New("sleep 10").WithContext(ctx))
+
+			assert.Equal(t, context.DeadlineExceeded, err)
+			assert.Less(t, time.Since(start), 5*time.Second)
+		})
+	}
+}
+
+func TestCmdObjRunnerDoesNotStartCancelledCommand(t *testing.T) {
+	builder := NewDummyCmdObjBuilder(getRunner())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	output, err := builder.New("echo hello").WithContext(ctx).RunWithOutput()
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, "", output)
+}
+
+func TestCmdObjRunnerWithContextCompletes(t *testing.T) {
+	builder := NewDummyCmdObjBuilder(getRunner())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	output, err := builder.New("echo hello").WithContext(ctx).RunWithOutput()
+	assert.NoError(t, err)
+	assert.Equal(t, "hello\n", output)
+}
+
+func TestCmdObjRunnerRunAndProcessLinesExitStatus(t *testing.T) {
+	type scenario struct {
+		testName        string
+		checkExitStatus bool
+		stopAt          string
+		expectedLines   []string
+		expectedError   string
+	}
+
+	scenarios := []scenario{
+		{
+			testName:        "exit status ignored by default",
+			checkExitStatus: false,
+			expectedLines:   []string{"a", "b"},
+		},
+		{
+			testName:        "exit status checked",
+			checkExitStatus: true,
+			expectedLines:   []string{"a", "b"},
+			expectedError:   "oops\n",
+		},
+		{
+			testName:        "stopped by the callback",
+			checkExitStatus: true,
+			stopAt:          "a",
+			expectedLines:   []string{"a"},
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			builder := NewDummyCmdObjBuilder(getRunner())
+			cmdObj := builder.NewShell("echo a; echo b; echo oops >&2; exit 1")
+			if s.checkExitStatus {
+				cmdObj.CheckExitStatus()
+			}
+
+			lines := []string{}
+			err := cmdObj.RunAndProcessLines(func(line string) (bool, error) {
+				lines = append(lines, line)
+				return line == s.stopAt, nil
+			})
+
+			if s.expectedError != "" {
+				assert.EqualError(t, err, s.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, s.expectedLines, lines)
+		})
+	}
+}
@@ -1,10 +1,12 @@
 package oscommands
 
 import (
+	"errors"
 	"strings"
 	"testing"
 
 	"github.com/jesseduffield/lazygit/pkg/utils"
+	"github.com/stretchr/testify/assert"
 )
 
 func getRunner() *cmdObjRunner {
@@ -107,3 +109,79 @@ func TestProcessOutput(t *testing.T) {
 		})
 	}
 }
+
+func TestErrorFromCommandOutput(t *testing.T) {
+	scenarios := []struct {
+		name         string
+		output       string
+		expectedKind CredentialErrorKind
+		isCredential bool
+	}{
+		{
+			name:         "no tty to prompt on",
+			output:       "fatal: could not read Username for 'https://github.com': terminal prompts disabled\n",
+			expectedKind: CREDENTIALS_REQUIRED,
+			isCredential: true,
+		},
+		{
+			name:         "bad password",
+			output:       "remote: Invalid username or password.\nfatal: Authentication failed for 'https://github.com/owner/repo.git/'\n",
+			expectedKind: AUTHENTICATION_FAILED,
+			isCredential: true,
+		},
+		{
+			name:         "ssh key rejected",
+			output:       "git@github.com: Permission denied (publickey).\nfatal: Could not read from remote repository.\n",
+			expectedKind: SSH_AUTHENTICATION_FAILED,
+			isCredential: true,
+		},
+		{
+			name:         "unknown host key",
+			output:       "Host key verification failed.\nfatal: Could not read from remote repository.\n",
+			expectedKind: SSH_AUTHENTICATION_FAILED,
+			isCredential: true,
+		},
+		{
+			name:         "rejected password after an echoed prompt",
+			output:       "Password for 'https://user@github.com': \nremote: Invalid username or password.\nfatal: Authentication failed for 'https://github.com/owner/repo.git/'\n",
+			expectedKind: AUTHENTICATION_FAILED,
+			isCredential: true,
+		},
+		{
+			name:         "unrelated error",
+			output:       "error: failed to push some refs to 'origin'\n",
+			isCredential: false,
+		},
+		{
+			name:         "could not read from a remote that isn't a repository",
+			output:       "fatal: 'origin' does not appear to be a git repository\nfatal: Could not read from remote repository.\n",
+			isCredential: false,
+		},
+		{
+			name:         "could not read from a repository that doesn't exist",
+			output:       "ERROR: Repository not found.\nfatal: Could not read from remote repository.\n",
+			isCredential: false,
+		},
+		{
+			name:         "repository not found after a credential prompt",
+			output:       "Username for 'https://github.com': \nremote: Repository not found.\nfatal: repository 'https://github.com/owner/repo.git/' not found\n",
+			isCredential: false,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		scenario := scenario
+		t.Run(scenario.name, func(t *testing.T) {
+			err := errorFromCommandOutput(scenario.output)
+			assert.EqualError(t, err, scenario.output)
+
+			var credentialErr *CredentialError
+			if scenario.isCredential {
+				assert.True(t, errors.As(err, &credentialErr))
+				assert.Equal(t, scenario.expectedKind, credentialErr.Kind)
+			} else {
+				assert.False(t, errors.As(err, &credentialErr))
+			}
+		})
+	}
+}
@@ -18,6 +18,7 @@ type OSCommandDeps struct {
 	Platform     *Platform
 	GetenvFn     func(string) string
 	RemoveFileFn func(string) error
+	TrashFileFn  func(string) error
 	Cmd          *CmdObjBuilder
 	TempDir      string
 }
@@ -38,6 +39,8 @@ func NewDummyOSCommandWithDeps(deps OSCommandDeps) *OSCommand {
 		Platform:     platform,
 		getenvFn:     deps.GetenvFn,
 		removeFileFn: deps.RemoveFileFn,
+		trashFileFn:  deps.TrashFileFn,
+		Cmd:          deps.Cmd,
 		guiIO:        NewNullGuiIO(utils.NewDummyLog()),
 		tempDir:      deps.TempDir,
 	}
@@ -27,14 +27,19 @@ type guiIO struct {
 	// that a command requests it.
 	// the 'credential' arg is something like 'username' or 'password'
 	promptForCredentialFn func(credential CredentialType) string
+	// this returns the env vars that make git run lazygit as its askpass helper,
+	// talking to us on the given unix socket. It's nil if there's nobody to ask
+	// for credentials, in which case we don't use askpass.
+	askpassEnvVarsFn func(socketPath string) []string
 }
 
-func NewGuiIO(log *logrus.Entry, logCommandFn func(string, bool), newCmdWriterFn func() io.Writer, promptForCredentialFn func(CredentialType) string) *guiIO {
+func NewGuiIO(log *logrus.Entry, logCommandFn func(string, bool), newCmdWriterFn func() io.Writer, promptForCredentialFn func(CredentialType) string, askpassEnvVarsFn func(string) []string) *guiIO {
 	return &guiIO{
 		log:                   log,
 		logCommandFn:          logCommandFn,
 		newCmdWriterFn:        newCmdWriterFn,
 		promptForCredentialFn: promptForCredentialFn,
+		askpassEnvVarsFn:      askpassEnvVarsFn,
 	}
 }
 
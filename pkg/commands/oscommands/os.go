@@ -27,6 +27,7 @@ type OSCommand struct {
 	guiIO    *guiIO
 
 	removeFileFn func(string) error
+	trashFileFn  func(string) error
 
 	Cmd *CmdObjBuilder
 
@@ -48,12 +49,19 @@ func NewOSCommand(common *common.Common, config config.AppConfigurer, platform *
 		Common:       common,
 		Platform:     platform,
 		getenvFn:     os.Getenv,
-		removeFileFn: os.RemoveAll,
+		removeFileFn: removeFile,
+		trashFileFn:  trashFile,
 		guiIO:        guiIO,
 		tempDir:      config.GetTempDir(),
 	}
 
-	runner := &cmdObjRunner{log: common.Log, guiIO: guiIO}
+	runner := &cmdObjRunner{
+		log:   common.Log,
+		guiIO: guiIO,
+		useAskpassFn: func() bool {
+			return common.UserConfig.Git.CredentialInput == "askpass" && guiIO.askpassEnvVarsFn != nil
+		},
+	}
 	c.Cmd = &CmdObjBuilder{runner: runner, platform: platform}
 
 	return c
@@ -110,6 +118,42 @@ func (c *OSCommand) OpenLink(link string) error {
 	return c.Cmd.NewShell(command).Run()
 }
 
+// OpenInFileManager shows the given file or directory in the platform's file
+// manager. On macOS and Windows it's selected in the directory containing it;
+// elsewhere we open the directory containing a file, or a directory itself.
+func (c *OSCommand) OpenInFileManager(path string) error {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Stat(absPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("cannot open '%s' in the file manager because it does not exist", path)
+		}
+		return err
+	}
+
+	switch c.Platform.OS {
+	case "darwin":
+		return c.Cmd.NewFromArgs([]string{"open", "-R", absPath}).Run()
+	case "windows":
+		// explorer exits with a non-zero code even when it succeeds, so there's
+		// no telling a real failure apart
+		_ = c.Cmd.NewFromArgs([]string{"explorer", "/select," + absPath}).Run()
+		return nil
+	default:
+		// xdg-open can't select anything, so for a file the best we can do is
+		// open the directory it's in
+		dir := filepath.Dir(absPath)
+		if info.IsDir() {
+			dir = absPath
+		}
+		return c.Cmd.NewFromArgs([]string{"xdg-open", dir}).Run()
+	}
+}
+
 // Quote wraps a message in platform-specific quotation marks
 func (c *OSCommand) Quote(message string) string {
 	return c.Cmd.Quote(message)
@@ -152,6 +196,106 @@ func (c *OSCommand) AppendLineToFile(filename, line string) error {
 	return nil
 }
 
+// AppendUniqueLinesToFile appends the given lines to the file in order,
+// skipping any that the file already contains and any repeats within lines.
+// The file is opened and written to only once.
+func (c *OSCommand) AppendUniqueLinesToFile(filename string, lines []string) error {
+	return c.AppendUniqueLinesToFileWhere(filename, lines, func(string) bool { return true })
+}
+
+// AppendUniqueLinesToFileWhere is like AppendUniqueLinesToFile, but only the
+// lines for which shouldDedupe returns true are skipped when they're already
+// there. The rest, e.g. blank lines, are always appended.
+func (c *OSCommand) AppendUniqueLinesToFileWhere(filename string, lines []string, shouldDedupe func(line string) bool) error {
+	c.LogCommand(fmt.Sprintf("Appending '%s' to file '%s'", strings.Join(lines, "', '"), filename), false)
+	f, err := os.OpenFile(filename, os.O_APPEND|os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return utils.WrapError(err)
+	}
+	defer f.Close()
+
+	existingContent, err := io.ReadAll(f)
+	if err != nil {
+		return utils.WrapError(err)
+	}
+
+	seen := map[string]bool{}
+	for _, line := range strings.Split(string(existingContent), "\n") {
+		seen[strings.TrimRight(line, "\r")] = true
+	}
+
+	var sb strings.Builder
+	for _, line := range lines {
+		if shouldDedupe(line) {
+			if seen[line] {
+				continue
+			}
+			seen[line] = true
+		}
+		sb.WriteString(line + "\n")
+	}
+
+	if sb.Len() == 0 {
+		return nil
+	}
+
+	toWrite := sb.String()
+	// if the last byte of the file is not a newline, add it
+	if len(existingContent) > 0 && existingContent[len(existingContent)-1] != '\n' {
+		toWrite = "\n" + toWrite
+	}
+
+	if _, err := f.WriteString(toWrite); err != nil {
+		return utils.WrapError(err)
+	}
+	return nil
+}
+
+// RemoveLineFromFile removes the first line in the file which exactly matches
+// the given line, leaving the rest of the file untouched. Returns an error if
+// there is no such line.
+func (c *OSCommand) RemoveLineFromFile(filename, line string) error {
+	c.LogCommand(fmt.Sprintf("Removing '%s' from file '%s'", line, filename), false)
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return utils.WrapError(err)
+	}
+
+	lines := strings.SplitAfter(string(content), "\n")
+	for i, existingLine := range lines {
+		if strings.TrimRight(existingLine, "\r\n") == line {
+			newContent := strings.Join(append(lines[:i:i], lines[i+1:]...), "")
+			return utils.WrapError(os.WriteFile(filename, []byte(newContent), 0o644))
+		}
+	}
+
+	return fmt.Errorf("'%s' not found in file '%s'", line, filename)
+}
+
+// SetExecutable adds or removes the file's executable bits. Windows has no
+// such bits, so there it does nothing.
+func (c *OSCommand) SetExecutable(path string, executable bool) error {
+	if c.Platform.OS == "windows" {
+		return nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return utils.WrapError(err)
+	}
+
+	mode := info.Mode().Perm()
+	if executable {
+		// like `chmod +x`, only give execute permission to those who can read it
+		mode |= (mode & 0o444) >> 2
+	} else {
+		mode &^= 0o111
+	}
+
+	c.LogCommand(fmt.Sprintf("Changing mode of '%s' to %o", path, mode), false)
+	return utils.WrapError(os.Chmod(path, mode))
+}
+
 // CreateFileWithContent creates a file with the given content
 func (c *OSCommand) CreateFileWithContent(path string, content string) error {
 	c.LogCommand(fmt.Sprintf("Creating file '%s'", path), false)
@@ -269,6 +413,25 @@ func (c *OSCommand) RemoveFile(path string) error {
 	return c.removeFileFn(path)
 }
 
+// removeFile removes the file or directory at the given path. A symlink is
+// unlinked itself, leaving whatever it points to alone, even if that's a
+// directory.
+func removeFile(path string) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	if info.Mode()&os.ModeSymlink != 0 {
+		return os.Remove(path)
+	}
+
+	return os.RemoveAll(path)
+}
+
 func (c *OSCommand) Getenv(key string) string {
 	return c.getenvFn(key)
 }
@@ -4,6 +4,8 @@
 package oscommands
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/go-errors/errors"
@@ -140,3 +142,142 @@ func TestOSCommandOpenFileLinux(t *testing.T) {
 		s.test(oSCmd.OpenFile(s.filename))
 	}
 }
+
+func TestOSCommandOpenInFileManager(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file.txt")
+	assert.NoError(t, os.WriteFile(file, []byte("content"), 0o644))
+
+	type scenario struct {
+		testName      string
+		platform      string
+		path          string
+		runner        *FakeCmdObjRunner
+		expectedError string
+	}
+
+	scenarios := []scenario{
+		{
+			testName: "file on macOS",
+			platform: "darwin",
+			path:     file,
+			runner:   NewFakeRunner(t).ExpectArgs([]string{"open", "-R", file}, "", nil),
+		},
+		{
+			testName: "file on Windows",
+			platform: "windows",
+			path:     file,
+			// explorer's exit code is meaningless, so this isn't an error
+			runner: NewFakeRunner(t).ExpectArgs([]string{"explorer", "/select," + file}, "", errors.New("exit status 1")),
+		},
+		{
+			testName: "file on Linux",
+			platform: "linux",
+			path:     file,
+			runner:   NewFakeRunner(t).ExpectArgs([]string{"xdg-open", dir}, "", nil),
+		},
+		{
+			testName: "directory on Linux",
+			platform: "linux",
+			path:     dir,
+			runner:   NewFakeRunner(t).ExpectArgs([]string{"xdg-open", dir}, "", nil),
+		},
+		{
+			testName:      "error from the file manager",
+			platform:      "linux",
+			path:          file,
+			runner:        NewFakeRunner(t).ExpectArgs([]string{"xdg-open", dir}, "", errors.New("error")),
+			expectedError: "error",
+		},
+		{
+			testName:      "path that doesn't exist",
+			platform:      "darwin",
+			path:          filepath.Join(dir, "missing.txt"),
+			runner:        NewFakeRunner(t),
+			expectedError: "cannot open '" + filepath.Join(dir, "missing.txt") + "' in the file manager because it does not exist",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			oSCmd := NewDummyOSCommandWithRunner(s.runner)
+			// copying the platform so that we don't change it for other tests
+			platform := *oSCmd.Platform
+			platform.OS = s.platform
+			oSCmd.Platform = &platform
+
+			err := oSCmd.OpenInFileManager(s.path)
+
+			if s.expectedError == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, s.expectedError)
+			}
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestOSCommandSetExecutable(t *testing.T) {
+	type scenario struct {
+		testName     string
+		platform     string
+		mode         os.FileMode
+		executable   bool
+		expectedMode os.FileMode
+	}
+
+	scenarios := []scenario{
+		{
+			testName:     "make executable",
+			platform:     "linux",
+			mode:         0o644,
+			executable:   true,
+			expectedMode: 0o755,
+		},
+		{
+			testName:     "make executable only for those who can read it",
+			platform:     "linux",
+			mode:         0o600,
+			executable:   true,
+			expectedMode: 0o700,
+		},
+		{
+			testName:     "make non-executable",
+			platform:     "darwin",
+			mode:         0o755,
+			executable:   false,
+			expectedMode: 0o644,
+		},
+		{
+			testName:     "Windows has no executable bits",
+			platform:     "windows",
+			mode:         0o644,
+			executable:   true,
+			expectedMode: 0o644,
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "script.sh")
+			assert.NoError(t, os.WriteFile(path, []byte("echo hi"), s.mode))
+			// the umask may have taken some bits away
+			assert.NoError(t, os.Chmod(path, s.mode))
+
+			oSCmd := NewDummyOSCommand()
+			// copying the platform so that we don't change it for other tests
+			platform := *oSCmd.Platform
+			platform.OS = s.platform
+			oSCmd.Platform = &platform
+
+			assert.NoError(t, oSCmd.SetExecutable(path, s.executable))
+
+			info, err := os.Stat(path)
+			assert.NoError(t, err)
+			assert.Equal(t, s.expectedMode, info.Mode().Perm())
+		})
+	}
+}
@@ -194,3 +194,128 @@ func TestOSCommandAppendLineToFile(t *testing.T) {
 		_ = os.RemoveAll(s.path)
 	}
 }
+
+func TestOSCommandAppendUniqueLinesToFile(t *testing.T) {
+	type scenario struct {
+		testName        string
+		existingContent *string
+		lines           []string
+		shouldDedupe    func(string) bool
+		expected        string
+	}
+
+	content := func(str string) *string { return &str }
+
+	scenarios := []scenario{
+		{
+			testName:        "file does not exist",
+			existingContent: nil,
+			lines:           []string{"a", "b"},
+			expected:        "a\nb\n",
+		},
+		{
+			testName:        "file without trailing newline",
+			existingContent: content("a"),
+			lines:           []string{"b", "c"},
+			expected:        "a\nb\nc\n",
+		},
+		{
+			testName:        "duplicates in file and in lines",
+			existingContent: content("a\nb\n"),
+			lines:           []string{"c", "a", "d", "c"},
+			expected:        "a\nb\nc\nd\n",
+		},
+		{
+			testName:        "nothing new",
+			existingContent: content("a\r\nb"),
+			lines:           []string{"b", "a"},
+			expected:        "a\r\nb",
+		},
+		{
+			testName:        "only some lines are deduped",
+			existingContent: content("a\n\nb\n"),
+			lines:           []string{"", "a", "c", "", "d"},
+			shouldDedupe:    func(line string) bool { return line != "" },
+			expected:        "a\n\nb\n\nc\n\nd\n",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), ".gitignore")
+			if s.existingContent != nil {
+				assert.NoError(t, os.WriteFile(path, []byte(*s.existingContent), 0o600))
+			}
+
+			osCommand := NewDummyOSCommand()
+			if s.shouldDedupe != nil {
+				assert.NoError(t, osCommand.AppendUniqueLinesToFileWhere(path, s.lines, s.shouldDedupe))
+			} else {
+				assert.NoError(t, osCommand.AppendUniqueLinesToFile(path, s.lines))
+			}
+
+			output, err := os.ReadFile(path)
+			assert.NoError(t, err)
+			assert.Equal(t, s.expected, string(output))
+		})
+	}
+}
+
+func TestOSCommandRemoveLineFromFile(t *testing.T) {
+	type scenario struct {
+		testName      string
+		content       string
+		line          string
+		expected      string
+		expectedError string
+	}
+
+	scenarios := []scenario{
+		{
+			testName: "removes only the first exact match",
+			content:  "a\nbb\nb\nb\n",
+			line:     "b",
+			expected: "a\nbb\nb\n",
+		},
+		{
+			testName: "last line without trailing newline",
+			content:  "a\nb",
+			line:     "b",
+			expected: "a\n",
+		},
+		{
+			testName: "windows line endings",
+			content:  "a\r\nb\r\nc\r\n",
+			line:     "b",
+			expected: "a\r\nc\r\n",
+		},
+		{
+			testName:      "line not present",
+			content:       "a\nb\n",
+			line:          "c",
+			expected:      "a\nb\n",
+			expectedError: "'c' not found in file",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), ".gitignore")
+			assert.NoError(t, os.WriteFile(path, []byte(s.content), 0o600))
+
+			osCommand := NewDummyOSCommand()
+			err := osCommand.RemoveLineFromFile(path, s.line)
+			if s.expectedError != "" {
+				assert.ErrorContains(t, err, s.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			output, err := os.ReadFile(path)
+			assert.NoError(t, err)
+			assert.Equal(t, s.expected, string(output))
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package oscommands
+
+import (
+	"fmt"
+)
+
+// TrashUnavailableError is returned when a file can't be moved to the trash,
+// e.g. because it's on a different filesystem to the trash directory. The file
+// is left where it was; we never fall back to deleting it permanently.
+type TrashUnavailableError struct {
+	Path string
+	Err  error
+}
+
+func (e *TrashUnavailableError) Error() string {
+	return fmt.Sprintf(
+		"could not move '%s' to the trash: %v. Set os.useTrash to false in your config to delete files permanently instead",
+		e.Path, e.Err,
+	)
+}
+
+func (e *TrashUnavailableError) Unwrap() error {
+	return e.Err
+}
+
+// TrashFile moves the file or directory at the given path to the OS's trash,
+// from where the user can restore it
+func (c *OSCommand) TrashFile(path string) error {
+	c.LogCommand(fmt.Sprintf("Moving path '%s' to the trash", path), false)
+
+	if err := c.trashFileFn(path); err != nil {
+		return &TrashUnavailableError{Path: path, Err: err}
+	}
+
+	return nil
+}
@@ -0,0 +1,43 @@
+package oscommands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// trashFile moves the path to ~/.Trash. Finder won't offer to put it back, but
+// the user can drag it out again.
+func trashFile(path string) error {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Lstat(absPath); err != nil {
+		return err
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return err
+	}
+	trashDir := filepath.Join(home, ".Trash")
+
+	baseName := filepath.Base(absPath)
+	ext := filepath.Ext(baseName)
+	stem := strings.TrimSuffix(baseName, ext)
+
+	// like Finder, we add a number to the name if the trash already has a file
+	// of the same name
+	destination := filepath.Join(trashDir, baseName)
+	for i := 2; ; i++ {
+		if _, err := os.Lstat(destination); os.IsNotExist(err) {
+			break
+		}
+		destination = filepath.Join(trashDir, fmt.Sprintf("%s %d%s", stem, i, ext))
+	}
+
+	return os.Rename(absPath, destination)
+}
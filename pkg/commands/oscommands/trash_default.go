@@ -0,0 +1,107 @@
+//go:build !windows && !darwin
+// +build !windows,!darwin
+
+package oscommands
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// trashFile moves the path to the user's home trash, as described by the
+// FreeDesktop.org trash spec, writing the .trashinfo file that file managers
+// need in order to restore it
+func trashFile(path string) error {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Lstat(absPath); err != nil {
+		return err
+	}
+
+	trashDir, err := xdgTrashDir()
+	if err != nil {
+		return err
+	}
+
+	filesDir := filepath.Join(trashDir, "files")
+	infoDir := filepath.Join(trashDir, "info")
+	for _, dir := range []string{filesDir, infoDir} {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return err
+		}
+	}
+
+	name, infoPath, err := reserveTrashName(filesDir, infoDir, filepath.Base(absPath), absPath)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Rename(absPath, filepath.Join(filesDir, name)); err != nil {
+		_ = os.Remove(infoPath)
+		return err
+	}
+
+	return nil
+}
+
+func xdgTrashDir() (string, error) {
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+
+	return filepath.Join(dataHome, "Trash"), nil
+}
+
+// picks a name under which to store the file in the trash, creating its info
+// file exclusively so that another process trashing a file of the same name at
+// the same time can't claim it too
+func reserveTrashName(filesDir string, infoDir string, baseName string, originalPath string) (string, string, error) {
+	info := fmt.Sprintf(
+		"[Trash Info]\nPath=%s\nDeletionDate=%s\n",
+		(&url.URL{Path: originalPath}).EscapedPath(),
+		time.Now().Format("2006-01-02T15:04:05"),
+	)
+
+	for i := 1; ; i++ {
+		name := baseName
+		if i > 1 {
+			name = baseName + "." + strconv.Itoa(i)
+		}
+
+		if _, err := os.Lstat(filepath.Join(filesDir, name)); err == nil {
+			continue
+		}
+
+		infoPath := filepath.Join(infoDir, name+".trashinfo")
+		infoFile, err := os.OpenFile(infoPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if err != nil {
+			if os.IsExist(err) {
+				continue
+			}
+			return "", "", err
+		}
+
+		_, err = infoFile.WriteString(info)
+		if closeErr := infoFile.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			_ = os.Remove(infoPath)
+			return "", "", err
+		}
+
+		return name, infoPath, nil
+	}
+}
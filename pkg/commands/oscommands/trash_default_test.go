@@ -0,0 +1,48 @@
+//go:build !windows && !darwin
+// +build !windows,!darwin
+
+package oscommands
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrashFile(t *testing.T) {
+	dataHome := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", dataHome)
+
+	repoDir := t.TempDir()
+	for _, dir := range []string{"a", "b"} {
+		assert.NoError(t, os.MkdirAll(filepath.Join(repoDir, dir), 0o755))
+		assert.NoError(t, os.WriteFile(filepath.Join(repoDir, dir, "new file.txt"), []byte(dir), 0o644))
+	}
+
+	assert.NoError(t, trashFile(filepath.Join(repoDir, "a", "new file.txt")))
+	// a second file with the same name mustn't overwrite the first one
+	assert.NoError(t, trashFile(filepath.Join(repoDir, "b", "new file.txt")))
+
+	assert.NoFileExists(t, filepath.Join(repoDir, "a", "new file.txt"))
+	assert.NoFileExists(t, filepath.Join(repoDir, "b", "new file.txt"))
+
+	trashDir := filepath.Join(dataHome, "Trash")
+	for name, dir := range map[string]string{"new file.txt": "a", "new file.txt.2": "b"} {
+		content, err := os.ReadFile(filepath.Join(trashDir, "files", name))
+		assert.NoError(t, err)
+		assert.Equal(t, dir, string(content))
+
+		info, err := os.ReadFile(filepath.Join(trashDir, "info", name+".trashinfo"))
+		assert.NoError(t, err)
+		assert.Regexp(t,
+			regexp.MustCompile(`^\[Trash Info\]\nPath=`+regexp.QuoteMeta(repoDir+"/"+dir+"/new%20file.txt")+`\nDeletionDate=\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}\n$`),
+			string(info),
+		)
+	}
+
+	assert.Error(t, trashFile(filepath.Join(repoDir, "missing.txt")))
+	assert.NoFileExists(t, filepath.Join(trashDir, "info", "missing.txt.trashinfo"))
+}
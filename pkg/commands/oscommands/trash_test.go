@@ -0,0 +1,30 @@
+package oscommands
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOSCommandTrashFile(t *testing.T) {
+	trashed := []string{}
+	osCommand := NewDummyOSCommandWithDeps(OSCommandDeps{
+		TrashFileFn: func(path string) error {
+			if path == "unavailable" {
+				return errors.New("invalid cross-device link")
+			}
+			trashed = append(trashed, path)
+			return nil
+		},
+	})
+
+	assert.NoError(t, osCommand.TrashFile("file.txt"))
+	assert.Equal(t, []string{"file.txt"}, trashed)
+
+	err := osCommand.TrashFile("unavailable")
+	var trashErr *TrashUnavailableError
+	assert.True(t, errors.As(err, &trashErr))
+	assert.Equal(t, "unavailable", trashErr.Path)
+	assert.EqualError(t, trashErr.Err, "invalid cross-device link")
+}
@@ -0,0 +1,41 @@
+package oscommands
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// trashFile sends the path to the Recycle Bin. There's no simple syscall for
+// this, so we ask PowerShell to do it via the VisualBasic file system helpers.
+func trashFile(path string) error {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Lstat(absPath)
+	if err != nil {
+		return err
+	}
+
+	method := "DeleteFile"
+	if info.IsDir() {
+		method = "DeleteDirectory"
+	}
+
+	script := fmt.Sprintf(
+		"Add-Type -AssemblyName Microsoft.VisualBasic; [Microsoft.VisualBasic.FileIO.FileSystem]::%s('%s', 'OnlyErrorDialogs', 'SendToRecycleBin')",
+		method,
+		strings.ReplaceAll(absPath, "'", "''"),
+	)
+
+	output, err := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", script).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	return nil
+}
@@ -391,6 +391,45 @@ func TestTransform(t *testing.T) {
  ...
  ...
  ...
+`,
+		},
+		{
+			testName:       "staging only the second hunk",
+			filename:       "filename",
+			firstLineIndex: 13,
+			lastLineIndex:  21,
+			diffText:       twoHunks,
+			expected: `--- a/filename
++++ b/filename
+@@ -8,6 +8,8 @@ grape
+ ...
+ ...
+ ...
++pear
++lemon
+ ...
+ ...
+ ...
+`,
+		},
+		{
+			testName:       "unstaging only the second hunk",
+			filename:       "filename",
+			firstLineIndex: 13,
+			lastLineIndex:  21,
+			reverse:        true,
+			diffText:       twoHunks,
+			expected: `--- a/filename
++++ b/filename
+@@ -8,6 +8,8 @@ grape
+ ...
+ ...
+ ...
++pear
++lemon
+ ...
+ ...
+ ...
 `,
 		},
 		{
@@ -12,3 +12,19 @@ const (
 	REBASE_MODE_REBASING
 	REBASE_MODE_MERGING
 )
+
+// GitOperation is the multi-step operation the repo is in the middle of, if any
+type GitOperation int
+
+const (
+	GIT_OPERATION_NONE GitOperation = iota
+	GIT_OPERATION_MERGING
+	// a rebase using the merge backend, which includes interactive rebases
+	GIT_OPERATION_REBASING_MERGE
+	// a rebase using the apply backend
+	GIT_OPERATION_REBASING_APPLY
+	// `git am`, which shares the rebase-apply directory with apply rebases
+	GIT_OPERATION_APPLYING_MAILBOX
+	GIT_OPERATION_CHERRY_PICKING
+	GIT_OPERATION_REVERTING
+)
@@ -88,6 +88,8 @@ type GitConfig struct {
 	ParseEmoji      bool      `yaml:"parseEmoji"`
 	Log             LogConfig `yaml:"log"`
 	DiffContextSize int       `yaml:"diffContextSize"`
+	// one of 'terminal' or 'askpass'
+	CredentialInput string `yaml:"credentialInput"`
 }
 
 type PagingConfig struct {
@@ -99,6 +101,8 @@ type PagingConfig struct {
 type CommitConfig struct {
 	SignOff bool   `yaml:"signOff"`
 	Verbose string `yaml:"verbose"`
+	// markers like TODO that we warn about when they're added in staged changes
+	NewMarkers []string `yaml:"newMarkers"`
 }
 
 type MergingConfig struct {
@@ -197,6 +201,7 @@ type KeybindingUniversalConfig struct {
 	SubmitEditorText             string   `yaml:"submitEditorText"`
 	ExtrasMenu                   string   `yaml:"extrasMenu"`
 	ToggleWhitespaceInDiffView   string   `yaml:"toggleWhitespaceInDiffView"`
+	ToggleBypassHooks            string   `yaml:"toggleBypassHooks"`
 	IncreaseContextInDiffView    string   `yaml:"increaseContextInDiffView"`
 	DecreaseContextInDiffView    string   `yaml:"decreaseContextInDiffView"`
 }
@@ -319,6 +324,15 @@ type OSConfig struct {
 	// Command for opening a link. Should contain "{{link}}".
 	OpenLink string `yaml:"openLink,omitempty"`
 
+	// Command for starting an interactive shell in the repo. Defaults to $SHELL.
+	Shell string `yaml:"shell,omitempty"`
+
+	// Whether discarding an untracked file, or cleaning out untracked files,
+	// moves them to the OS's trash rather than deleting them permanently. If
+	// the trash can't be used, the discard fails instead of falling back to
+	// deleting the file.
+	UseTrash bool `yaml:"useTrash,omitempty"`
+
 	// --------
 
 	// The following configs are all deprecated and kept for backward
@@ -430,8 +444,9 @@ func GetDefaultConfig() *UserConfig {
 				UseConfig: false,
 			},
 			Commit: CommitConfig{
-				SignOff: false,
-				Verbose: "default",
+				SignOff:    false,
+				Verbose:    "default",
+				NewMarkers: []string{"TODO", "FIXME"},
 			},
 			Merging: MergingConfig{
 				ManualCommit: false,
@@ -451,6 +466,7 @@ func GetDefaultConfig() *UserConfig {
 			CommitPrefixes:      map[string]CommitPrefixConfig(nil),
 			ParseEmoji:          false,
 			DiffContextSize:     3,
+			CredentialInput:     "terminal",
 		},
 		Refresher: RefresherConfig{
 			RefreshInterval: 10,
@@ -525,6 +541,7 @@ func GetDefaultConfig() *UserConfig {
 				SubmitEditorText:             "<enter>",
 				ExtrasMenu:                   "@",
 				ToggleWhitespaceInDiffView:   "<c-w>",
+				ToggleBypassHooks:            "<c-n>",
 				IncreaseContextInDiffView:    "}",
 				DecreaseContextInDiffView:    "{",
 			},
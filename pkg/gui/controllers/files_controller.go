@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"errors"
 	"strings"
 
 	"github.com/jesseduffield/gocui"
@@ -182,11 +183,13 @@ func (self *FilesController) GetOnRenderToMain() func() error {
 
 			if node.File != nil && node.File.HasInlineMergeConflicts {
 				hasConflicts, err := self.c.Helpers().MergeConflicts.SetMergeState(node.GetPath())
-				if err != nil {
+				var unsupportedErr *git_commands.UnsupportedConflictTypeError
+				if err != nil && !errors.As(err, &unsupportedErr) {
 					return err
 				}
 
-				if hasConflicts {
+				// for unsupported conflict types we fall through to showing the diff
+				if err == nil && hasConflicts {
 					return self.c.Helpers().MergeConflicts.Render(false)
 				}
 			}
@@ -518,7 +521,7 @@ func (self *FilesController) ignoreOrExcludeTracked(node *filetree.FileNode, trA
 		return err
 	}
 
-	if err := self.c.Git().WorkingTree.RemoveTrackedFiles(node.GetPath()); err != nil {
+	if err := self.c.Git().WorkingTree.RemoveTrackedFiles([]string{node.GetPath()}, true); err != nil {
 		return err
 	}
 
@@ -112,6 +112,11 @@ func (self *GlobalController) GetKeybindings(opts types.KeybindingsOpts) []*type
 			Handler:     self.toggleWhitespace,
 			Description: self.c.Tr.ToggleWhitespaceInDiffView,
 		},
+		{
+			Key:         opts.GetKey(opts.Config.Universal.ToggleBypassHooks),
+			Handler:     self.toggleBypassHooks,
+			Description: self.c.Tr.ToggleBypassHooks,
+		},
 	}
 }
 
@@ -166,3 +171,15 @@ func (self *GlobalController) escape() error {
 func (self *GlobalController) toggleWhitespace() error {
 	return (&ToggleWhitespaceAction{c: self.c}).Call()
 }
+
+func (self *GlobalController) toggleBypassHooks() error {
+	self.c.State().SetBypassHooks(!self.c.State().GetBypassHooks())
+
+	if self.c.State().GetBypassHooks() {
+		self.c.Toast(self.c.Tr.BypassingHooks)
+	} else {
+		self.c.Toast(self.c.Tr.NotBypassingHooks)
+	}
+
+	return nil
+}
@@ -25,6 +25,11 @@ func (self *MergeConflictsHelper) SetMergeState(path string) (bool, error) {
 }
 
 func (self *MergeConflictsHelper) setMergeStateWithoutLock(path string) (bool, error) {
+	// files with a custom merge driver won't have conflict markers for us to parse
+	if err := self.c.Git().WorkingTree.CheckMergeDriver(path); err != nil {
+		return false, err
+	}
+
 	content, err := self.c.Git().File.Cat(path)
 	if err != nil {
 		return false, err
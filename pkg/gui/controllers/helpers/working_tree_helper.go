@@ -161,6 +161,10 @@ func (self *WorkingTreeHelper) HandleCommitPress() error {
 		}
 	}
 
+	if message == "" {
+		message = self.c.Git().Commit.CommitTemplateMessage()
+	}
+
 	return self.HandleCommitPressWithMessage(message)
 }
 
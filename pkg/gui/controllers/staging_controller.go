@@ -221,7 +221,7 @@ func (self *StagingController) applySelection(reverse bool) error {
 		applyFlags = append(applyFlags, "cached")
 	}
 	self.c.LogAction(self.c.Tr.Actions.ApplyPatch)
-	err := self.c.Git().WorkingTree.ApplyPatch(patchToApply, applyFlags...)
+	err := self.c.Git().WorkingTree.ApplyPatch(patchToApply, self.diffContextSize(), applyFlags...)
 	if err != nil {
 		return self.c.Error(err)
 	}
@@ -293,13 +293,19 @@ func (self *StagingController) editHunk() error {
 	if self.staged {
 		applyFlags = append(applyFlags, "reverse")
 	}
-	if err := self.c.Git().WorkingTree.ApplyPatch(newPatchText, applyFlags...); err != nil {
+	if err := self.c.Git().WorkingTree.ApplyPatch(newPatchText, self.diffContextSize(), applyFlags...); err != nil {
 		return self.c.Error(err)
 	}
 
 	return nil
 }
 
+// the context size of the diff we're staging from. The diff is reloaded
+// whenever the context size changes, so it's always the configured one.
+func (self *StagingController) diffContextSize() int {
+	return self.c.UserConfig.Git.DiffContextSize
+}
+
 func (self *StagingController) FilePath() string {
 	return self.c.Contexts().Files.GetSelectedPath()
 }
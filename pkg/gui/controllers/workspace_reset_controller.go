@@ -3,6 +3,7 @@ package controllers
 import (
 	"fmt"
 
+	"github.com/jesseduffield/lazygit/pkg/commands/git_commands"
 	"github.com/jesseduffield/lazygit/pkg/gui/style"
 	"github.com/jesseduffield/lazygit/pkg/gui/types"
 )
@@ -25,7 +26,7 @@ func (self *FilesController) createResetMenu() error {
 			},
 			OnPress: func() error {
 				self.c.LogAction(self.c.Tr.Actions.NukeWorkingTree)
-				if err := self.c.Git().WorkingTree.ResetAndClean(); err != nil {
+				if err := self.c.Git().WorkingTree.ResetAndClean(git_commands.ResetAndCleanOpts{}); err != nil {
 					return self.c.Error(err)
 				}
 
@@ -53,3 +53,25 @@ func (self *FileNode) GetPreviousPath() string {
 
 	return self.File.PreviousName
 }
+
+// GetStagedPaths returns the paths of all files within this node that have staged changes
+func (self *FileNode) GetStagedPaths() []string {
+	return self.getFilePathsWhere(func(file *models.File) bool { return file.HasStagedChanges })
+}
+
+// GetUnstagedPaths returns the paths of all files within this node that have unstaged changes
+func (self *FileNode) GetUnstagedPaths() []string {
+	return self.getFilePathsWhere(func(file *models.File) bool { return file.HasUnstagedChanges })
+}
+
+// GetConflictedPaths returns the paths of all files within this node that have merge conflicts
+func (self *FileNode) GetConflictedPaths() []string {
+	return self.getFilePathsWhere(func(file *models.File) bool { return file.HasMergeConflicts })
+}
+
+func (self *FileNode) getFilePathsWhere(test func(*models.File) bool) []string {
+	return self.GetPathsMatching(func(node *Node[models.File]) bool {
+		// directory nodes have no file so they never match
+		return node.File != nil && test(node.File)
+	})
+}
@@ -161,3 +161,47 @@ func TestGetFile(t *testing.T) {
 		})
 	}
 }
+
+func TestGetPathsByStatus(t *testing.T) {
+	root := &FileNode{
+		Node: &Node[models.File]{
+			Path: "",
+			Children: []*Node[models.File]{
+				{
+					Path: "dir1",
+					Children: []*Node[models.File]{
+						{
+							File: &models.File{Name: "dir1/staged", ShortStatus: "M ", HasStagedChanges: true},
+							Path: "dir1/staged",
+						},
+						{
+							Path: "dir1/nested",
+							Children: []*Node[models.File]{
+								{
+									File: &models.File{Name: "dir1/nested/both", ShortStatus: "MM", HasStagedChanges: true, HasUnstagedChanges: true},
+									Path: "dir1/nested/both",
+								},
+								{
+									File: &models.File{Name: "dir1/nested/conflicted", ShortStatus: "UU", HasUnstagedChanges: true, HasMergeConflicts: true},
+									Path: "dir1/nested/conflicted",
+								},
+							},
+						},
+					},
+				},
+				{
+					File: &models.File{Name: "unstaged", ShortStatus: " M", HasUnstagedChanges: true},
+					Path: "unstaged",
+				},
+			},
+		},
+	}
+
+	assert.EqualValues(t, []string{"dir1/staged", "dir1/nested/both"}, root.GetStagedPaths())
+	assert.EqualValues(t, []string{"dir1/nested/both", "dir1/nested/conflicted", "unstaged"}, root.GetUnstagedPaths())
+	assert.EqualValues(t, []string{"dir1/nested/conflicted"}, root.GetConflictedPaths())
+
+	subtree := &FileNode{Node: root.Children[0].Children[1]}
+	assert.EqualValues(t, []string{"dir1/nested/both"}, subtree.GetStagedPaths())
+	assert.EqualValues(t, []string{}, (&FileNode{Node: root.Children[1]}).GetConflictedPaths())
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/jesseduffield/gocui"
 	"github.com/jesseduffield/lazycore/pkg/boxlayout"
+	"github.com/jesseduffield/lazygit/pkg/app/daemon"
 	appTypes "github.com/jesseduffield/lazygit/pkg/app/types"
 	"github.com/jesseduffield/lazygit/pkg/commands"
 	"github.com/jesseduffield/lazygit/pkg/commands/git_commands"
@@ -106,6 +107,10 @@ type Gui struct {
 	// flag as to whether or not the diff view should ignore whitespace
 	IgnoreWhitespaceInDiffView bool
 
+	// whether commits and pushes skip git hooks. This lasts for the session,
+	// across switching repos.
+	BypassHooks bool
+
 	IsRefreshingFiles bool
 
 	// we use this to decide whether we'll return to the original directory that
@@ -146,6 +151,15 @@ func (self *StateAccessor) SetIgnoreWhitespaceInDiffView(value bool) {
 	self.gui.IgnoreWhitespaceInDiffView = value
 }
 
+func (self *StateAccessor) GetBypassHooks() bool {
+	return self.gui.BypassHooks
+}
+
+func (self *StateAccessor) SetBypassHooks(value bool) {
+	self.gui.BypassHooks = value
+	self.gui.git.SetBypassHooks(value)
+}
+
 func (self *StateAccessor) GetRepoPathStack() *utils.StringStack {
 	return self.gui.RepoPathStack
 }
@@ -286,6 +300,7 @@ func (gui *Gui) onNewRepo(startArgs appTypes.StartArgs, reuseState bool) error {
 	if err != nil {
 		return err
 	}
+	gui.git.SetBypassHooks(gui.BypassHooks)
 
 	contextToPush := gui.resetState(startArgs, reuseState)
 
@@ -485,6 +500,9 @@ func NewGui(
 		gui.LogCommand,
 		gui.getCmdWriter,
 		credentialsHelper.PromptUserForCredential,
+		func(socketPath string) []string {
+			return daemon.ToEnvVars(daemon.NewAskpassInstruction(socketPath))
+		},
 	)
 
 	osCommand := oscommands.NewOSCommand(cmn, config, oscommands.GetPlatform(), guiIO)
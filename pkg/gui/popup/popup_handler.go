@@ -2,9 +2,11 @@ package popup
 
 import (
 	"context"
+	"errors"
 	"strings"
 
 	"github.com/jesseduffield/gocui"
+	"github.com/jesseduffield/lazygit/pkg/commands/oscommands"
 	"github.com/jesseduffield/lazygit/pkg/common"
 	gctx "github.com/jesseduffield/lazygit/pkg/gui/context"
 	"github.com/jesseduffield/lazygit/pkg/gui/style"
@@ -72,9 +74,25 @@ func (self *PopupHandler) Error(err error) error {
 		return err
 	}
 
+	var credentialErr *oscommands.CredentialError
+	if errors.As(err, &credentialErr) {
+		return self.ErrorMsg(strings.TrimSpace(err.Error()) + "\n\n" + self.credentialErrorHint(credentialErr.Kind))
+	}
+
 	return self.ErrorMsg(err.Error())
 }
 
+func (self *PopupHandler) credentialErrorHint(kind oscommands.CredentialErrorKind) string {
+	switch kind {
+	case oscommands.AUTHENTICATION_FAILED:
+		return self.Tr.AuthenticationFailedHint
+	case oscommands.SSH_AUTHENTICATION_FAILED:
+		return self.Tr.SshAuthenticationFailedHint
+	default:
+		return self.Tr.CredentialsRequiredHint
+	}
+}
+
 func (self *PopupHandler) ErrorMsg(message string) error {
 	self.Lock()
 	self.index++
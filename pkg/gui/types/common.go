@@ -229,6 +229,8 @@ type Mutexes struct {
 type IStateAccessor interface {
 	GetIgnoreWhitespaceInDiffView() bool
 	SetIgnoreWhitespaceInDiffView(value bool)
+	GetBypassHooks() bool
+	SetBypassHooks(value bool)
 	GetRepoPathStack() *utils.StringStack
 	GetRepoState() IRepoStateAccessor
 	// tells us whether we're currently updating lazygit
@@ -428,6 +428,10 @@ type TranslationSet struct {
 	LcBuildingPatch                     string
 	LcViewCommits                       string
 	MinGitVersionError                  string
+	GitNotFoundError                    string
+	CredentialsRequiredHint             string
+	AuthenticationFailedHint            string
+	SshAuthenticationFailedHint         string
 	LcRunningCustomCommandStatus        string
 	LcSubmoduleStashAndReset            string
 	LcAndResetSubmodules                string
@@ -483,6 +487,9 @@ type TranslationSet struct {
 	ToggleWhitespaceInDiffView          string
 	IgnoringWhitespaceInDiffView        string
 	ShowingWhitespaceInDiffView         string
+	ToggleBypassHooks                   string
+	BypassingHooks                      string
+	NotBypassingHooks                   string
 	IncreaseContextInDiffView           string
 	DecreaseContextInDiffView           string
 	CreatePullRequestOptions            string
@@ -1100,6 +1107,10 @@ func EnglishTranslationSet() TranslationSet {
 		LcBuildingPatch:                     "building patch",
 		LcViewCommits:                       "view commits",
 		MinGitVersionError:                  "Git version must be at least 2.20 (i.e. from 2018 onwards). Please upgrade your git version. Alternatively raise an issue at https://github.com/jesseduffield/lazygit/issues for lazygit to be more backwards compatible.",
+		GitNotFoundError:                    "Could not find git. Please install git and make sure it is on your PATH.",
+		CredentialsRequiredHint:             "Git needed credentials for the remote but could not ask for them. Consider setting up a credential helper (see `git help credential`).",
+		AuthenticationFailedHint:            "The remote rejected your credentials. If you use a personal access token, check that it hasn't expired.",
+		SshAuthenticationFailedHint:         "The remote rejected your SSH key. Check that your key is loaded (`ssh-add -l`) and registered with the remote, and that the remote's host key is in your known_hosts.",
 		LcRunningCustomCommandStatus:        "running custom command",
 		LcSubmoduleStashAndReset:            "stash uncommitted submodule changes and update",
 		LcAndResetSubmodules:                "and reset submodules",
@@ -1154,6 +1165,9 @@ func EnglishTranslationSet() TranslationSet {
 		ToggleWhitespaceInDiffView:          "Toggle whether or not whitespace changes are shown in the diff view",
 		IgnoringWhitespaceInDiffView:        "Whitespace will be ignored in the diff view",
 		ShowingWhitespaceInDiffView:         "Whitespace will be shown in the diff view",
+		ToggleBypassHooks:                   "Toggle whether git hooks are skipped for commits and pushes",
+		BypassingHooks:                      "Git hooks will be skipped for commits and pushes",
+		NotBypassingHooks:                   "Git hooks will run for commits and pushes",
 		IncreaseContextInDiffView:           "Increase the size of the context shown around changes in the diff view",
 		DecreaseContextInDiffView:           "Decrease the size of the context shown around changes in the diff view",
 		CreatePullRequest:                   "Create pull request",
@@ -0,0 +1,37 @@
+package commit
+
+import (
+	"github.com/jesseduffield/lazygit/pkg/config"
+	. "github.com/jesseduffield/lazygit/pkg/integration/components"
+)
+
+var CommitBypassingHooks = NewIntegrationTest(NewIntegrationTestArgs{
+	Description:  "Commit past a broken pre-commit hook by toggling hooks off for the session",
+	ExtraCmdArgs: "",
+	Skip:         false,
+	SetupConfig:  func(config *config.AppConfig) {},
+	SetupRepo: func(shell *Shell) {
+		shell.CreateFile(".git/hooks/pre-commit", "#!/bin/sh\nexit 1\n")
+		shell.RunCommand("chmod +x .git/hooks/pre-commit")
+
+		shell.CreateFileAndAdd("myfile", "myfile content")
+	},
+	Run: func(t *TestDriver, keys config.KeybindingConfig) {
+		t.Views().Commits().
+			IsEmpty()
+
+		t.Views().Files().
+			IsFocused().
+			Press(keys.Universal.ToggleBypassHooks).
+			Press(keys.Files.CommitChanges)
+
+		t.ExpectPopup().CommitMessagePanel().
+			Type("my commit").
+			Confirm()
+
+		t.Views().Commits().
+			Lines(
+				Contains("my commit"),
+			)
+	},
+})
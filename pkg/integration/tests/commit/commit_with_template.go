@@ -0,0 +1,37 @@
+package commit
+
+import (
+	"github.com/jesseduffield/lazygit/pkg/config"
+	. "github.com/jesseduffield/lazygit/pkg/integration/components"
+)
+
+var CommitWithTemplate = NewIntegrationTest(NewIntegrationTestArgs{
+	Description:  "Commit with the message starting off as the configured commit template",
+	ExtraCmdArgs: "",
+	Skip:         false,
+	SetupConfig:  func(config *config.AppConfig) {},
+	SetupRepo: func(shell *Shell) {
+		shell.CreateFile(".git/commit-template", "feat: \n# what changed and why\n")
+		shell.SetConfig("commit.template", ".git/commit-template")
+		shell.CreateFile("myfile", "myfile content")
+	},
+	Run: func(t *TestDriver, keys config.KeybindingConfig) {
+		t.Views().Commits().
+			IsEmpty()
+
+		t.Views().Files().
+			IsFocused().
+			PressPrimaryAction().
+			Press(keys.Files.CommitChanges)
+
+		t.ExpectPopup().CommitMessagePanel().
+			InitialText(Equals("feat: ")).
+			Type("add myfile").
+			Confirm()
+
+		t.Views().Commits().
+			Lines(
+				Contains("feat: add myfile"),
+			)
+	},
+})
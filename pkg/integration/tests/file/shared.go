@@ -0,0 +1,48 @@
+package file
+
+import (
+	"github.com/jesseduffield/lazygit/pkg/config"
+	. "github.com/jesseduffield/lazygit/pkg/integration/components"
+)
+
+// with core.quotePath on, git quotes non-ASCII characters in paths (other than
+// when it separates them with NUL), so we check that such file names survive
+// staging and discarding both with and without it
+func unicodeFileNameSetup(shell *Shell, quotePath string) {
+	shell.CreateFileAndAdd("café.txt", "original content")
+	shell.Commit("first commit")
+	shell.SetConfig("core.quotePath", quotePath)
+
+	shell.UpdateFile("café.txt", "new content")
+	shell.CreateFile("🎉 party.txt", "party")
+}
+
+func unicodeFileNameRun(t *TestDriver, keys config.KeybindingConfig) {
+	t.Views().Files().
+		IsFocused().
+		Lines(
+			Contains(` M café.txt`).IsSelected(),
+			Contains(`?? 🎉 party.txt`),
+		).
+		PressPrimaryAction().
+		Lines(
+			Contains(`M  café.txt`).IsSelected(),
+			Contains(`?? 🎉 party.txt`),
+		).
+		Press(keys.Universal.Remove)
+
+	t.ExpectPopup().Menu().Title(Equals("café.txt")).Select(Contains("discard all changes")).Confirm()
+
+	t.Views().Files().
+		Lines(
+			Contains(`?? 🎉 party.txt`).IsSelected(),
+		).
+		Press(keys.Universal.Remove)
+
+	t.ExpectPopup().Menu().Title(Equals("🎉 party.txt")).Select(Contains("discard all changes")).Confirm()
+
+	t.Views().Files().IsEmpty()
+
+	t.FileSystem().FileContent("café.txt", Equals("original content"))
+	t.FileSystem().PathNotPresent("🎉 party.txt")
+}
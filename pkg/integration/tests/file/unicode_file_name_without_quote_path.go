@@ -0,0 +1,18 @@
+package file
+
+import (
+	"github.com/jesseduffield/lazygit/pkg/config"
+	. "github.com/jesseduffield/lazygit/pkg/integration/components"
+)
+
+var UnicodeFileNameWithoutQuotePath = NewIntegrationTest(NewIntegrationTestArgs{
+	Description:  "Staging and discarding files with non-ASCII names when git doesn't quote them",
+	ExtraCmdArgs: "",
+	Skip:         false,
+	SetupConfig: func(config *config.AppConfig) {
+	},
+	SetupRepo: func(shell *Shell) {
+		unicodeFileNameSetup(shell, "false")
+	},
+	Run: unicodeFileNameRun,
+})
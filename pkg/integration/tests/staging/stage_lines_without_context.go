@@ -0,0 +1,76 @@
+package staging
+
+import (
+	"github.com/jesseduffield/lazygit/pkg/config"
+	. "github.com/jesseduffield/lazygit/pkg/integration/components"
+)
+
+var StageLinesWithoutContext = NewIntegrationTest(NewIntegrationTestArgs{
+	Description:  "Stage lines of a file when diffs are shown without any context lines",
+	ExtraCmdArgs: "",
+	Skip:         false,
+	SetupConfig: func(config *config.AppConfig) {
+		config.UserConfig.Git.DiffContextSize = 0
+	},
+	SetupRepo: func(shell *Shell) {
+		shell.CreateFileAndAdd("file1", "1a\n2a\n3a\n4a\n5a\n")
+		shell.Commit("one")
+
+		shell.UpdateFile("file1", "1a\n2b\n3a\n4a\n4b\n5a\n")
+
+		// hunks look like:
+		// @@ -2 +2 @@
+		// -2a
+		// +2b
+		// @@ -4,0 +5 @@
+		// +4b
+	},
+	Run: func(t *TestDriver, keys config.KeybindingConfig) {
+		t.Views().Files().
+			IsFocused().
+			Lines(
+				Contains("file1").IsSelected(),
+			).
+			PressEnter()
+
+		t.Views().Staging().
+			IsFocused().
+			ContainsLines(
+				Contains(`@@`),
+				Contains(`-2a`),
+				Contains(`+2b`),
+				Contains(`@@`),
+				Contains(`+4b`),
+			).
+			SelectedLines(Contains(`-2a`)).
+			NavigateToLine(Contains(`+4b`)).
+			PressPrimaryAction().
+			ContainsLines(
+				Contains(`@@`),
+				Contains(`-2a`),
+				Contains(`+2b`),
+			).
+			Tap(func() {
+				t.Views().StagingSecondary().
+					ContainsLines(
+						Contains(`@@`),
+						Contains(`+4b`),
+					)
+			}).
+			NavigateToLine(Contains(`+2b`)).
+			PressPrimaryAction().
+			ContainsLines(
+				Contains(`@@`),
+				Contains(`-2a`),
+			).
+			Tap(func() {
+				t.Views().StagingSecondary().
+					ContainsLines(
+						Contains(`@@`),
+						Contains(`+2b`),
+						Contains(`@@`),
+						Contains(`+4b`),
+					)
+			})
+	},
+})
@@ -46,7 +46,9 @@ var tests = []*components.IntegrationTest{
 	cherry_pick.CherryPickConflicts,
 	commit.Amend,
 	commit.Commit,
+	commit.CommitBypassingHooks,
 	commit.CommitMultiline,
+	commit.CommitWithTemplate,
 	commit.CreateTag,
 	commit.DiscardOldFileChange,
 	commit.History,
@@ -83,6 +85,8 @@ var tests = []*components.IntegrationTest{
 	file.DiscardStagedChanges,
 	file.Gitignore,
 	file.RememberCommitMessageAfterFail,
+	file.UnicodeFileNameWithQuotePath,
+	file.UnicodeFileNameWithoutQuotePath,
 	filter_by_path.CliArg,
 	filter_by_path.SelectFile,
 	filter_by_path.TypeFile,
@@ -140,6 +144,7 @@ var tests = []*components.IntegrationTest{
 	staging.Search,
 	staging.StageHunks,
 	staging.StageLines,
+	staging.StageLinesWithoutContext,
 	staging.StageRanges,
 	stash.Apply,
 	stash.ApplyPatch,
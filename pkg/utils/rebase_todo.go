@@ -2,6 +2,7 @@ package utils
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"strings"
 
@@ -55,7 +56,7 @@ func WriteRebaseTodoFile(fileName string, todos []todo.Todo) error {
 	if err != nil {
 		return err
 	}
-	err = todo.Write(f, todos)
+	err = writeTodos(f, todos)
 	err2 := f.Close()
 	if err == nil {
 		err = err2
@@ -63,6 +64,30 @@ func WriteRebaseTodoFile(fileName string, todos []todo.Todo) error {
 	return err
 }
 
+// todo.Write drops the subject of a fixup line, e.g. 'fixup -C abc123 my
+// subject', so we write those lines ourselves and leave the rest to it
+func writeTodos(f io.Writer, todos []todo.Todo) error {
+	for _, t := range todos {
+		if t.Command != todo.Fixup || t.Msg == "" {
+			if err := todo.Write(f, []todo.Todo{t}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		line := t.Command.String() + " "
+		if t.Flag != "" {
+			line += t.Flag + " "
+		}
+		line += t.Commit + " " + t.Msg + "\n"
+		if _, err := io.WriteString(f, line); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func PrependStrToTodoFile(filePath string, linesToPrepend []byte) error {
 	existingContent, err := os.ReadFile(filePath)
 	if err != nil {